@@ -0,0 +1,239 @@
+package abac
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/G0tem/go-service-auth/internal"
+	"github.com/G0tem/go-service-auth/internal/handler/rbac"
+	"github.com/G0tem/go-service-auth/internal/model"
+	"github.com/rs/zerolog/log"
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	EffectAllow         = "allow"
+	EffectDeny          = "deny"
+	EffectNotApplicable = "not_applicable"
+
+	// DenyOverrides - any applicable deny rule wins, otherwise any applicable allow rule allows
+	DenyOverrides = "deny-overrides"
+	// PermitOverrides - any applicable allow rule wins, otherwise any applicable deny rule denies
+	PermitOverrides = "permit-overrides"
+	// FirstApplicable - effect of the first applicable rule in the policy order
+	FirstApplicable = "first-applicable"
+)
+
+// Rule - allow or deny actions when condition over request attributes is true
+type Rule struct {
+	ID          string `json:"id" yaml:"id"`
+	Description string `json:"description,omitempty" yaml:"description,omitempty"`
+	Effect      string `json:"effect" yaml:"effect"`
+	// Actions in model:action format with wildcards (see rbac.PermissionPattern), empty list matches any action
+	Actions []string `json:"actions,omitempty" yaml:"actions,omitempty"`
+	// Condition in the expression language (see Compile), empty condition is always true
+	Condition string `json:"condition,omitempty" yaml:"condition,omitempty"`
+}
+
+type Policy struct {
+	// Algorithm combining effects of applicable rules (deny-overrides by default)
+	Algorithm string `json:"algorithm" yaml:"algorithm"`
+	Rules     []Rule `json:"rules" yaml:"rules"`
+}
+
+// Request - subject requests to perform action on resource in environment
+type Request struct {
+	Action      string
+	Subject     Attributes
+	Resource    Attributes
+	Environment Attributes
+}
+
+// Decision - result of request evaluation, request without applicable rules is denied
+type Decision struct {
+	Allowed bool   `json:"allowed"`
+	Effect  string `json:"effect" enums:"allow,deny,not_applicable"`
+	// RuleID of the rule decided the result
+	RuleID string `json:"rule_id,omitempty"`
+	Reason string `json:"reason,omitempty"`
+}
+
+type compiledRule struct {
+	Rule
+	actions   []rbac.PermissionPattern
+	condition *Expression
+}
+
+// Engine - compiled ABAC policy, safe for concurrent use
+type Engine struct {
+	algorithm string
+	rules     []compiledRule
+	// LogDecisions logs every decision with info level (debug level otherwise)
+	LogDecisions bool
+}
+
+// NewEngine - validate and compile policy
+func NewEngine(policy Policy) (*Engine, error) {
+	engine := &Engine{algorithm: policy.Algorithm}
+	if engine.algorithm == "" {
+		engine.algorithm = DenyOverrides
+	}
+	switch engine.algorithm {
+	case DenyOverrides, PermitOverrides, FirstApplicable:
+	default:
+		return nil, internal.PrintError(internal.ErrInvalidAbacPolicy, fmt.Errorf("unknown algorithm %v", policy.Algorithm))
+	}
+
+	ids := map[string]bool{}
+	for i, rule := range policy.Rules {
+		if rule.ID == "" {
+			rule.ID = fmt.Sprintf("rule-%d", i+1)
+		}
+		if ids[rule.ID] {
+			return nil, internal.PrintError(internal.ErrInvalidAbacPolicy, fmt.Errorf("duplicate rule %v", rule.ID))
+		}
+		ids[rule.ID] = true
+
+		if rule.Effect != EffectAllow && rule.Effect != EffectDeny {
+			return nil, internal.PrintError(internal.ErrInvalidAbacPolicy, fmt.Errorf("rule %v: unknown effect %v", rule.ID, rule.Effect))
+		}
+		condition, err := Compile(rule.Condition)
+		if err != nil {
+			return nil, internal.PrintError(internal.ErrInvalidAbacPolicy, fmt.Errorf("rule %v: %w", rule.ID, err))
+		}
+
+		compiled := compiledRule{Rule: rule, condition: condition}
+		for _, action := range rule.Actions {
+			if !strings.Contains(action, ":") {
+				return nil, internal.PrintError(internal.ErrInvalidAbacPolicy, fmt.Errorf("rule %v: action %v must be in model:action format", rule.ID, action))
+			}
+			compiled.actions = append(compiled.actions, rbac.ParsePermission(action))
+		}
+		engine.rules = append(engine.rules, compiled)
+	}
+
+	return engine, nil
+}
+
+// LoadPolicyFile - read YAML (or JSON) policy file and compile it
+func LoadPolicyFile(path string) (*Engine, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, internal.PrintError(internal.ErrInvalidAbacPolicy, err)
+	}
+
+	var policy Policy
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+	if err = decoder.Decode(&policy); err != nil {
+		return nil, internal.PrintError(internal.ErrInvalidAbacPolicy, err)
+	}
+
+	return NewEngine(policy)
+}
+
+// SubjectFromUser - subject attributes of the user
+func SubjectFromUser(user model.User, roles, permissions []string) Attributes {
+	return Attributes{
+		"user_id":         user.ID.String(),
+		"username":        user.Username,
+		"email":           user.Email,
+		"email_confirmed": user.EmailConfirmed,
+		"is_active":       user.IsActive,
+		"roles":           roles,
+		"permissions":     permissions,
+	}
+}
+
+// Environment - environment attributes of the moment: time (RFC3339), hour, minute and weekday ("monday"...)
+func Environment(now time.Time) Attributes {
+	return Attributes{
+		"time":    now.Format(time.RFC3339),
+		"hour":    float64(now.Hour()),
+		"minute":  float64(now.Minute()),
+		"weekday": strings.ToLower(now.Weekday().String()),
+	}
+}
+
+// Evaluate - decide the request. Rule failed to evaluate (e.g. non-boolean condition) is treated
+// as applicable if it is a deny rule and as not applicable if it is an allow rule (fail closed).
+func (engine *Engine) Evaluate(request Request) Decision {
+	if request.Environment == nil {
+		request.Environment = Environment(time.Now())
+	}
+	attributes := Attributes{
+		"subject":     request.Subject,
+		"resource":    request.Resource,
+		"env":         request.Environment,
+		"environment": request.Environment,
+		"action":      request.Action,
+	}
+	requiredAction := rbac.ParsePermission(request.Action)
+
+	decision := Decision{Effect: EffectNotApplicable, Reason: "no applicable rules"}
+	var overridable *Decision
+	for _, rule := range engine.rules {
+		if !rule.matchesAction(requiredAction) {
+			continue
+		}
+
+		applicable, err := rule.condition.Evaluate(attributes)
+		reason := fmt.Sprintf("condition %q", rule.Condition)
+		if err != nil {
+			applicable = rule.Effect == EffectDeny
+			reason = err.Error()
+		}
+		if !applicable {
+			continue
+		}
+
+		ruleDecision := Decision{Allowed: rule.Effect == EffectAllow, Effect: rule.Effect, RuleID: rule.ID, Reason: reason}
+		if engine.algorithm == FirstApplicable ||
+			(engine.algorithm == DenyOverrides && rule.Effect == EffectDeny) ||
+			(engine.algorithm == PermitOverrides && rule.Effect == EffectAllow) {
+			decision = ruleDecision
+			break
+		}
+		// Overridable effect is remembered, the first one decides if nothing overrides it
+		if overridable == nil {
+			overridable = &ruleDecision
+		}
+	}
+	if decision.Effect == EffectNotApplicable && overridable != nil {
+		decision = *overridable
+	}
+
+	engine.logDecision(request, decision)
+	return decision
+}
+
+func (rule compiledRule) matchesAction(required rbac.PermissionPattern) bool {
+	if len(rule.actions) == 0 {
+		return true
+	}
+	for _, action := range rule.actions {
+		if action.Matches(required) {
+			return true
+		}
+	}
+	return false
+}
+
+func (engine *Engine) logDecision(request Request, decision Decision) {
+	event := log.Debug()
+	if engine.LogDecisions {
+		event = log.Info()
+	}
+	event.
+		Str("action", request.Action).
+		Interface("subject", request.Subject["user_id"]).
+		Interface("resource", request.Resource).
+		Bool("allowed", decision.Allowed).
+		Str("effect", decision.Effect).
+		Str("rule", decision.RuleID).
+		Str("reason", decision.Reason).
+		Msg("abac decision")
+}
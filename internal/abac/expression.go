@@ -0,0 +1,511 @@
+package abac
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// Expression language of rule conditions.
+//
+//	expr       = or
+//	or         = and { "||" and }
+//	and        = not { "&&" not }
+//	not        = "!" not | comparison
+//	comparison = operand [ ( "==" | "!=" | "<" | "<=" | ">" | ">=" | "in" ) operand ]
+//	operand    = string | number | "true" | "false" | "null" | path | list | "(" expr ")"
+//	list       = "[" [ expr { "," expr } ] "]"
+//	path       = identifier { "." identifier }, e.g. subject.user_id, resource.owner_id, env.hour
+//
+// Strings are quoted with ' or ". Missing attributes are null. Numbers and numeric strings are
+// compared as numbers, "in" checks membership in a list or a substring in a string.
+
+// Attributes - attributes of subject, resource or environment
+type Attributes map[string]any
+
+// Expression - compiled condition
+type Expression struct {
+	source string
+	root   node
+}
+
+// Compile - parse condition, empty condition is always true
+func Compile(source string) (*Expression, error) {
+	if strings.TrimSpace(source) == "" {
+		return &Expression{source: source, root: literalNode{value: true}}, nil
+	}
+
+	tokens, err := tokenize(source)
+	if err != nil {
+		return nil, err
+	}
+	p := &parser{tokens: tokens}
+	root, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if p.peek().kind != tokenEOF {
+		return nil, fmt.Errorf("unexpected %q at %d", p.peek().text, p.peek().pos)
+	}
+
+	return &Expression{source: source, root: root}, nil
+}
+
+func (e *Expression) String() string {
+	return e.source
+}
+
+// Evaluate - evaluate condition over attributes (roots of paths), result must be boolean
+func (e *Expression) Evaluate(attributes Attributes) (bool, error) {
+	value, err := e.root.eval(attributes)
+	if err != nil {
+		return false, err
+	}
+	result, ok := value.(bool)
+	if !ok {
+		return false, fmt.Errorf("condition %q result %v is not boolean", e.source, value)
+	}
+	return result, nil
+}
+
+// Tokens
+
+type tokenKind int
+
+const (
+	tokenEOF tokenKind = iota
+	tokenIdent
+	tokenString
+	tokenNumber
+	tokenOperator
+)
+
+type token struct {
+	kind tokenKind
+	text string
+	pos  int
+}
+
+var operators = []string{"&&", "||", "==", "!=", "<=", ">=", "<", ">", "!", "(", ")", "[", "]", ","}
+
+func tokenize(source string) ([]token, error) {
+	var tokens []token
+	runes := []rune(source)
+	for i := 0; i < len(runes); {
+		r := runes[i]
+		switch {
+		case unicode.IsSpace(r):
+			i++
+		case r == '"' || r == '\'':
+			end := i + 1
+			var text strings.Builder
+			for end < len(runes) && runes[end] != r {
+				if runes[end] == '\\' && end+1 < len(runes) {
+					end++
+				}
+				text.WriteRune(runes[end])
+				end++
+			}
+			if end >= len(runes) {
+				return nil, fmt.Errorf("unterminated string at %d", i)
+			}
+			tokens = append(tokens, token{kind: tokenString, text: text.String(), pos: i})
+			i = end + 1
+		case unicode.IsDigit(r) || (r == '-' && i+1 < len(runes) && unicode.IsDigit(runes[i+1])):
+			end := i + 1
+			for end < len(runes) && (unicode.IsDigit(runes[end]) || runes[end] == '.') {
+				end++
+			}
+			tokens = append(tokens, token{kind: tokenNumber, text: string(runes[i:end]), pos: i})
+			i = end
+		case unicode.IsLetter(r) || r == '_':
+			end := i + 1
+			for end < len(runes) && (unicode.IsLetter(runes[end]) || unicode.IsDigit(runes[end]) || runes[end] == '_' || runes[end] == '.') {
+				end++
+			}
+			tokens = append(tokens, token{kind: tokenIdent, text: string(runes[i:end]), pos: i})
+			i = end
+		default:
+			matched := false
+			for _, operator := range operators {
+				if strings.HasPrefix(string(runes[i:]), operator) {
+					tokens = append(tokens, token{kind: tokenOperator, text: operator, pos: i})
+					i += len([]rune(operator))
+					matched = true
+					break
+				}
+			}
+			if !matched {
+				return nil, fmt.Errorf("unexpected %q at %d", r, i)
+			}
+		}
+	}
+
+	return append(tokens, token{kind: tokenEOF, pos: len(runes)}), nil
+}
+
+// Parser
+
+type parser struct {
+	tokens []token
+	pos    int
+}
+
+func (p *parser) peek() token {
+	return p.tokens[p.pos]
+}
+
+func (p *parser) next() token {
+	t := p.tokens[p.pos]
+	if t.kind != tokenEOF {
+		p.pos++
+	}
+	return t
+}
+
+func (p *parser) accept(text string) bool {
+	t := p.peek()
+	if (t.kind == tokenOperator || t.kind == tokenIdent) && t.text == text {
+		p.pos++
+		return true
+	}
+	return false
+}
+
+func (p *parser) expect(text string) error {
+	if !p.accept(text) {
+		return fmt.Errorf("expected %q at %d", text, p.peek().pos)
+	}
+	return nil
+}
+
+func (p *parser) parseOr() (node, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for p.accept("||") {
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		left = binaryNode{operator: "||", left: left, right: right}
+	}
+	return left, nil
+}
+
+func (p *parser) parseAnd() (node, error) {
+	left, err := p.parseNot()
+	if err != nil {
+		return nil, err
+	}
+	for p.accept("&&") {
+		right, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		left = binaryNode{operator: "&&", left: left, right: right}
+	}
+	return left, nil
+}
+
+func (p *parser) parseNot() (node, error) {
+	if p.accept("!") {
+		operand, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		return notNode{operand: operand}, nil
+	}
+	return p.parseComparison()
+}
+
+func (p *parser) parseComparison() (node, error) {
+	left, err := p.parseOperand()
+	if err != nil {
+		return nil, err
+	}
+	for _, operator := range []string{"==", "!=", "<=", ">=", "<", ">", "in"} {
+		if p.accept(operator) {
+			right, err := p.parseOperand()
+			if err != nil {
+				return nil, err
+			}
+			return binaryNode{operator: operator, left: left, right: right}, nil
+		}
+	}
+	return left, nil
+}
+
+func (p *parser) parseOperand() (node, error) {
+	t := p.next()
+	switch t.kind {
+	case tokenString:
+		return literalNode{value: t.text}, nil
+	case tokenNumber:
+		number, err := strconv.ParseFloat(t.text, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid number %q at %d", t.text, t.pos)
+		}
+		return literalNode{value: number}, nil
+	case tokenIdent:
+		switch t.text {
+		case "true":
+			return literalNode{value: true}, nil
+		case "false":
+			return literalNode{value: false}, nil
+		case "null":
+			return literalNode{value: nil}, nil
+		}
+		return pathNode{path: strings.Split(t.text, ".")}, nil
+	case tokenOperator:
+		switch t.text {
+		case "(":
+			inner, err := p.parseOr()
+			if err != nil {
+				return nil, err
+			}
+			return inner, p.expect(")")
+		case "[":
+			list := listNode{}
+			if p.accept("]") {
+				return list, nil
+			}
+			for {
+				item, err := p.parseOr()
+				if err != nil {
+					return nil, err
+				}
+				list.items = append(list.items, item)
+				if p.accept("]") {
+					return list, nil
+				}
+				if err = p.expect(","); err != nil {
+					return nil, err
+				}
+			}
+		}
+	}
+	if t.kind == tokenEOF {
+		return nil, fmt.Errorf("unexpected end of condition")
+	}
+	return nil, fmt.Errorf("unexpected %q at %d", t.text, t.pos)
+}
+
+// Nodes
+
+type node interface {
+	eval(attributes Attributes) (any, error)
+}
+
+type literalNode struct {
+	value any
+}
+
+func (n literalNode) eval(Attributes) (any, error) {
+	return n.value, nil
+}
+
+type pathNode struct {
+	path []string
+}
+
+func (n pathNode) eval(attributes Attributes) (any, error) {
+	var current any = map[string]any(attributes)
+	for _, key := range n.path {
+		switch container := current.(type) {
+		case map[string]any:
+			current = container[key]
+		case Attributes:
+			current = container[key]
+		default:
+			return nil, nil
+		}
+	}
+	return normalize(current), nil
+}
+
+type listNode struct {
+	items []node
+}
+
+func (n listNode) eval(attributes Attributes) (any, error) {
+	values := make([]any, 0, len(n.items))
+	for _, item := range n.items {
+		value, err := item.eval(attributes)
+		if err != nil {
+			return nil, err
+		}
+		values = append(values, value)
+	}
+	return values, nil
+}
+
+type notNode struct {
+	operand node
+}
+
+func (n notNode) eval(attributes Attributes) (any, error) {
+	value, err := n.operand.eval(attributes)
+	if err != nil {
+		return nil, err
+	}
+	return !truthy(value), nil
+}
+
+type binaryNode struct {
+	operator    string
+	left, right node
+}
+
+func (n binaryNode) eval(attributes Attributes) (any, error) {
+	left, err := n.left.eval(attributes)
+	if err != nil {
+		return nil, err
+	}
+
+	// Short circuit
+	switch n.operator {
+	case "&&":
+		if !truthy(left) {
+			return false, nil
+		}
+	case "||":
+		if truthy(left) {
+			return true, nil
+		}
+	}
+
+	right, err := n.right.eval(attributes)
+	if err != nil {
+		return nil, err
+	}
+
+	switch n.operator {
+	case "&&", "||":
+		return truthy(right), nil
+	case "==":
+		return equal(left, right), nil
+	case "!=":
+		return !equal(left, right), nil
+	case "in":
+		return contains(right, left), nil
+	}
+
+	// Ordering is defined for numbers and strings only, otherwise it is false
+	if a, b, ok := numbers(left, right); ok {
+		return compare(n.operator, a, b), nil
+	}
+	a, aOk := left.(string)
+	b, bOk := right.(string)
+	if aOk && bOk {
+		return compare(n.operator, strings.Compare(a, b), 0), nil
+	}
+	return false, nil
+}
+
+// Values
+
+// normalize - convert attribute value to one of nil, bool, float64, string or []any
+func normalize(value any) any {
+	switch v := value.(type) {
+	case int:
+		return float64(v)
+	case int32:
+		return float64(v)
+	case int64:
+		return float64(v)
+	case uint:
+		return float64(v)
+	case float32:
+		return float64(v)
+	case []string:
+		result := make([]any, 0, len(v))
+		for _, item := range v {
+			result = append(result, item)
+		}
+		return result
+	case fmt.Stringer:
+		return v.String()
+	}
+	return value
+}
+
+func truthy(value any) bool {
+	b, ok := value.(bool)
+	return ok && b
+}
+
+func toNumber(value any) (float64, bool) {
+	switch v := value.(type) {
+	case float64:
+		return v, true
+	case string:
+		number, err := strconv.ParseFloat(strings.TrimSpace(v), 64)
+		return number, err == nil
+	}
+	return 0, false
+}
+
+// numbers - both values as numbers if at least one of them is a number and other one is numeric
+func numbers(left, right any) (float64, float64, bool) {
+	_, leftNumber := left.(float64)
+	_, rightNumber := right.(float64)
+	if !leftNumber && !rightNumber {
+		return 0, 0, false
+	}
+	a, aOk := toNumber(left)
+	b, bOk := toNumber(right)
+	return a, b, aOk && bOk
+}
+
+func equal(left, right any) bool {
+	if a, b, ok := numbers(left, right); ok {
+		return a == b
+	}
+	leftList, leftIsList := left.([]any)
+	rightList, rightIsList := right.([]any)
+	if leftIsList || rightIsList {
+		if len(leftList) != len(rightList) || leftIsList != rightIsList {
+			return false
+		}
+		for i := range leftList {
+			if !equal(leftList[i], rightList[i]) {
+				return false
+			}
+		}
+		return true
+	}
+	return left == right
+}
+
+func contains(container, value any) bool {
+	switch c := container.(type) {
+	case []any:
+		for _, item := range c {
+			if equal(item, value) {
+				return true
+			}
+		}
+	case string:
+		if s, ok := value.(string); ok {
+			return strings.Contains(c, s)
+		}
+	}
+	return false
+}
+
+func compare[T float64 | int](operator string, a, b T) bool {
+	switch operator {
+	case "<":
+		return a < b
+	case "<=":
+		return a <= b
+	case ">":
+		return a > b
+	case ">=":
+		return a >= b
+	}
+	return false
+}
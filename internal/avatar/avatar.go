@@ -0,0 +1,136 @@
+package avatar
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"image"
+	"image/color"
+	_ "image/gif" // registers decoder
+	"image/jpeg"
+	_ "image/png" // registers decoder
+	"net/http"
+	"path"
+	"strconv"
+
+	"github.com/disintegration/imaging"
+	_ "golang.org/x/image/webp" // registers decoder
+)
+
+// Limits of uploaded images (the size of the file is limited by MAX_FILE_UPLOAD_SIZE)
+const (
+	MinDimension = 32
+	MaxDimension = 4096
+)
+
+// ContentType of generated variants
+const ContentType = "image/jpeg"
+
+// AllowedTypes - content types of uploaded images detected from their content
+var AllowedTypes = []string{"image/jpeg", "image/png", "image/gif", "image/webp"}
+
+// Variant is a square image resized from the uploaded one
+type Variant struct {
+	Name string
+	Size int
+}
+
+// Variants generated from uploaded image, the first one is the default avatar
+var Variants = []Variant{
+	{Name: "large", Size: 512},
+	{Name: "medium", Size: 256},
+	{Name: "small", Size: 64},
+}
+
+// Image is the encoded variant and its object key
+type Image struct {
+	Variant Variant
+	Key     string
+	Data    []byte
+}
+
+// ValidationError means the uploaded file is not acceptable image
+type ValidationError struct {
+	Message string
+}
+
+func (e *ValidationError) Error() string {
+	return e.Message
+}
+
+func invalid(format string, args ...any) error {
+	return &ValidationError{Message: fmt.Sprintf(format, args...)}
+}
+
+// Process checks type and dimensions of uploaded image and encodes its variants. Variants are
+// center-cropped squares encoded to JPEG (without EXIF and other metadata, transparency is
+// flattened on white), their keys are "<owner>/<content hash>/<size>.jpg"
+func Process(owner string, data []byte) ([]Image, error) {
+	contentType := http.DetectContentType(data)
+	if !isAllowed(contentType) {
+		return nil, invalid("unsupported image type %v", contentType)
+	}
+
+	// Dimensions are checked before decoding to avoid allocating huge images
+	config, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, invalid("invalid image: %v", err)
+	}
+	if config.Width < MinDimension || config.Height < MinDimension ||
+		config.Width > MaxDimension || config.Height > MaxDimension {
+		return nil, invalid("image must be from %dx%d to %dx%d pixels, got %dx%d",
+			MinDimension, MinDimension, MaxDimension, MaxDimension, config.Width, config.Height)
+	}
+
+	// EXIF orientation is applied to pixels since metadata is not kept
+	src, err := imaging.Decode(bytes.NewReader(data), imaging.AutoOrientation(true))
+	if err != nil {
+		return nil, invalid("invalid image: %v", err)
+	}
+	bounds := src.Bounds()
+	flat := imaging.New(bounds.Dx(), bounds.Dy(), color.White)
+	flat = imaging.Overlay(flat, src, image.Pt(0, 0), 1)
+
+	hash := sha256.Sum256(data)
+	dir := Dir(owner, hex.EncodeToString(hash[:8]))
+	images := make([]Image, 0, len(Variants))
+	for _, variant := range Variants {
+		resized := imaging.Fill(flat, variant.Size, variant.Size, imaging.Center, imaging.Lanczos)
+		var buf bytes.Buffer
+		if err = jpeg.Encode(&buf, resized, &jpeg.Options{Quality: 85}); err != nil {
+			return nil, err
+		}
+		images = append(images, Image{Variant: variant, Key: Key(dir, variant), Data: buf.Bytes()})
+	}
+	return images, nil
+}
+
+// Dir - directory of variants of the image
+func Dir(owner, hash string) string {
+	return path.Join(owner, hash)
+}
+
+// Key - object key of the variant in the directory
+func Key(dir string, variant Variant) string {
+	return path.Join(dir, strconv.Itoa(variant.Size)+".jpg")
+}
+
+// VariantKeys - object keys of variants (by name) of the avatar stored under the key of default variant
+func VariantKeys(defaultKey string) map[string]string {
+	dir := path.Dir(defaultKey)
+	keys := make(map[string]string, len(Variants))
+	for _, variant := range Variants {
+		keys[variant.Name] = Key(dir, variant)
+	}
+	return keys
+}
+
+func isAllowed(contentType string) bool {
+	for _, allowed := range AllowedTypes {
+		if contentType == allowed {
+			return true
+		}
+	}
+	return false
+}
@@ -1,6 +1,7 @@
 package config
 
 import (
+	"fmt"
 	"os"
 	"strconv"
 	"strings"
@@ -22,6 +23,8 @@ type Config struct {
 	PublicPasswordResetConfirmationUrl string   `binding:"required" envconfig:"PUBLIC_PASSWORD_RESET_CONFIRMATION_URL"`
 	PublicUrl                          string   `binding:"required" envconfig:"PUBLIC_URL"`
 	PublicErrorUrl                     string   `binding:"required" envconfig:"PUBLIC_ERROR_URL"`
+	// PublicEmailChangeConfirmationUrl is the page confirming new email (token is passed as "token" query parameter)
+	PublicEmailChangeConfirmationUrl string `envconfig:"PUBLIC_EMAIL_CHANGE_CONFIRMATION_URL"`
 
 	PostgresHost            string        `binding:"required" envconfig:"POSTGRES_HOST"`
 	PostgresPort            string        `binding:"required" envconfig:"POSTGRES_PORT"`
@@ -31,6 +34,7 @@ type Config struct {
 	PostgresMaxIdleConns    int           `default:"10" envconfig:"POSTGRES_MAX_IDLE_CONNS"`
 	PostgresMaxOpenConns    int           `default:"100" envconfig:"POSTGRES_MAX_OPEN_CONNS"`
 	PostgresConnMaxLifetime time.Duration `default:"1h" envconfig:"POSTGRES_CONN_MAX_LIFETIME"`
+	DbLockTimeout           time.Duration `default:"1m" envconfig:"DB_LOCK_TIMEOUT"`
 
 	JwtValidationUrl string `binding:"required" envconfig:"JWT_VALIDATION_URL"`
 
@@ -41,6 +45,9 @@ type Config struct {
 	RedisAddr string `binding:"required" envconfig:"REDIS_ADDR"`
 	RedisDB   int    `binding:"required" envconfig:"REDIS_DB"`
 
+	// RbacCacheTTL is ttl of cached effective permissions, 0 disables cache
+	RbacCacheTTL time.Duration `default:"5m" envconfig:"RBAC_CACHE_TTL"`
+
 	CdnPublicUrl        string `binding:"required" envconfig:"CDN_PUBLIC_URL"`
 	S3AvatarsBucketName string `binding:"required" envconfig:"S3_AVATARS_BUCKET_NAME"`
 	S3CoversBucketName  string `binding:"required" envconfig:"S3_COVERS_BUCKET_NAME"`
@@ -48,8 +55,69 @@ type Config struct {
 	S3Endpoint          string `binding:"required" envconfig:"S3_ENDPOINT"`
 	S3AccessKey         string `binding:"required" envconfig:"S3_ACCESS_KEY"`
 	S3SecretAccessKey   string `binding:"required" envconfig:"S3_SECRET_ACCESS_KEY"`
+	// S3Secure enables https to S3 endpoint
+	S3Secure bool `default:"true" envconfig:"S3_SECURE"`
+
+	// StorageDriver selects object storage of avatars and covers: "s3" (S3_*) or "local" (STORAGE_LOCAL_*)
+	StorageDriver string `default:"s3" envconfig:"STORAGE_DRIVER"`
+	// StorageLocalRoot is the directory of local storage (buckets are its subdirectories)
+	StorageLocalRoot string `default:"./storage" envconfig:"STORAGE_LOCAL_ROOT"`
+	// StorageLocalPublicUrl is the external address objects of local storage are served at
+	StorageLocalPublicUrl string `envconfig:"STORAGE_LOCAL_PUBLIC_URL"`
 
 	MaxFileUploadSizeInBytes int `default:"10485760" envconfig:"MAX_FILE_UPLOAD_SIZE"`
+
+	// CdnCoversUrl is the public address of covers bucket (CDN_PUBLIC_URL/S3_COVERS_BUCKET_NAME by default)
+	CdnCoversUrl string `envconfig:"CDN_COVERS_URL"`
+	// CoverMaxSizeInBytes limits covers uploaded directly to the storage (not proxied, so not limited by MAX_FILE_UPLOAD_SIZE)
+	CoverMaxSizeInBytes int64 `default:"26214400" envconfig:"COVER_MAX_SIZE"`
+	// CoverUploadTTL is how long presigned cover upload is valid
+	CoverUploadTTL time.Duration `default:"15m" envconfig:"COVER_UPLOAD_TTL"`
+
+	RbacPolicyFile string `envconfig:"RBAC_POLICY_FILE"`
+	RbacSyncAction string `default:"delete_links" envconfig:"RBAC_SYNC_ACTION"`
+
+	AbacPolicyFile   string `envconfig:"ABAC_POLICY_FILE"`
+	AbacLogDecisions bool   `envconfig:"ABAC_LOG_DECISIONS"`
+
+	// GrpcTlsCertFile and GrpcTlsKeyFile enable TLS of gRPC server, GrpcTlsClientCaFile enables client certificates (mTLS)
+	GrpcTlsCertFile     string `envconfig:"GRPC_TLS_CERT_FILE"`
+	GrpcTlsKeyFile      string `envconfig:"GRPC_TLS_KEY_FILE"`
+	GrpcTlsClientCaFile string `envconfig:"GRPC_TLS_CLIENT_CA_FILE"`
+	// GrpcAuthEnabled requires gRPC callers to authenticate with service JWT or client certificate
+	GrpcAuthEnabled bool `default:"true" envconfig:"GRPC_AUTH_ENABLED"`
+	// GrpcClientPermissions are permissions of callers authenticated by client certificate (by common name)
+	GrpcClientPermissions map[string][]string `envconfig:"GRPC_CLIENT_PERMISSIONS"`
+	// GrpcRequestTimeout is max duration of gRPC request (shorter client deadline is kept)
+	GrpcRequestTimeout time.Duration `default:"30s" envconfig:"GRPC_REQUEST_TIMEOUT"`
+	// GrpcReflectionEnabled registers gRPC server reflection (for grpcurl and similar tools)
+	GrpcReflectionEnabled bool `envconfig:"GRPC_REFLECTION_ENABLED"`
+
+	// HealthCheckInterval is interval of database, Redis and RabbitMQ checks reported by grpc.health.v1
+	HealthCheckInterval time.Duration `default:"10s" envconfig:"HEALTH_CHECK_INTERVAL"`
+	// UserEventsMaxLen is approximate number of user change events retained in Redis stream for WatchUserEvents
+	UserEventsMaxLen int64 `default:"100000" envconfig:"USER_EVENTS_MAX_LEN"`
+	// ShutdownTimeout is how long HTTP and gRPC servers drain requests on SIGINT or SIGTERM
+	ShutdownTimeout time.Duration `default:"30s" envconfig:"SHUTDOWN_TIMEOUT"`
+
+	// EmailChangeTTL is how long the link confirming new email is valid
+	EmailChangeTTL time.Duration `default:"24h" envconfig:"EMAIL_CHANGE_TTL"`
+	// UsernameChangeCooldown is min interval between username changes of the user, 0 disables the limit
+	UsernameChangeCooldown time.Duration `default:"0s" envconfig:"USERNAME_CHANGE_COOLDOWN"`
+
+	// AuthCookieSecure sends session cookies (cookie mode of login) only over https
+	AuthCookieSecure bool `default:"true" envconfig:"AUTH_COOKIE_SECURE"`
+	// AuthCookieSameSite is SameSite attribute of session cookies: Strict, Lax or None
+	AuthCookieSameSite string `default:"Lax" envconfig:"AUTH_COOKIE_SAME_SITE"`
+	// AuthCookieDomain is Domain attribute of session cookies (host of the request if empty)
+	AuthCookieDomain string `envconfig:"AUTH_COOKIE_DOMAIN"`
+
+	// CorsAllowCredentials allows cookies and Authorization header of requests from CORS_ORIGINS ("*" never allows them)
+	CorsAllowCredentials bool `default:"true" envconfig:"CORS_ALLOW_CREDENTIALS"`
+	// CorsMaxAge is how long browsers cache preflight responses
+	CorsMaxAge time.Duration `default:"24h" envconfig:"CORS_MAX_AGE"`
+	// CorsRouteOrigins replace CORS_ORIGINS for paths starting with the prefix (by the longest prefix)
+	CorsRouteOrigins map[string][]string `envconfig:"CORS_ROUTE_ORIGINS"`
 }
 
 func getenvDef(key, def string) string {
@@ -72,10 +140,11 @@ func LoadConfig() Config {
 		HttpPort:                           internal.ParseUint16(os.Getenv("HTTP_PORT"), 8002),
 		GrpcPort:                           internal.ParseUint16(os.Getenv("GRPC_PORT"), 50051),
 		UserServiceBaseUrl:                 getenvDef("USER_SERVICE_BASE_URL", "http://127.0.0.1:8002/api"),
-		CorsOrigins:                        strings.Split(os.Getenv("CORS_ORIGINS"), ","),
+		CorsOrigins:                        parseList(os.Getenv("CORS_ORIGINS")),
 		SecretKey:                          os.Getenv("SECRET_KEY"),
 		PublicEmailConfirmationUrl:         os.Getenv("PUBLIC_EMAIL_CONFIRMATION_URL"),
 		PublicPasswordResetConfirmationUrl: os.Getenv("PUBLIC_PASSWORD_RESET_CONFIRMATION_URL"),
+		PublicEmailChangeConfirmationUrl:   getenvDef("PUBLIC_EMAIL_CHANGE_CONFIRMATION_URL", os.Getenv("PUBLIC_EMAIL_CONFIRMATION_URL")),
 		PublicUrl:                          os.Getenv("PUBLIC_URL"),
 		PublicErrorUrl:                     os.Getenv("PUBLIC_ERROR_URL"),
 
@@ -87,6 +156,7 @@ func LoadConfig() Config {
 		PostgresMaxIdleConns:    internal.ParseInt(os.Getenv("POSTGRES_MAX_IDLE_CONNS"), 10),
 		PostgresMaxOpenConns:    internal.ParseInt(os.Getenv("POSTGRES_MAX_OPEN_CONNS"), 100),
 		PostgresConnMaxLifetime: internal.ParseDuration(os.Getenv("POSTGRES_CONN_MAX_LIFETIME"), 1*time.Hour),
+		DbLockTimeout:           internal.ParseDuration(os.Getenv("DB_LOCK_TIMEOUT"), 1*time.Minute),
 
 		JwtValidationUrl: os.Getenv("JWT_VALIDATION_URL"),
 
@@ -97,6 +167,8 @@ func LoadConfig() Config {
 		RedisAddr: os.Getenv("REDIS_ADDR"),
 		RedisDB:   internal.ParseInt(os.Getenv("REDIS_DB"), 0),
 
+		RbacCacheTTL: internal.ParseDuration(getenvDef("RBAC_CACHE_TTL", "5m"), 5*time.Minute),
+
 		CdnPublicUrl:        os.Getenv("CDN_PUBLIC_URL"),
 		S3AvatarsBucketName: os.Getenv("S3_AVATARS_BUCKET_NAME"),
 		S3CoversBucketName:  os.Getenv("S3_COVERS_BUCKET_NAME"),
@@ -104,7 +176,72 @@ func LoadConfig() Config {
 		S3Endpoint:          os.Getenv("S3_ENDPOINT"),
 		S3AccessKey:         os.Getenv("S3_ACCESS_KEY"),
 		S3SecretAccessKey:   os.Getenv("S3_SECRET_ACCESS_KEY"),
+		S3Secure:            internal.ParseBool(getenvDef("S3_SECURE", "true")),
+
+		StorageDriver:    getenvDef("STORAGE_DRIVER", "s3"),
+		StorageLocalRoot: getenvDef("STORAGE_LOCAL_ROOT", "./storage"),
+		StorageLocalPublicUrl: getenvDef(
+			"STORAGE_LOCAL_PUBLIC_URL", fmt.Sprintf("http://localhost:%d/api/v1/storage", internal.ParseUint16(os.Getenv("HTTP_PORT"), 8002)),
+		),
 
 		MaxFileUploadSizeInBytes: internal.ParseInt(os.Getenv("MAX_FILE_UPLOAD_SIZE"), 10485760),
+
+		CdnCoversUrl:        getenvDef("CDN_COVERS_URL", internal.JoinUrl(os.Getenv("CDN_PUBLIC_URL"), os.Getenv("S3_COVERS_BUCKET_NAME"))),
+		CoverMaxSizeInBytes: int64(internal.ParseInt(os.Getenv("COVER_MAX_SIZE"), 26214400)),
+		CoverUploadTTL:      internal.ParseDuration(getenvDef("COVER_UPLOAD_TTL", "15m"), 15*time.Minute),
+
+		RbacPolicyFile: os.Getenv("RBAC_POLICY_FILE"),
+		RbacSyncAction: getenvDef("RBAC_SYNC_ACTION", "delete_links"),
+
+		AbacPolicyFile:   os.Getenv("ABAC_POLICY_FILE"),
+		AbacLogDecisions: internal.ParseBool(os.Getenv("ABAC_LOG_DECISIONS")),
+
+		GrpcTlsCertFile:       os.Getenv("GRPC_TLS_CERT_FILE"),
+		GrpcTlsKeyFile:        os.Getenv("GRPC_TLS_KEY_FILE"),
+		GrpcTlsClientCaFile:   os.Getenv("GRPC_TLS_CLIENT_CA_FILE"),
+		GrpcAuthEnabled:       internal.ParseBool(getenvDef("GRPC_AUTH_ENABLED", "true")),
+		GrpcClientPermissions: parseNamedLists(os.Getenv("GRPC_CLIENT_PERMISSIONS")),
+		GrpcRequestTimeout:    internal.ParseDuration(getenvDef("GRPC_REQUEST_TIMEOUT", "30s"), 30*time.Second),
+		GrpcReflectionEnabled: internal.ParseBool(os.Getenv("GRPC_REFLECTION_ENABLED")),
+
+		HealthCheckInterval: internal.ParseDuration(getenvDef("HEALTH_CHECK_INTERVAL", "10s"), 10*time.Second),
+		UserEventsMaxLen:    int64(internal.ParseInt(os.Getenv("USER_EVENTS_MAX_LEN"), 100000)),
+		ShutdownTimeout:     internal.ParseDuration(getenvDef("SHUTDOWN_TIMEOUT", "30s"), 30*time.Second),
+
+		EmailChangeTTL:         internal.ParseDuration(getenvDef("EMAIL_CHANGE_TTL", "24h"), 24*time.Hour),
+		UsernameChangeCooldown: internal.ParseDuration(getenvDef("USERNAME_CHANGE_COOLDOWN", "0s"), 0),
+
+		AuthCookieSecure:   internal.ParseBool(getenvDef("AUTH_COOKIE_SECURE", "true")),
+		AuthCookieSameSite: getenvDef("AUTH_COOKIE_SAME_SITE", "Lax"),
+		AuthCookieDomain:   os.Getenv("AUTH_COOKIE_DOMAIN"),
+
+		CorsAllowCredentials: internal.ParseBool(getenvDef("CORS_ALLOW_CREDENTIALS", "true")),
+		CorsMaxAge:           internal.ParseDuration(getenvDef("CORS_MAX_AGE", "24h"), 24*time.Hour),
+		CorsRouteOrigins:     parseNamedLists(os.Getenv("CORS_ROUTE_ORIGINS")),
+	}
+}
+
+// parseNamedLists parses "name=value value;name=value" list (e.g. permissions of clients)
+func parseNamedLists(value string) map[string][]string {
+	result := map[string][]string{}
+	for _, client := range strings.Split(value, ";") {
+		name, permissions, _ := strings.Cut(client, "=")
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+		result[name] = append(result[name], strings.Fields(permissions)...)
+	}
+	return result
+}
+
+// parseList parses comma separated list, empty items are skipped
+func parseList(value string) []string {
+	result := []string{}
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			result = append(result, item)
+		}
 	}
+	return result
 }
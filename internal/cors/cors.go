@@ -0,0 +1,157 @@
+package cors
+
+import (
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/G0tem/go-service-auth/internal/config"
+	"github.com/gofiber/fiber/v2"
+)
+
+// Methods - methods allowed for cross-origin requests
+var Methods = []string{
+	fiber.MethodGet, fiber.MethodHead, fiber.MethodPost, fiber.MethodPut, fiber.MethodPatch, fiber.MethodDelete,
+}
+
+// Policy allows cross-origin requests from Origins. Origins are exact ("https://app.example.com"),
+// wildcard subdomains ("https://*.example.com" matches any subdomain, but not example.com itself)
+// or "*" (any origin, credentials are never allowed for it as browsers reject them).
+type Policy struct {
+	Origins []string
+	Methods []string
+	// Headers are allowed request headers, headers requested by preflight are allowed if empty
+	Headers       []string
+	ExposeHeaders []string
+	// Credentials allows cookies and Authorization header of cross-origin requests
+	Credentials bool
+	MaxAge      time.Duration
+}
+
+// Override replaces the policy for paths under Prefix: the prefix itself and paths continuing it
+// with "/" ("/api/v1/storage" covers "/api/v1/storage/covers", but not "/api/v1/storage-admin").
+// The longest matching prefix wins.
+type Override struct {
+	Prefix string
+	Policy Policy
+}
+
+// FromConfig - policy of CORS_ORIGINS and overrides of CORS_ROUTE_ORIGINS
+func FromConfig(cfg *config.Config) (Policy, []Override) {
+	policy := Policy{
+		Origins:     cfg.CorsOrigins,
+		Methods:     Methods,
+		Credentials: cfg.CorsAllowCredentials,
+		MaxAge:      cfg.CorsMaxAge,
+	}
+	overrides := []Override{}
+	for prefix, origins := range cfg.CorsRouteOrigins {
+		override := policy
+		override.Origins = origins
+		overrides = append(overrides, Override{Prefix: prefix, Policy: override})
+	}
+	return policy, overrides
+}
+
+// AllowsOrigin - origin is allowed by the policy (origins are compared case-insensitively)
+func (p Policy) AllowsOrigin(origin string) bool {
+	origin = strings.ToLower(origin)
+	for _, allowed := range p.Origins {
+		if matchOrigin(strings.ToLower(allowed), origin) {
+			return true
+		}
+	}
+	return false
+}
+
+func (p Policy) anyOrigin() bool {
+	for _, allowed := range p.Origins {
+		if allowed == "*" {
+			return true
+		}
+	}
+	return false
+}
+
+func matchOrigin(pattern, origin string) bool {
+	if pattern == "*" || pattern == origin {
+		return true
+	}
+	prefix, suffix, found := strings.Cut(pattern, "*")
+	if !found || !strings.HasPrefix(suffix, ".") {
+		return false
+	}
+	if len(origin) <= len(prefix)+len(suffix) || !strings.HasPrefix(origin, prefix) || !strings.HasSuffix(origin, suffix) {
+		return false
+	}
+	// Subdomain can't contain port, path or credentials
+	subdomain := origin[len(prefix) : len(origin)-len(suffix)]
+	return !strings.ContainsAny(subdomain, ":/@?#") && !strings.HasPrefix(subdomain, ".")
+}
+
+// New creates middleware applying the policy (or its override for the route group) to cross-origin
+// requests, preflight requests are answered by the middleware. Responses vary by Origin,
+// preflight responses also by requested method and headers.
+func New(policy Policy, overrides ...Override) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		p := policyFor(c.Path(), policy, overrides)
+		preflight := c.Method() == fiber.MethodOptions && c.Get(fiber.HeaderAccessControlRequestMethod) != ""
+
+		c.Vary(fiber.HeaderOrigin)
+		if preflight {
+			c.Vary(fiber.HeaderAccessControlRequestMethod, fiber.HeaderAccessControlRequestHeaders)
+		}
+
+		origin := c.Get(fiber.HeaderOrigin)
+		if origin == "" || !p.AllowsOrigin(origin) {
+			if preflight {
+				// Without CORS headers the browser rejects the request
+				return c.SendStatus(fiber.StatusNoContent)
+			}
+			return c.Next()
+		}
+
+		if p.anyOrigin() {
+			c.Set(fiber.HeaderAccessControlAllowOrigin, "*")
+		} else {
+			c.Set(fiber.HeaderAccessControlAllowOrigin, origin)
+			if p.Credentials {
+				c.Set(fiber.HeaderAccessControlAllowCredentials, "true")
+			}
+		}
+
+		if !preflight {
+			if len(p.ExposeHeaders) > 0 {
+				c.Set(fiber.HeaderAccessControlExposeHeaders, strings.Join(p.ExposeHeaders, ", "))
+			}
+			return c.Next()
+		}
+
+		c.Set(fiber.HeaderAccessControlAllowMethods, strings.Join(p.Methods, ", "))
+		if len(p.Headers) > 0 {
+			c.Set(fiber.HeaderAccessControlAllowHeaders, strings.Join(p.Headers, ", "))
+		} else if requested := c.Get(fiber.HeaderAccessControlRequestHeaders); requested != "" {
+			c.Set(fiber.HeaderAccessControlAllowHeaders, requested)
+		}
+		if p.MaxAge > 0 {
+			c.Set(fiber.HeaderAccessControlMaxAge, strconv.Itoa(int(p.MaxAge.Seconds())))
+		}
+		return c.SendStatus(fiber.StatusNoContent)
+	}
+}
+
+func underPrefix(path, prefix string) bool {
+	prefix = strings.TrimSuffix(prefix, "/")
+	return path == prefix || strings.HasPrefix(path, prefix+"/")
+}
+
+func policyFor(path string, policy Policy, overrides []Override) Policy {
+	matched := ""
+	for _, override := range overrides {
+		if underPrefix(path, override.Prefix) && len(override.Prefix) > len(matched) {
+			matched = override.Prefix
+			policy = override.Policy
+		}
+	}
+	return policy
+}
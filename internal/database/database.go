@@ -47,14 +47,34 @@ func Connect(cfg config.Config) (*gorm.DB, error) {
 
 	log.Info().Msg("Connected")
 	log.Info().Msg("running migrations")
-	err = db.AutoMigrate(
-		&model.User{},
-		&model.UserRole{},
-		&model.UserPermission{},
-		&model.UserRolePermission{},
-	)
+	// Replicas started at the same time must not run migrations concurrently
+	err = WithAdvisoryLock(db, MigrationsLock, cfg.DbLockTimeout, func() error {
+		err := db.AutoMigrate(
+			&model.User{},
+			&model.UserRole{},
+			&model.UserPermission{},
+			&model.UserRolePermission{},
+			&model.UserRoleAssignment{},
+			&model.UserDirectPermission{},
+			&model.UserScopedRole{},
+			&model.UserScopedPermission{},
+			&model.RbacPolicyRevision{},
+			&model.UserSession{},
+		)
+		if err != nil {
+			log.Error().Msgf("failed run auto-migrations. %v\n", err)
+			return err
+		}
+
+		err = migrateUserRoleAssignments(db)
+		if err != nil {
+			log.Error().Msgf("failed migrate user roles. %v\n", err)
+			return err
+		}
+
+		return nil
+	})
 	if err != nil {
-		log.Error().Msgf("failed run auto-migrations. %v\n", err)
 		return nil, err
 	}
 
@@ -70,3 +90,16 @@ func Connect(cfg config.Config) (*gorm.DB, error) {
 
 	return db, nil
 }
+
+// migrateUserRoleAssignments copies legacy users.role_id values into role assignments.
+// Users that already have any assignment are skipped, so it is safe to run on every start.
+func migrateUserRoleAssignments(db *gorm.DB) error {
+	return db.Exec(`
+		INSERT INTO user_role_assignments (user_id, role_id, created_at)
+		SELECT users.id, users.role_id, now()
+		FROM users
+		JOIN user_roles ON user_roles.id = users.role_id
+		WHERE users.deleted_at IS NULL
+			AND NOT EXISTS (SELECT 1 FROM user_role_assignments a WHERE a.user_id = users.id)
+		ON CONFLICT DO NOTHING`).Error
+}
@@ -0,0 +1,64 @@
+package database
+
+import (
+	"fmt"
+	"hash/fnv"
+	"time"
+
+	"github.com/G0tem/go-service-auth/internal"
+	"github.com/rs/zerolog/log"
+	"gorm.io/gorm"
+)
+
+const (
+	// MigrationsLock guards auto-migrations
+	MigrationsLock = "auth:migrations"
+	// RbacSyncLock guards synchronization of roles and permissions
+	RbacSyncLock = "auth:rbac-sync"
+
+	DefaultLockTimeout = time.Minute
+	lockRetryInterval  = 500 * time.Millisecond
+)
+
+// LockKey - Postgres advisory lock key of the lock name
+func LockKey(name string) int64 {
+	hash := fnv.New64a()
+	_, _ = hash.Write([]byte(name))
+	return int64(hash.Sum64())
+}
+
+// WithAdvisoryLock - run fn holding Postgres session advisory lock, so replicas of the service
+// run it one by one. Returns error if the lock isn't acquired within timeout.
+func WithAdvisoryLock(db *gorm.DB, name string, timeout time.Duration, fn func() error) error {
+	if timeout <= 0 {
+		timeout = DefaultLockTimeout
+	}
+	key := LockKey(name)
+
+	// Advisory lock belongs to the connection, so lock and unlock must use the same one
+	return db.Connection(func(conn *gorm.DB) error {
+		deadline := time.Now().Add(timeout)
+		for {
+			var locked bool
+			if err := conn.Raw("SELECT pg_try_advisory_lock(?)", key).Scan(&locked).Error; err != nil {
+				return internal.PrintError(internal.ErrAcquiringLock, err)
+			}
+			if locked {
+				break
+			}
+			if time.Now().After(deadline) {
+				return internal.PrintError(internal.ErrAcquiringLock, fmt.Errorf("%v: timeout %v", name, timeout))
+			}
+			log.Debug().Msgf("waiting for lock %v", name)
+			time.Sleep(lockRetryInterval)
+		}
+
+		defer func() {
+			if err := conn.Exec("SELECT pg_advisory_unlock(?)", key).Error; err != nil {
+				log.Error().Msgf("failed to release lock %v. %v", name, err)
+			}
+		}()
+
+		return fn()
+	})
+}
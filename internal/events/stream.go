@@ -0,0 +1,208 @@
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/google/uuid"
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	// UserEventsStream is the Redis stream key of user change events
+	UserEventsStream = "auth:user-events"
+	// DefaultMaxLen is the default approximate number of events retained in the stream
+	DefaultMaxLen = 100000
+	// CursorLatest is the cursor of events published after the read has started
+	CursorLatest = "$"
+	// CursorOldest is the cursor of all events retained in the stream
+	CursorOldest = "0"
+)
+
+// Types of user change events
+const (
+	UserUpdated                 = "user.updated"
+	UserDeactivated             = "user.deactivated"
+	UserActivated               = "user.activated"
+	UserDeleted                 = "user.deleted"
+	UserPasswordChanged         = "user.password_changed"
+	UserSessionsRevoked         = "user.sessions_revoked"
+	UserRoleGranted             = "user.role_granted"
+	UserRoleRevoked             = "user.role_revoked"
+	UserPermissionGranted       = "user.permission_granted"
+	UserPermissionDenied        = "user.permission_denied"
+	UserPermissionRevoked       = "user.permission_revoked"
+	UserScopedRoleGranted       = "user.scoped_role_granted"
+	UserScopedRoleRevoked       = "user.scoped_role_revoked"
+	UserScopedPermissionGranted = "user.scoped_permission_granted"
+	UserScopedPermissionRevoked = "user.scoped_permission_revoked"
+	// PermissionsChanged - roles, permissions or links between them have changed (affects any user, UserID is empty)
+	PermissionsChanged = "rbac.permissions_changed"
+)
+
+// Event - change of the user (or of RBAC model if UserID is empty)
+type Event struct {
+	// ID is the stream entry id, it is the cursor to resume reading after the event
+	ID     string            `json:"id"`
+	Type   string            `json:"type"`
+	UserID string            `json:"user_id,omitempty"`
+	Data   map[string]string `json:"data,omitempty"`
+	Time   time.Time         `json:"time"`
+}
+
+// Stream - durable log of user change events in a Redis stream. Consumers read it from any
+// retained position, so they can reconnect without missing events. Nil stream publishes nothing.
+type Stream struct {
+	redis  *redis.Client
+	key    string
+	maxLen int64
+}
+
+func NewStream(redisClient *redis.Client, maxLen int64) *Stream {
+	if maxLen <= 0 {
+		maxLen = DefaultMaxLen
+	}
+	return &Stream{redis: redisClient, key: UserEventsStream, maxLen: maxLen}
+}
+
+// Publish - append event of the user (uuid.Nil for RBAC model events), failures are only logged
+// because events are published after the change is committed
+func (stream *Stream) Publish(ctx context.Context, eventType string, userId uuid.UUID, data map[string]string) {
+	if stream == nil {
+		return
+	}
+
+	values := map[string]any{
+		"type": eventType,
+		"time": time.Now().UTC().Format(time.RFC3339Nano),
+	}
+	if userId != uuid.Nil {
+		values["user_id"] = userId.String()
+	}
+	if len(data) > 0 {
+		encoded, err := json.Marshal(data)
+		if err != nil {
+			log.Error().Err(err).Msgf("user events: encode %v", eventType)
+			return
+		}
+		values["data"] = string(encoded)
+	}
+
+	err := stream.redis.XAdd(ctx, &redis.XAddArgs{
+		Stream: stream.key,
+		MaxLen: stream.maxLen,
+		Approx: true,
+		Values: values,
+	}).Err()
+	if err != nil {
+		log.Error().Err(err).Msgf("user events: publish %v", eventType)
+	}
+}
+
+// Read - events after cursor (CursorLatest waits for new ones), blocks up to block duration.
+// No events and no error is returned if nothing was published in time.
+func (stream *Stream) Read(ctx context.Context, cursor string, count int64, block time.Duration) ([]Event, error) {
+	streams, err := stream.redis.XRead(ctx, &redis.XReadArgs{
+		Streams: []string{stream.key, cursor},
+		Count:   count,
+		Block:   block,
+	}).Result()
+	if err == redis.Nil {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var result []Event
+	for _, s := range streams {
+		for _, message := range s.Messages {
+			result = append(result, decodeEvent(message))
+		}
+	}
+	return result, nil
+}
+
+// LastID - id of the last event in the stream (CursorOldest if the stream is empty)
+func (stream *Stream) LastID(ctx context.Context) (string, error) {
+	messages, err := stream.redis.XRevRangeN(ctx, stream.key, "+", "-", 1).Result()
+	if err != nil || len(messages) == 0 {
+		return CursorOldest, err
+	}
+	return messages[0].ID, nil
+}
+
+// FirstID - id of the oldest retained event (empty if the stream is empty)
+func (stream *Stream) FirstID(ctx context.Context) (string, error) {
+	messages, err := stream.redis.XRangeN(ctx, stream.key, "-", "+", 1).Result()
+	if err != nil || len(messages) == 0 {
+		return "", err
+	}
+	return messages[0].ID, nil
+}
+
+func decodeEvent(message redis.XMessage) Event {
+	event := Event{ID: message.ID}
+	event.Type, _ = message.Values["type"].(string)
+	event.UserID, _ = message.Values["user_id"].(string)
+	if value, ok := message.Values["time"].(string); ok {
+		event.Time, _ = time.Parse(time.RFC3339Nano, value)
+	}
+	if value, ok := message.Values["data"].(string); ok {
+		_ = json.Unmarshal([]byte(value), &event.Data)
+	}
+	return event
+}
+
+// ParseID - parse stream entry id ("<milliseconds>-<sequence>", sequence is optional)
+func ParseID(id string) (ms, seq uint64, err error) {
+	msPart, seqPart, hasSeq := strings.Cut(id, "-")
+	if ms, err = strconv.ParseUint(msPart, 10, 64); err != nil {
+		return 0, 0, fmt.Errorf("invalid cursor %q", id)
+	}
+	if hasSeq {
+		if seq, err = strconv.ParseUint(seqPart, 10, 64); err != nil {
+			return 0, 0, fmt.Errorf("invalid cursor %q", id)
+		}
+	}
+	return ms, seq, nil
+}
+
+// CompareIDs - -1, 0 or 1 if stream entry id a is before, equal or after b
+func CompareIDs(a, b string) (int, error) {
+	aMs, aSeq, err := ParseID(a)
+	if err != nil {
+		return 0, err
+	}
+	bMs, bSeq, err := ParseID(b)
+	if err != nil {
+		return 0, err
+	}
+	switch {
+	case aMs < bMs || (aMs == bMs && aSeq < bSeq):
+		return -1, nil
+	case aMs == bMs && aSeq == bSeq:
+		return 0, nil
+	}
+	return 1, nil
+}
+
+// Filter - selects events by type and user, empty fields match anything.
+// RBAC model events (without user) match any user because they may change permissions of everyone.
+type Filter struct {
+	Types  []string
+	UserID string
+}
+
+func (filter Filter) Match(event Event) bool {
+	if len(filter.Types) > 0 && !slices.Contains(filter.Types, event.Type) {
+		return false
+	}
+	return filter.UserID == "" || event.UserID == "" || event.UserID == filter.UserID
+}
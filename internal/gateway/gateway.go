@@ -0,0 +1,79 @@
+package gateway
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net/http"
+
+	"github.com/G0tem/go-service-auth/internal/config"
+	"github.com/G0tem/go-service-auth/proto"
+	"github.com/gofiber/fiber/v2"
+	"github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/protobuf/encoding/protojson"
+)
+
+// Prefix is the path of REST endpoints transcoded to gRPC (relative to it are the paths of proto HTTP annotations)
+const Prefix = "/api/v1/gateway"
+
+// Services are the gRPC services exposed by the gateway. AuthAdminService is for services only and is
+// never exposed on the public port.
+var Services = []string{"AuthService"}
+
+// Gateway - REST/JSON endpoints generated from proto HTTP annotations (methods without annotations are
+// available as POST <Prefix>/<package.Service>/<Method>). Requests are proxied to the gRPC server of
+// the service, so they pass the same authentication (Authorization header is sent as metadata).
+type Gateway struct {
+	mux  *runtime.ServeMux
+	conn *grpc.ClientConn
+}
+
+// New connects to the gRPC server of the service and registers handlers of its services
+func New(ctx context.Context, cfg *config.Config) (*Gateway, error) {
+	creds := insecure.NewCredentials()
+	if cfg.GrpcTlsCertFile != "" {
+		// The server is the same process listening on loopback, its certificate is issued for external names
+		creds = credentials.NewTLS(&tls.Config{InsecureSkipVerify: true, MinVersion: tls.VersionTLS12})
+	}
+	conn, err := grpc.NewClient(fmt.Sprintf("localhost:%d", cfg.GrpcPort), grpc.WithTransportCredentials(creds))
+	if err != nil {
+		return nil, fmt.Errorf("gateway connection: %v", err)
+	}
+
+	mux := runtime.NewServeMux(
+		// Field names are the same as in proto files and in the rest of HTTP API (snake_case)
+		runtime.WithMarshalerOption(runtime.MIMEWildcard, &runtime.JSONPb{
+			MarshalOptions:   protojson.MarshalOptions{UseProtoNames: true, EmitUnpopulated: true},
+			UnmarshalOptions: protojson.UnmarshalOptions{DiscardUnknown: true},
+		}),
+	)
+	registrations := []func(context.Context, *runtime.ServeMux, *grpc.ClientConn) error{
+		proto.RegisterAuthServiceHandler,
+	}
+	for _, register := range registrations {
+		if err = register(ctx, mux, conn); err != nil {
+			_ = conn.Close()
+			return nil, fmt.Errorf("gateway registration: %v", err)
+		}
+	}
+
+	return &Gateway{mux: mux, conn: conn}, nil
+}
+
+// Mount serves gateway endpoints under Prefix, requests pass guards (authentication and access checks
+// of HTTP API) before they are proxied
+func (g *Gateway) Mount(app *fiber.App, guards ...fiber.Handler) {
+	args := []any{Prefix}
+	for _, guard := range guards {
+		args = append(args, guard)
+	}
+	app.Use(append(args, streamHandler(http.StripPrefix(Prefix, g.mux)))...)
+}
+
+// Close closes connection to the gRPC server
+func (g *Gateway) Close() error {
+	return g.conn.Close()
+}
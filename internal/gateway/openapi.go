@@ -0,0 +1,117 @@
+package gateway
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+)
+
+// SecurityDefinition is the swag security definition of JWT, gateway operations require it
+const SecurityDefinition = "ApiKeyAuth"
+
+// MergeOpenAPI adds paths, definitions and tags of the gateway OpenAPI document (generated from protos
+// by protoc-gen-openapiv2) to the swag document. Gateway paths are prefixed with Prefix relative to the
+// swag basePath, existing swag paths and definitions are kept. Operations and tags of services
+// not exposed by the gateway (see Services) are skipped.
+func MergeOpenAPI(swagDoc, gatewayDoc []byte) ([]byte, error) {
+	var swag, gateway map[string]any
+	if err := json.Unmarshal(swagDoc, &swag); err != nil {
+		return nil, fmt.Errorf("swag document: %v", err)
+	}
+	if err := json.Unmarshal(gatewayDoc, &gateway); err != nil {
+		return nil, fmt.Errorf("gateway document: %v", err)
+	}
+
+	basePath, _ := swag["basePath"].(string)
+	prefix := Prefix
+	if basePath != "" && basePath != "/" {
+		if !strings.HasPrefix(Prefix, basePath+"/") {
+			return nil, fmt.Errorf("gateway prefix %v is outside of base path %v", Prefix, basePath)
+		}
+		prefix = strings.TrimPrefix(Prefix, basePath)
+	}
+
+	_, secured := object(swag, "securityDefinitions")[SecurityDefinition]
+	paths := object(swag, "paths")
+	for path, item := range object(gateway, "paths") {
+		operations, ok := item.(map[string]any)
+		if !ok {
+			continue
+		}
+		for method, operation := range operations {
+			operation, ok := operation.(map[string]any)
+			if !ok {
+				continue
+			}
+			if !exposed(operation) {
+				delete(operations, method)
+				continue
+			}
+			if secured && operation["security"] == nil {
+				operation["security"] = []any{map[string]any{SecurityDefinition: []any{}}}
+			}
+		}
+		if len(operations) == 0 {
+			continue
+		}
+		if _, exists := paths[prefix+path]; !exists {
+			paths[prefix+path] = operations
+		}
+	}
+
+	definitions := object(swag, "definitions")
+	for name, definition := range object(gateway, "definitions") {
+		if _, exists := definitions[name]; !exists {
+			definitions[name] = definition
+		}
+	}
+
+	tags, _ := swag["tags"].([]any)
+	if gatewayTags, ok := gateway["tags"].([]any); ok {
+		for _, tag := range gatewayTags {
+			if tag, ok := tag.(map[string]any); ok && slices.Contains(Services, fmt.Sprint(tag["name"])) {
+				tags = append(tags, tag)
+			}
+		}
+		swag["tags"] = tags
+	}
+
+	return json.MarshalIndent(swag, "", "    ")
+}
+
+// MergeOpenAPIFiles reads and merges swag and gateway documents (see MergeOpenAPI)
+func MergeOpenAPIFiles(swagFile, gatewayFile string) ([]byte, error) {
+	swagDoc, err := os.ReadFile(swagFile)
+	if err != nil {
+		return nil, err
+	}
+	gatewayDoc, err := os.ReadFile(gatewayFile)
+	if err != nil {
+		return nil, err
+	}
+	return MergeOpenAPI(swagDoc, gatewayDoc)
+}
+
+// exposed - operation belongs to a service of the gateway (protoc-gen-openapiv2 tags operations
+// with the service name)
+func exposed(operation map[string]any) bool {
+	tags, _ := operation["tags"].([]any)
+	for _, tag := range tags {
+		if slices.Contains(Services, fmt.Sprint(tag)) {
+			return true
+		}
+	}
+	return false
+}
+
+// object returns JSON object stored under key, creating it if missing
+func object(doc map[string]any, key string) map[string]any {
+	value, ok := doc[key].(map[string]any)
+	if !ok {
+		value = map[string]any{}
+		doc[key] = value
+	}
+	return value
+}
@@ -0,0 +1,98 @@
+package gateway
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"net/http"
+	"sync"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/valyala/fasthttp/fasthttpadaptor"
+)
+
+// streamHandler serves net/http handler in Fiber without buffering the response (adaptor.HTTPHandler
+// sends the response only after the handler returns), so server streaming methods are delivered
+// to the client message by message
+func streamHandler(handler http.Handler) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		var req http.Request
+		if err := fasthttpadaptor.ConvertRequest(c.Context(), &req, true); err != nil {
+			return err
+		}
+		ctx, cancel := context.WithCancel(c.UserContext())
+		reader, writer := io.Pipe()
+		w := &streamWriter{header: http.Header{}, body: writer, started: make(chan struct{})}
+
+		go func() {
+			defer cancel()
+			handler.ServeHTTP(w, req.WithContext(ctx))
+			w.start()
+			_ = writer.Close()
+		}()
+		<-w.started
+
+		c.Status(w.status)
+		for key, values := range w.header {
+			for _, value := range values {
+				c.Response().Header.Add(key, value)
+			}
+		}
+		c.Context().SetBodyStreamWriter(func(out *bufio.Writer) {
+			buf := make([]byte, 32*1024)
+			for {
+				n, err := reader.Read(buf)
+				if n > 0 {
+					_, writeErr := out.Write(buf[:n])
+					if writeErr == nil {
+						writeErr = out.Flush()
+					}
+					if writeErr != nil {
+						// Client has gone, stop the handler
+						cancel()
+						_ = reader.CloseWithError(writeErr)
+						return
+					}
+				}
+				if err != nil {
+					return
+				}
+			}
+		})
+		return nil
+	}
+}
+
+// streamWriter passes the body to the stream writer of Fiber, status and headers are sent
+// when the handler writes the body, flushes or returns
+type streamWriter struct {
+	header  http.Header
+	status  int
+	body    *io.PipeWriter
+	started chan struct{}
+	once    sync.Once
+}
+
+func (w *streamWriter) Header() http.Header {
+	return w.header
+}
+
+func (w *streamWriter) WriteHeader(status int) {
+	w.once.Do(func() {
+		w.status = status
+		close(w.started)
+	})
+}
+
+func (w *streamWriter) Write(data []byte) (int, error) {
+	w.start()
+	return w.body.Write(data)
+}
+
+func (w *streamWriter) Flush() {
+	w.start()
+}
+
+func (w *streamWriter) start() {
+	w.WriteHeader(http.StatusOK)
+}
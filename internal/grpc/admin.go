@@ -0,0 +1,346 @@
+package grpc
+
+import (
+	"context"
+	"time"
+
+	"github.com/G0tem/go-service-auth/internal"
+	"github.com/G0tem/go-service-auth/internal/handler/rbac"
+	"github.com/G0tem/go-service-auth/internal/model"
+	"github.com/G0tem/go-service-auth/internal/service"
+	"github.com/G0tem/go-service-auth/internal/types"
+	"github.com/G0tem/go-service-auth/proto"
+	"github.com/google/uuid"
+	"github.com/rs/zerolog/log"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// AdminServer реализует gRPC сервис администрирования поверх общего сервисного слоя
+type AdminServer struct {
+	proto.UnimplementedAuthAdminServiceServer
+	svc *service.Service
+}
+
+// NewAdminServer создает сервис администрирования
+func NewAdminServer(svc *service.Service) *AdminServer {
+	return &AdminServer{svc: svc}
+}
+
+// ListUsers возвращает страницу пользователей
+func (s *AdminServer) ListUsers(ctx context.Context, req *proto.ListUsersRequest) (*proto.ListUsersResponse, error) {
+	users, pagination, err := s.svc.ListUsers(ctx,
+		types.PaginationRequest{Page: int(req.Page), PageSize: int(req.PageSize)},
+		types.UserFilter{Search: req.Search},
+		nil,
+	)
+	if err != nil {
+		return nil, statusFromError(err)
+	}
+
+	return &proto.ListUsersResponse{
+		Users:        internal.Mapping(users, func(x model.User) *proto.AdminUser { return s.toAdminUser(&x) }),
+		CurrentPage:  int32(pagination.CurrentPage),
+		PageSize:     int32(pagination.PageSize),
+		TotalPages:   int32(pagination.TotalPages),
+		TotalRecords: pagination.TotalRecords,
+	}, nil
+}
+
+// GetUser возвращает пользователя по ID
+func (s *AdminServer) GetUser(ctx context.Context, req *proto.GetUserRequest) (*proto.AdminUser, error) {
+	userId, err := parseUserId(req.UserId)
+	if err != nil {
+		return nil, err
+	}
+	user, err := s.svc.GetUser(ctx, userId)
+	if err != nil {
+		return nil, statusFromError(err)
+	}
+	return s.toAdminUser(user), nil
+}
+
+// CreateUser создает пользователя
+func (s *AdminServer) CreateUser(ctx context.Context, req *proto.CreateUserRequest) (*proto.AdminUser, error) {
+	log.Info().
+		Str("username", req.Username).
+		Strs("roles", req.Roles).
+		Msg("Received CreateUser gRPC request")
+
+	// Назначение ролей требует тех же прав, что и GrantUser
+	if len(req.Roles) > 0 {
+		if caller, ok := CallerFromContext(ctx); ok && !rbac.NewPermissionSet(caller.Permissions...).Allows("rbac:manage") {
+			return nil, status.Errorf(codes.PermissionDenied, "%v is not permitted to assign roles", caller.Name)
+		}
+	}
+
+	user, err := s.svc.CreateUser(ctx, types.CreateUserRequest{
+		Username:       req.Username,
+		Email:          req.Email,
+		Password:       req.Password,
+		Roles:          req.Roles,
+		EmailConfirmed: req.EmailConfirmed,
+	})
+	if err != nil {
+		return nil, statusFromError(err)
+	}
+	return s.toAdminUser(user), nil
+}
+
+// UpdateUser изменяет указанные поля пользователя
+func (s *AdminServer) UpdateUser(ctx context.Context, req *proto.UpdateUserRequest) (*proto.AdminUser, error) {
+	log.Info().Str("user_id", req.UserId).Msg("Received UpdateUser gRPC request")
+
+	userId, err := parseUserId(req.UserId)
+	if err != nil {
+		return nil, err
+	}
+	user, err := s.svc.UpdateUser(ctx, userId, types.UpdateUserRequest{
+		Username:       req.Username,
+		Email:          req.Email,
+		Password:       req.Password,
+		IsActive:       req.IsActive,
+		EmailConfirmed: req.EmailConfirmed,
+	})
+	if err != nil {
+		return nil, statusFromError(err)
+	}
+	return s.toAdminUser(user), nil
+}
+
+// DeleteUser удаляет пользователя
+func (s *AdminServer) DeleteUser(ctx context.Context, req *proto.GetUserRequest) (*proto.AdminEmpty, error) {
+	log.Info().Str("user_id", req.UserId).Msg("Received DeleteUser gRPC request")
+
+	userId, err := parseUserId(req.UserId)
+	if err != nil {
+		return nil, err
+	}
+	if err = s.svc.DeleteUser(ctx, userId); err != nil {
+		return nil, statusFromError(err)
+	}
+	return &proto.AdminEmpty{}, nil
+}
+
+// RevokeUserSessions отзывает все выданные пользователю токены
+func (s *AdminServer) RevokeUserSessions(ctx context.Context, req *proto.GetUserRequest) (*proto.AdminEmpty, error) {
+	log.Info().Str("user_id", req.UserId).Msg("Received RevokeUserSessions gRPC request")
+
+	userId, err := parseUserId(req.UserId)
+	if err != nil {
+		return nil, err
+	}
+	if err = s.svc.RevokeUserSessions(ctx, userId); err != nil {
+		return nil, statusFromError(err)
+	}
+	return &proto.AdminEmpty{}, nil
+}
+
+// ListRoles возвращает все роли
+func (s *AdminServer) ListRoles(ctx context.Context, req *proto.AdminEmpty) (*proto.ListRolesResponse, error) {
+	roles, err := s.svc.ListRoles()
+	if err != nil {
+		return nil, statusFromError(err)
+	}
+
+	names := make(map[uuid.UUID]string, len(roles))
+	for _, role := range roles {
+		names[role.ID] = role.Name
+	}
+	return &proto.ListRolesResponse{
+		Roles: internal.Mapping(roles, func(x model.UserRole) *proto.Role {
+			role := toRole(x)
+			if x.ParentID != nil {
+				role.Parent = names[*x.ParentID]
+			}
+			return role
+		}),
+	}, nil
+}
+
+// CreateRole создает роль
+func (s *AdminServer) CreateRole(ctx context.Context, req *proto.CreateRoleRequest) (*proto.Role, error) {
+	log.Info().Str("role", req.Name).Msg("Received CreateRole gRPC request")
+
+	role, err := s.svc.CreateRole(req.Name, req.Description)
+	if err != nil {
+		return nil, statusFromError(err)
+	}
+	return toRole(role), nil
+}
+
+// DeleteRole удаляет роль
+func (s *AdminServer) DeleteRole(ctx context.Context, req *proto.RoleRequest) (*proto.AdminEmpty, error) {
+	log.Info().Str("role", req.Name).Msg("Received DeleteRole gRPC request")
+
+	if err := s.svc.DeleteRole(req.Name); err != nil {
+		return nil, statusFromError(err)
+	}
+	return &proto.AdminEmpty{}, nil
+}
+
+// SetRoleParent задает родительскую роль
+func (s *AdminServer) SetRoleParent(ctx context.Context, req *proto.SetRoleParentRequest) (*proto.Role, error) {
+	log.Info().
+		Str("role", req.Name).
+		Str("parent", req.Parent).
+		Msg("Received SetRoleParent gRPC request")
+
+	role, err := s.svc.SetRoleParent(req.Name, req.Parent)
+	if err != nil {
+		return nil, statusFromError(err)
+	}
+	result := toRole(role)
+	result.Parent = req.Parent
+	return result, nil
+}
+
+// ListPermissions возвращает все права
+func (s *AdminServer) ListPermissions(ctx context.Context, req *proto.AdminEmpty) (*proto.ListPermissionsResponse, error) {
+	permissions, err := s.svc.ListPermissions()
+	if err != nil {
+		return nil, statusFromError(err)
+	}
+	return &proto.ListPermissionsResponse{
+		Permissions: internal.Mapping(permissions, func(x model.UserPermission) *proto.Permission { return toPermission(&x) }),
+	}, nil
+}
+
+// CreatePermission создает право
+func (s *AdminServer) CreatePermission(ctx context.Context, req *proto.PermissionRequest) (*proto.Permission, error) {
+	log.Info().
+		Str("permission", req.Model+":"+req.Action).
+		Msg("Received CreatePermission gRPC request")
+
+	permission, err := s.svc.CreatePermission(req.Model, req.Action)
+	if err != nil {
+		return nil, statusFromError(err)
+	}
+	return toPermission(permission), nil
+}
+
+// DeletePermission удаляет право
+func (s *AdminServer) DeletePermission(ctx context.Context, req *proto.PermissionRequest) (*proto.AdminEmpty, error) {
+	log.Info().
+		Str("permission", req.Model+":"+req.Action).
+		Msg("Received DeletePermission gRPC request")
+
+	if err := s.svc.DeletePermission(req.Model, req.Action); err != nil {
+		return nil, statusFromError(err)
+	}
+	return &proto.AdminEmpty{}, nil
+}
+
+// AddRolePermission разрешает (или запрещает) право роли
+func (s *AdminServer) AddRolePermission(ctx context.Context, req *proto.RolePermissionRequest) (*proto.AdminEmpty, error) {
+	log.Info().
+		Str("role", req.Role).
+		Str("permission", req.Model+":"+req.Action).
+		Bool("deny", req.Deny).
+		Msg("Received AddRolePermission gRPC request")
+
+	if err := s.svc.AddRolePermission(req.Role, req.Model, req.Action, req.Deny); err != nil {
+		return nil, statusFromError(err)
+	}
+	return &proto.AdminEmpty{}, nil
+}
+
+// RemoveRolePermission убирает право из роли
+func (s *AdminServer) RemoveRolePermission(ctx context.Context, req *proto.RolePermissionRequest) (*proto.AdminEmpty, error) {
+	log.Info().
+		Str("role", req.Role).
+		Str("permission", req.Model+":"+req.Action).
+		Msg("Received RemoveRolePermission gRPC request")
+
+	if err := s.svc.RemoveRolePermission(req.Role, req.Model, req.Action); err != nil {
+		return nil, statusFromError(err)
+	}
+	return &proto.AdminEmpty{}, nil
+}
+
+// GrantUser выдает пользователю роль или право
+func (s *AdminServer) GrantUser(ctx context.Context, req *proto.UserGrantRequest) (*proto.AdminEmpty, error) {
+	return s.changeUserGrant(req, false)
+}
+
+// RevokeUser отзывает у пользователя роль или право
+func (s *AdminServer) RevokeUser(ctx context.Context, req *proto.UserGrantRequest) (*proto.AdminEmpty, error) {
+	return s.changeUserGrant(req, true)
+}
+
+func (s *AdminServer) changeUserGrant(req *proto.UserGrantRequest, revoke bool) (*proto.AdminEmpty, error) {
+	log.Info().
+		Str("user_id", req.UserId).
+		Str("role", req.Role).
+		Str("permission", req.Permission).
+		Str("resource_type", req.ResourceType).
+		Str("resource_id", req.ResourceId).
+		Bool("revoke", revoke).
+		Msg("Received user grant gRPC request")
+
+	userId, err := parseUserId(req.UserId)
+	if err != nil {
+		return nil, err
+	}
+
+	input := types.UserGrantRequest{Role: req.Role, Permission: req.Permission, Deny: req.Deny}
+	if req.ResourceType != "" || req.ResourceId != "" {
+		input.Resource = &types.Resource{Type: req.ResourceType, ID: req.ResourceId}
+	}
+	if err = s.svc.ChangeUserGrant(userId, input, revoke); err != nil {
+		return nil, statusFromError(err)
+	}
+	return &proto.AdminEmpty{}, nil
+}
+
+func (s *AdminServer) toAdminUser(user *model.User) *proto.AdminUser {
+	response := s.svc.ToUserResponse(user)
+	return &proto.AdminUser{
+		UserId:         response.ID,
+		Username:       response.Username,
+		Email:          response.Email,
+		EmailConfirmed: response.EmailConfirmed,
+		IsActive:       response.IsActive,
+		Role:           response.Role,
+		AvatarUrl:      response.AvatarURL,
+		CreatedAt:      response.CreatedAt.Format(time.RFC3339),
+		UpdatedAt:      response.UpdatedAt.Format(time.RFC3339),
+	}
+}
+
+func toRole(role model.UserRole) *proto.Role {
+	return &proto.Role{
+		Id:          role.ID.String(),
+		Name:        role.Name,
+		Description: role.Description,
+	}
+}
+
+func toPermission(permission *model.UserPermission) *proto.Permission {
+	return &proto.Permission{
+		Id:     permission.ID.String(),
+		Model:  permission.Model,
+		Action: permission.Action,
+	}
+}
+
+func parseUserId(value string) (uuid.UUID, error) {
+	userId, err := uuid.Parse(value)
+	if err != nil {
+		return uuid.Nil, status.Errorf(codes.InvalidArgument, "invalid user_id: %v", err)
+	}
+	return userId, nil
+}
+
+// statusFromError преобразует ошибку сервисного слоя в статус gRPC
+func statusFromError(err error) error {
+	switch service.Kind(err) {
+	case service.ErrInvalidArgument:
+		return status.Error(codes.InvalidArgument, err.Error())
+	case service.ErrNotFound:
+		return status.Error(codes.NotFound, err.Error())
+	case service.ErrAlreadyExists:
+		return status.Error(codes.AlreadyExists, err.Error())
+	}
+	return status.Error(codes.Internal, err.Error())
+}
@@ -0,0 +1,99 @@
+package grpc
+
+import (
+	"time"
+
+	"github.com/G0tem/go-service-auth/internal/events"
+	"github.com/G0tem/go-service-auth/proto"
+	"github.com/google/uuid"
+	"github.com/rs/zerolog/log"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	// watchBatchSize - максимальное число событий, читаемых из потока за раз
+	watchBatchSize = 100
+	// watchBlock - время ожидания новых событий, после которого проверяется отключение клиента
+	watchBlock = 5 * time.Second
+)
+
+// WatchUserEvents передает события изменения пользователей, их ролей и прав.
+// Клиент продолжает чтение после переподключения с курсором последнего полученного события.
+func (s *AuthServer) WatchUserEvents(req *proto.WatchUserEventsRequest, stream grpc.ServerStreamingServer[proto.UserEvent]) error {
+	log.Info().
+		Strs("event_types", req.EventTypes).
+		Str("user_id", req.UserId).
+		Str("cursor", req.Cursor).
+		Msg("Received WatchUserEvents gRPC request")
+
+	if s.rbac.Events == nil {
+		return status.Error(codes.FailedPrecondition, "user events are not configured")
+	}
+	if req.UserId != "" {
+		if _, err := uuid.Parse(req.UserId); err != nil {
+			return status.Errorf(codes.InvalidArgument, "invalid user_id: %v", err)
+		}
+	}
+
+	ctx := stream.Context()
+	cursor := req.Cursor
+	if cursor == "" {
+		// "$" нельзя использовать повторно между чтениями, поэтому запоминаем последнее событие
+		last, err := s.rbac.Events.LastID(ctx)
+		if err != nil {
+			return status.Errorf(codes.Unavailable, "read user events: %v", err)
+		}
+		cursor = last
+	} else if cursor != events.CursorOldest {
+		if _, _, err := events.ParseID(cursor); err != nil {
+			return status.Error(codes.InvalidArgument, err.Error())
+		}
+		// События старше курсора могли быть удалены из потока - клиент должен перечитать состояние
+		first, err := s.rbac.Events.FirstID(ctx)
+		if err != nil {
+			return status.Errorf(codes.Unavailable, "read user events: %v", err)
+		}
+		if first != "" {
+			if cmp, _ := events.CompareIDs(cursor, first); cmp < 0 {
+				return status.Errorf(codes.OutOfRange, "cursor %v is older than retained events (oldest %v)", cursor, first)
+			}
+		}
+	}
+
+	filter := events.Filter{Types: req.EventTypes, UserID: req.UserId}
+	for {
+		select {
+		case <-s.shutdown:
+			// Клиент переподключается к другой реплике с последним курсором
+			return status.Error(codes.Unavailable, "server is shutting down")
+		default:
+		}
+
+		batch, err := s.rbac.Events.Read(ctx, cursor, watchBatchSize, watchBlock)
+		if ctx.Err() != nil {
+			return nil
+		}
+		if err != nil {
+			return status.Errorf(codes.Unavailable, "read user events: %v", err)
+		}
+
+		for _, event := range batch {
+			cursor = event.ID
+			if !filter.Match(event) {
+				continue
+			}
+			err = stream.Send(&proto.UserEvent{
+				Id:     event.ID,
+				Type:   event.Type,
+				UserId: event.UserID,
+				Data:   event.Data,
+				Time:   event.Time.Format(time.RFC3339),
+			})
+			if err != nil {
+				return err
+			}
+		}
+	}
+}
@@ -0,0 +1,112 @@
+package grpc
+
+import (
+	"context"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	amqp "github.com/rabbitmq/amqp091-go"
+	"github.com/rs/zerolog/log"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"gorm.io/gorm"
+)
+
+// Имена зависимостей в сервисе здоровья (пустое имя - общее состояние сервера)
+const (
+	HealthPostgres = "postgres"
+	HealthRedis    = "redis"
+	HealthRabbitMQ = "rabbitmq"
+
+	DefaultHealthCheckInterval = 10 * time.Second
+)
+
+// HealthChecker периодически проверяет зависимости и публикует их состояние
+// в стандартном сервисе grpc.health.v1
+type HealthChecker struct {
+	Server   *health.Server
+	db       *gorm.DB
+	redis    *redis.Client
+	rmqUrl   string
+	interval time.Duration
+}
+
+// NewHealthChecker создает проверку зависимостей (RabbitMQ проверяется, только если задан rmqUrl)
+func NewHealthChecker(db *gorm.DB, redisClient *redis.Client, rmqUrl string, interval time.Duration) *HealthChecker {
+	if interval <= 0 {
+		interval = DefaultHealthCheckInterval
+	}
+	return &HealthChecker{
+		Server:   health.NewServer(),
+		db:       db,
+		redis:    redisClient,
+		rmqUrl:   rmqUrl,
+		interval: interval,
+	}
+}
+
+// Run проверяет зависимости сразу и затем с заданным интервалом, пока не завершится ctx
+func (h *HealthChecker) Run(ctx context.Context) {
+	ticker := time.NewTicker(h.interval)
+	defer ticker.Stop()
+
+	for {
+		h.Check(ctx)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Check проверяет зависимости и обновляет их состояние
+func (h *HealthChecker) Check(ctx context.Context) {
+	ctx, cancel := context.WithTimeout(ctx, h.interval)
+	defer cancel()
+
+	checks := map[string]func(context.Context) error{
+		HealthPostgres: h.checkPostgres,
+		HealthRedis:    h.checkRedis,
+	}
+	if h.rmqUrl != "" {
+		checks[HealthRabbitMQ] = h.checkRabbitMQ
+	}
+
+	overall := healthpb.HealthCheckResponse_SERVING
+	for name, check := range checks {
+		status := healthpb.HealthCheckResponse_SERVING
+		if err := check(ctx); err != nil {
+			log.Warn().Err(err).Str("dependency", name).Msg("Health check failed")
+			status = healthpb.HealthCheckResponse_NOT_SERVING
+			overall = status
+		}
+		h.Server.SetServingStatus(name, status)
+	}
+	h.Server.SetServingStatus("", overall)
+}
+
+// Shutdown переводит все сервисы в NOT_SERVING (новые запросы не принимаются)
+func (h *HealthChecker) Shutdown() {
+	h.Server.Shutdown()
+}
+
+func (h *HealthChecker) checkPostgres(ctx context.Context) error {
+	sqlDB, err := h.db.DB()
+	if err != nil {
+		return err
+	}
+	return sqlDB.PingContext(ctx)
+}
+
+func (h *HealthChecker) checkRedis(ctx context.Context) error {
+	return h.redis.Ping(ctx).Err()
+}
+
+func (h *HealthChecker) checkRabbitMQ(context.Context) error {
+	conn, err := amqp.DialConfig(h.rmqUrl, amqp.Config{Dial: amqp.DefaultDial(h.interval)})
+	if err != nil {
+		return err
+	}
+	return conn.Close()
+}
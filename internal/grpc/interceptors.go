@@ -0,0 +1,256 @@
+package grpc
+
+import (
+	"context"
+	"runtime/debug"
+	"strings"
+	"time"
+
+	"github.com/G0tem/go-service-auth/internal/config"
+	"github.com/G0tem/go-service-auth/internal/handler/rbac"
+	"github.com/G0tem/go-service-auth/proto"
+	"github.com/rs/zerolog/log"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	CallerKindJWT         = "jwt"
+	CallerKindCertificate = "certificate"
+)
+
+// MethodPermissions - права, необходимые вызывающему сервису для каждого метода
+// (пустой список - достаточно аутентификации, методы не из списка запрещены)
+var MethodPermissions = map[string][]string{
+	proto.AuthService_GetTestData_FullMethodName:           {},
+	proto.AuthService_ValidateToken_FullMethodName:         {},
+	proto.AuthService_GetUserInfo_FullMethodName:           {"user:list"},
+	proto.AuthService_Can_FullMethodName:                   {"rbac:read"},
+	proto.AuthService_Authorize_FullMethodName:             {"rbac:read"},
+	proto.AuthService_CheckPermission_FullMethodName:       {"rbac:read"},
+	proto.AuthService_BatchCheckPermissions_FullMethodName: {"rbac:read"},
+	proto.AuthService_WatchUserEvents_FullMethodName:       {"user:list"},
+
+	proto.AuthAdminService_ListUsers_FullMethodName:            {"user:list"},
+	proto.AuthAdminService_GetUser_FullMethodName:              {"user:list"},
+	proto.AuthAdminService_CreateUser_FullMethodName:           {"user:manage"},
+	proto.AuthAdminService_UpdateUser_FullMethodName:           {"user:manage"},
+	proto.AuthAdminService_DeleteUser_FullMethodName:           {"user:manage"},
+	proto.AuthAdminService_RevokeUserSessions_FullMethodName:   {"user:manage"},
+	proto.AuthAdminService_ListRoles_FullMethodName:            {"rbac:read"},
+	proto.AuthAdminService_CreateRole_FullMethodName:           {"rbac:manage"},
+	proto.AuthAdminService_DeleteRole_FullMethodName:           {"rbac:manage"},
+	proto.AuthAdminService_SetRoleParent_FullMethodName:        {"rbac:manage"},
+	proto.AuthAdminService_ListPermissions_FullMethodName:      {"rbac:read"},
+	proto.AuthAdminService_CreatePermission_FullMethodName:     {"rbac:manage"},
+	proto.AuthAdminService_DeletePermission_FullMethodName:     {"rbac:manage"},
+	proto.AuthAdminService_AddRolePermission_FullMethodName:    {"rbac:manage"},
+	proto.AuthAdminService_RemoveRolePermission_FullMethodName: {"rbac:manage"},
+	proto.AuthAdminService_GrantUser_FullMethodName:            {"rbac:manage"},
+	proto.AuthAdminService_RevokeUser_FullMethodName:           {"rbac:manage"},
+}
+
+// serviceOnlyServices - сервисы, доступные только сервисам: клиентским сертификатам и JWT
+// с аудиторией handler.ServiceAudience (токены пользователей не принимаются, даже с нужными правами)
+var serviceOnlyServices = []string{
+	"/" + proto.AuthAdminService_ServiceDesc.ServiceName + "/",
+}
+
+// publicServices - сервисы, доступные без аутентификации (проверки здоровья балансировщиков и оркестратора)
+var publicServices = []string{
+	"/grpc.health.v1.Health/",
+}
+
+// Caller - аутентифицированный вызывающий сервис
+type Caller struct {
+	// Name - имя сервиса: username из JWT или common name клиентского сертификата
+	Name        string
+	Kind        string
+	Permissions []string
+	// Service - вызывающий является сервисом (клиентский сертификат или сервисный JWT), а не пользователем
+	Service bool
+}
+
+type callerKey struct{}
+
+// CallerFromContext возвращает вызывающий сервис, аутентифицированный перехватчиком
+func CallerFromContext(ctx context.Context) (*Caller, bool) {
+	caller, ok := ctx.Value(callerKey{}).(*Caller)
+	return caller, ok
+}
+
+// TokenAuthenticator проверяет JWT вызывающего и возвращает его с актуальными правами
+type TokenAuthenticator interface {
+	CallerFromToken(ctx context.Context, token string) (*Caller, error)
+}
+
+// Interceptors - перехватчики gRPC сервера: восстановление после паники, логирование,
+// ограничение времени выполнения и аутентификация вызывающего сервиса
+type Interceptors struct {
+	tokens            TokenAuthenticator
+	authEnabled       bool
+	clientPermissions map[string][]string
+	timeout           time.Duration
+}
+
+// NewInterceptors создает перехватчики по конфигурации, JWT вызывающих проверяет tokens
+func NewInterceptors(cfg *config.Config, tokens TokenAuthenticator) *Interceptors {
+	return &Interceptors{
+		tokens:            tokens,
+		authEnabled:       cfg.GrpcAuthEnabled,
+		clientPermissions: cfg.GrpcClientPermissions,
+		timeout:           cfg.GrpcRequestTimeout,
+	}
+}
+
+// Unary возвращает перехватчик unary-вызовов
+func (i *Interceptors) Unary() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (resp any, err error) {
+		start := time.Now()
+		defer func() {
+			if r := recover(); r != nil {
+				err = recovered(info.FullMethod, r)
+			}
+			logCall(ctx, info.FullMethod, start, err)
+		}()
+
+		ctx, cancel := i.withDeadline(ctx)
+		defer cancel()
+
+		if ctx, err = i.authenticate(ctx, info.FullMethod); err != nil {
+			return nil, err
+		}
+		return handler(ctx, req)
+	}
+}
+
+// Stream возвращает перехватчик потоковых вызовов
+func (i *Interceptors) Stream() grpc.StreamServerInterceptor {
+	return func(srv any, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) (err error) {
+		ctx := stream.Context()
+		start := time.Now()
+		defer func() {
+			if r := recover(); r != nil {
+				err = recovered(info.FullMethod, r)
+			}
+			logCall(ctx, info.FullMethod, start, err)
+		}()
+
+		// Потоки живут долго, поэтому ограничение времени к ним не применяется
+		if ctx, err = i.authenticate(ctx, info.FullMethod); err != nil {
+			return err
+		}
+		return handler(srv, &serverStream{ServerStream: stream, ctx: ctx})
+	}
+}
+
+type serverStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *serverStream) Context() context.Context {
+	return s.ctx
+}
+
+// withDeadline ограничивает время выполнения запроса (более короткий дедлайн клиента сохраняется)
+func (i *Interceptors) withDeadline(ctx context.Context) (context.Context, context.CancelFunc) {
+	if i.timeout <= 0 {
+		return ctx, func() {}
+	}
+	return context.WithTimeout(ctx, i.timeout)
+}
+
+// authenticate определяет вызывающий сервис по JWT из метаданных (authorization: Bearer <token>)
+// или по проверенному клиентскому сертификату и проверяет права на вызов метода
+func (i *Interceptors) authenticate(ctx context.Context, method string) (context.Context, error) {
+	for _, service := range publicServices {
+		if strings.HasPrefix(method, service) {
+			return ctx, nil
+		}
+	}
+	required, known := MethodPermissions[method]
+	// Reflection раскрывает только схему API, поэтому достаточно аутентификации
+	if strings.HasPrefix(method, "/grpc.reflection.") {
+		required, known = nil, true
+	}
+
+	caller, err := i.caller(ctx)
+	if err != nil {
+		return ctx, err
+	}
+	if caller != nil {
+		ctx = context.WithValue(ctx, callerKey{}, caller)
+	}
+
+	if !i.authEnabled {
+		return ctx, nil
+	}
+	if caller == nil {
+		return ctx, status.Error(codes.Unauthenticated, "service jwt or client certificate is required")
+	}
+	for _, service := range serviceOnlyServices {
+		if strings.HasPrefix(method, service) && !caller.Service {
+			return ctx, status.Errorf(codes.PermissionDenied, "%v is not a service and can't call %v", caller.Name, method)
+		}
+	}
+	if !known || (len(required) > 0 && !rbac.NewPermissionSet(caller.Permissions...).Allows(required...)) {
+		return ctx, status.Errorf(codes.PermissionDenied, "%v is not permitted to call %v", caller.Name, method)
+	}
+	return ctx, nil
+}
+
+func (i *Interceptors) caller(ctx context.Context) (*Caller, error) {
+	md, _ := metadata.FromIncomingContext(ctx)
+	if values := md.Get("authorization"); len(values) > 0 {
+		scheme, token, _ := strings.Cut(values[0], " ")
+		if !strings.EqualFold(scheme, "Bearer") || strings.TrimSpace(token) == "" {
+			return nil, status.Error(codes.Unauthenticated, "invalid authorization metadata")
+		}
+		return i.tokens.CallerFromToken(ctx, strings.TrimSpace(token))
+	}
+
+	if p, ok := peer.FromContext(ctx); ok {
+		if tlsInfo, ok := p.AuthInfo.(credentials.TLSInfo); ok && len(tlsInfo.State.VerifiedChains) > 0 {
+			name := tlsInfo.State.VerifiedChains[0][0].Subject.CommonName
+			return &Caller{Name: name, Kind: CallerKindCertificate, Permissions: i.clientPermissions[name], Service: true}, nil
+		}
+	}
+
+	return nil, nil
+}
+
+func recovered(method string, r any) error {
+	log.Error().
+		Str("method", method).
+		Interface("panic", r).
+		Str("stack", string(debug.Stack())).
+		Msg("gRPC handler panic")
+	return status.Error(codes.Internal, "internal server error")
+}
+
+func logCall(ctx context.Context, method string, start time.Time, err error) {
+	code := status.Code(err)
+	event := log.Info()
+	if code != codes.OK {
+		event = log.Warn().Err(err)
+	}
+	if code == codes.Internal || code == codes.Unknown {
+		event = log.Error().Err(err)
+	}
+	if caller, ok := CallerFromContext(ctx); ok {
+		event = event.Str("caller", caller.Name)
+	}
+	if p, ok := peer.FromContext(ctx); ok {
+		event = event.Str("peer", p.Addr.String())
+	}
+	event.
+		Str("method", method).
+		Str("code", code.String()).
+		Dur("duration", time.Since(start)).
+		Msg("gRPC request")
+}
@@ -2,26 +2,56 @@ package grpc
 
 import (
 	"context"
+	"crypto/tls"
+	"crypto/x509"
 	"fmt"
 	"net"
+	"os"
+	"slices"
+	"strings"
 	"time"
 
+	"github.com/G0tem/go-service-auth/internal"
+	"github.com/G0tem/go-service-auth/internal/abac"
 	"github.com/G0tem/go-service-auth/internal/config"
+	"github.com/G0tem/go-service-auth/internal/handler"
+	"github.com/G0tem/go-service-auth/internal/handler/rbac"
+	"github.com/G0tem/go-service-auth/internal/model"
+	"github.com/G0tem/go-service-auth/internal/service"
+	"github.com/G0tem/go-service-auth/internal/types"
 	"github.com/G0tem/go-service-auth/proto"
+	"github.com/go-redis/redis/v8"
+	"github.com/google/uuid"
 	"github.com/rs/zerolog/log"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/reflection"
+	"google.golang.org/grpc/status"
+	"gorm.io/gorm"
 )
 
 // AuthServer реализует gRPC сервер для авторизации
 type AuthServer struct {
 	proto.UnimplementedAuthServiceServer
-	cfg *config.Config
+	db   *gorm.DB
+	cfg  *config.Config
+	rbac *rbac.RBACLayer
+	abac *abac.Engine
+	// shutdown закрывается при остановке сервера, чтобы завершить потоковые вызовы
+	shutdown chan struct{}
 }
 
 // NewAuthServer создает новый экземпляр gRPC сервера
-func NewAuthServer(cfg *config.Config) *AuthServer {
+func NewAuthServer(db *gorm.DB, cfg *config.Config, rbac *rbac.RBACLayer, abac *abac.Engine) *AuthServer {
 	return &AuthServer{
-		cfg: cfg,
+		db:   db,
+		cfg:  cfg,
+		rbac: rbac,
+		abac: abac,
+
+		shutdown: make(chan struct{}),
 	}
 }
 
@@ -38,37 +68,468 @@ func (s *AuthServer) GetTestData(ctx context.Context, req *proto.GetTestDataRequ
 	}, nil
 }
 
-// GetUserInfo возвращает информацию о пользователе по ID
+// GetUserInfo возвращает информацию о пользователе по ID, email или имени пользователя
 func (s *AuthServer) GetUserInfo(ctx context.Context, req *proto.GetUserInfoRequest) (*proto.GetUserInfoResponse, error) {
 	log.Info().
 		Str("user_id", req.UserId).
+		Str("email", req.Email).
+		Str("username", req.Username).
 		Msg("Received GetUserInfo gRPC request")
 
-	// Пример тестовых данных
+	query := s.db.WithContext(ctx).Preload("Role")
+	switch {
+	case req.UserId != "" && req.Email == "" && req.Username == "":
+		userId, err := uuid.Parse(req.UserId)
+		if err != nil {
+			return nil, status.Errorf(codes.InvalidArgument, "invalid user_id: %v", err)
+		}
+		query = query.Where("id = ?", userId)
+	case req.Email != "" && req.UserId == "" && req.Username == "":
+		query = query.Where("email = ?", req.Email)
+	case req.Username != "" && req.UserId == "" && req.Email == "":
+		query = query.Where("username = ?", req.Username)
+	default:
+		return nil, status.Error(codes.InvalidArgument, "exactly one of user_id, email or username must be set")
+	}
+
+	user, err := s.findUser(query)
+	if err != nil {
+		return nil, err
+	}
+
+	roles, err := s.rbac.GetUserRoles(user.ID)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "get user roles: %v", err)
+	}
+	permissions, err := s.rbac.GetUserPermissionList(user.ID)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "get user permissions: %v", err)
+	}
+
 	return &proto.GetUserInfoResponse{
-		UserId:   req.UserId,
-		Email:    "test@example.com",
-		Username: "testuser",
-		IsActive: true,
+		UserId:         user.ID.String(),
+		Email:          user.Email,
+		Username:       user.Username,
+		IsActive:       user.IsActive,
+		Role:           user.Role.Name,
+		Roles:          internal.Mapping(roles, func(x model.UserRole) string { return x.Name }),
+		Permissions:    permissions,
+		EmailConfirmed: user.EmailConfirmed,
+		AvatarUrl:      user.GetAvatarUrl(s.cfg.CdnPublicUrl),
+	}, nil
+}
+
+// findUser возвращает первого пользователя по запросу или ошибку NotFound
+func (s *AuthServer) findUser(query *gorm.DB) (*model.User, error) {
+	var user model.User
+	res := query.Limit(1).Find(&user)
+	if res.Error != nil {
+		return nil, status.Errorf(codes.Internal, "get user: %v", res.Error)
+	}
+	if res.RowsAffected == 0 {
+		return nil, status.Error(codes.NotFound, "user not found")
+	}
+	return &user, nil
+}
+
+// Can проверяет, разрешено ли пользователю действие над ресурсом
+func (s *AuthServer) Can(ctx context.Context, req *proto.CanRequest) (*proto.CanResponse, error) {
+	log.Info().
+		Str("user_id", req.UserId).
+		Str("permission", req.Permission).
+		Str("resource_type", req.ResourceType).
+		Str("resource_id", req.ResourceId).
+		Msg("Received Can gRPC request")
+
+	userId, err := uuid.Parse(req.UserId)
+	if err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "invalid user_id: %v", err)
+	}
+	if !strings.Contains(req.Permission, ":") {
+		return nil, status.Error(codes.InvalidArgument, "permission must be in model:action format")
+	}
+
+	allowed, err := s.rbac.Can(userId, req.Permission, types.Resource{
+		Type: req.ResourceType,
+		ID:   req.ResourceId,
+	})
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "check permission: %v", err)
+	}
+
+	return &proto.CanResponse{Allowed: allowed}, nil
+}
+
+// Authorize проверяет доступ пользователя по ABAC-политике
+func (s *AuthServer) Authorize(ctx context.Context, req *proto.AuthorizeRequest) (*proto.AuthorizeResponse, error) {
+	log.Info().
+		Str("user_id", req.UserId).
+		Str("action", req.Action).
+		Msg("Received Authorize gRPC request")
+
+	userId, err := uuid.Parse(req.UserId)
+	if err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "invalid user_id: %v", err)
+	}
+	if !strings.Contains(req.Action, ":") {
+		return nil, status.Error(codes.InvalidArgument, "action must be in model:action format")
+	}
+	if s.abac == nil {
+		return nil, status.Error(codes.FailedPrecondition, "abac policy is not configured")
+	}
+	// Атрибуты окружения из запроса дополняют текущее время, переопределять атрибуты сервера нельзя
+	environment := abac.Environment(time.Now())
+	for key, value := range req.Environment {
+		if _, reserved := environment[key]; reserved {
+			return nil, status.Errorf(codes.InvalidArgument, "environment attribute %v is set by the server", key)
+		}
+		environment[key] = value
+	}
+
+	user, err := s.findUser(s.db.WithContext(ctx).Where("id = ?", userId))
+	if err != nil {
+		return nil, err
+	}
+	roles, err := s.rbac.GetUserRoles(userId)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "get user roles: %v", err)
+	}
+	permissions, err := s.rbac.GetUserPermissionList(userId)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "get user permissions: %v", err)
+	}
+
+	resource := abac.Attributes{}
+	for key, value := range req.Resource {
+		resource[key] = value
+	}
+
+	decision := s.abac.Evaluate(abac.Request{
+		Action:      req.Action,
+		Subject:     abac.SubjectFromUser(*user, internal.Mapping(roles, func(x model.UserRole) string { return x.Name }), permissions),
+		Resource:    resource,
+		Environment: environment,
+	})
+
+	return &proto.AuthorizeResponse{
+		Allowed: decision.Allowed,
+		Effect:  decision.Effect,
+		RuleId:  decision.RuleID,
+		Reason:  decision.Reason,
+	}, nil
+}
+
+// ValidateToken проверяет JWT токен и возвращает его claims
+func (s *AuthServer) ValidateToken(ctx context.Context, req *proto.ValidateTokenRequest) (*proto.ValidateTokenResponse, error) {
+	log.Info().Msg("Received ValidateToken gRPC request")
+
+	if req.Token == "" {
+		return nil, status.Error(codes.InvalidArgument, "token must be set")
+	}
+	return s.validateToken(ctx, req.Token)
+}
+
+// validateToken проверяет подпись и срок действия токена (как JWTMiddleware),
+// а также что пользователь токена существует, активен и его сессии не отозваны
+func (s *AuthServer) validateToken(ctx context.Context, token string) (*proto.ValidateTokenResponse, error) {
+	_, res, err := s.checkToken(ctx, token)
+	return res, err
+}
+
+// checkToken - результат validateToken и claims токена (nil, если токен не удалось разобрать)
+func (s *AuthServer) checkToken(ctx context.Context, token string) (*handler.JwtClaims, *proto.ValidateTokenResponse, error) {
+	claims, err := handler.ParseJWT(token, s.cfg.SecretKey)
+	if err != nil {
+		return nil, &proto.ValidateTokenResponse{Error: err.Error()}, nil
+	}
+
+	res := &proto.ValidateTokenResponse{
+		Valid:       true,
+		UserId:      claims.UserID,
+		Username:    claims.Username,
+		Email:       claims.Email,
+		Role:        claims.Role,
+		Roles:       claims.Roles,
+		Permissions: claims.Permissions,
+	}
+	if !claims.Exp.IsZero() {
+		res.ExpiresAt = claims.Exp.Format(time.RFC3339)
+	}
+
+	userId, err := uuid.Parse(claims.UserID)
+	if err != nil {
+		res.Valid = false
+		res.Error = internal.PrintError(internal.ErrInvalidClaims, err).Error()
+		return claims, res, nil
+	}
+	user, err := s.findUser(s.db.WithContext(ctx).Where("id = ?", userId))
+	if status.Code(err) == codes.NotFound || (err == nil && !user.IsActive) {
+		res.Valid = false
+		res.Revoked = true
+		res.Error = "user is deleted or deactivated"
+		return claims, res, nil
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	if user.TokenRevoked(claims.IssuedAt) {
+		res.Valid = false
+		res.Revoked = true
+		res.Error = "user sessions are revoked"
+		return claims, res, nil
+	}
+
+	// Токены, выпущенные до учёта сессий, не содержат sid
+	if claims.SessionID != "" {
+		active, err := s.sessionActive(ctx, userId, claims.SessionID, claims.IssuedAt)
+		if err != nil {
+			return nil, nil, err
+		}
+		if !active {
+			res.Valid = false
+			res.Revoked = true
+			res.Error = "session is revoked, expired or refreshed"
+		}
+	}
+
+	return claims, res, nil
+}
+
+// CallerFromToken проверяет JWT вызывающего так же, как ValidateToken (пользователь активен, токен
+// и сессия не отозваны), права берутся из RBAC, так как права в токене могут устареть
+func (s *AuthServer) CallerFromToken(ctx context.Context, token string) (*Caller, error) {
+	claims, res, err := s.checkToken(ctx, token)
+	if err != nil {
+		return nil, err
+	}
+	if !res.Valid {
+		return nil, status.Error(codes.Unauthenticated, res.Error)
+	}
+
+	permissions, err := s.rbac.GetUserPermissionList(uuid.MustParse(res.UserId))
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "get user permissions: %v", err)
+	}
+	return &Caller{
+		Name:        res.Username,
+		Kind:        CallerKindJWT,
+		Permissions: permissions,
+		Service:     slices.Contains(claims.Audience, handler.ServiceAudience),
 	}, nil
 }
 
-// StartGrpcServer запускает gRPC сервер
-func StartGrpcServer(cfg *config.Config) error {
+// sessionActive проверяет, что сессия пользователя не отозвана, не истекла
+// и токен, выпущенный в issuedAt, не заменен обновлением сессии
+func (s *AuthServer) sessionActive(ctx context.Context, userId uuid.UUID, sessionId string, issuedAt time.Time) (bool, error) {
+	id, err := uuid.Parse(sessionId)
+	if err != nil {
+		return false, nil
+	}
+	var session model.UserSession
+	res := s.db.WithContext(ctx).Where("id = ? AND user_id = ?", id, userId).Limit(1).Find(&session)
+	if res.Error != nil {
+		return false, status.Errorf(codes.Internal, "get session: %v", res.Error)
+	}
+	return res.RowsAffected > 0 && session.Active(time.Now()) && session.Current(issuedAt), nil
+}
+
+// CheckPermission проверяет, есть ли у пользователя (или владельца токена) все указанные права
+func (s *AuthServer) CheckPermission(ctx context.Context, req *proto.CheckPermissionRequest) (*proto.CheckPermissionResponse, error) {
+	log.Info().
+		Str("user_id", req.UserId).
+		Strs("permissions", req.Permissions).
+		Str("resource_type", req.ResourceType).
+		Str("resource_id", req.ResourceId).
+		Msg("Received CheckPermission gRPC request")
+
+	userId, permissions, err := s.checkPermissions(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+
+	return &proto.CheckPermissionResponse{
+		Allowed: permissions.Allows(req.Permissions...),
+		UserId:  userId.String(),
+	}, nil
+}
+
+// BatchCheckPermissions проверяет каждое из указанных прав пользователя (или владельца токена)
+func (s *AuthServer) BatchCheckPermissions(ctx context.Context, req *proto.CheckPermissionRequest) (*proto.BatchCheckPermissionsResponse, error) {
+	log.Info().
+		Str("user_id", req.UserId).
+		Strs("permissions", req.Permissions).
+		Str("resource_type", req.ResourceType).
+		Str("resource_id", req.ResourceId).
+		Msg("Received BatchCheckPermissions gRPC request")
+
+	userId, permissions, err := s.checkPermissions(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+
+	return &proto.BatchCheckPermissionsResponse{
+		Results: internal.Mapping(req.Permissions, func(x string) *proto.PermissionCheck {
+			return &proto.PermissionCheck{Permission: x, Allowed: permissions.Allows(x)}
+		}),
+		UserId: userId.String(),
+	}, nil
+}
+
+// checkPermissions проверяет запрос и возвращает пользователя и его эффективные права для ресурса
+func (s *AuthServer) checkPermissions(ctx context.Context, req *proto.CheckPermissionRequest) (uuid.UUID, *rbac.PermissionSet, error) {
+	if len(req.Permissions) == 0 {
+		return uuid.Nil, nil, status.Error(codes.InvalidArgument, "permissions must be set")
+	}
+	for _, permission := range req.Permissions {
+		if !strings.Contains(permission, ":") {
+			return uuid.Nil, nil, status.Errorf(codes.InvalidArgument, "permission %v must be in model:action format", permission)
+		}
+	}
+
+	var userId uuid.UUID
+	var err error
+	switch {
+	case req.UserId != "" && req.Token == "":
+		userId, err = uuid.Parse(req.UserId)
+		if err != nil {
+			return uuid.Nil, nil, status.Errorf(codes.InvalidArgument, "invalid user_id: %v", err)
+		}
+	case req.Token != "" && req.UserId == "":
+		token, err := s.validateToken(ctx, req.Token)
+		if err != nil {
+			return uuid.Nil, nil, err
+		}
+		if !token.Valid {
+			return uuid.Nil, nil, status.Error(codes.Unauthenticated, token.Error)
+		}
+		userId = uuid.MustParse(token.UserId)
+	default:
+		return uuid.Nil, nil, status.Error(codes.InvalidArgument, "exactly one of user_id or token must be set")
+	}
+
+	permissions, err := s.rbac.GetUserResourcePermissionList(userId, types.Resource{
+		Type: req.ResourceType,
+		ID:   req.ResourceId,
+	})
+	if err != nil {
+		return uuid.Nil, nil, status.Errorf(codes.Internal, "check permission: %v", err)
+	}
+
+	return userId, rbac.NewPermissionSet(permissions...), nil
+}
+
+// ServerOptions возвращает опции gRPC сервера: TLS (mTLS при заданном CA клиентов) и перехватчики,
+// JWT вызывающих проверяет tokens
+func ServerOptions(cfg *config.Config, tokens TokenAuthenticator) ([]grpc.ServerOption, error) {
+	interceptors := NewInterceptors(cfg, tokens)
+	options := []grpc.ServerOption{
+		grpc.UnaryInterceptor(interceptors.Unary()),
+		grpc.StreamInterceptor(interceptors.Stream()),
+	}
+
+	if cfg.GrpcTlsCertFile == "" && cfg.GrpcTlsKeyFile == "" {
+		if cfg.GrpcTlsClientCaFile != "" {
+			return nil, fmt.Errorf("client CA requires GRPC_TLS_CERT_FILE and GRPC_TLS_KEY_FILE")
+		}
+		log.Warn().Msg("gRPC server TLS is disabled")
+		return options, nil
+	}
+
+	certificate, err := tls.LoadX509KeyPair(cfg.GrpcTlsCertFile, cfg.GrpcTlsKeyFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load TLS certificate: %v", err)
+	}
+	tlsConfig := &tls.Config{
+		Certificates: []tls.Certificate{certificate},
+		MinVersion:   tls.VersionTLS12,
+	}
+
+	if cfg.GrpcTlsClientCaFile != "" {
+		caData, err := os.ReadFile(cfg.GrpcTlsClientCaFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read client CA: %v", err)
+		}
+		clientCAs := x509.NewCertPool()
+		if !clientCAs.AppendCertsFromPEM(caData) {
+			return nil, fmt.Errorf("no certificates in client CA %v", cfg.GrpcTlsClientCaFile)
+		}
+		tlsConfig.ClientCAs = clientCAs
+		// Клиент может аутентифицироваться сертификатом или JWT, поэтому сертификат не обязателен
+		tlsConfig.ClientAuth = tls.VerifyClientCertIfGiven
+	}
+
+	return append(options, grpc.Creds(credentials.NewTLS(tlsConfig))), nil
+}
+
+// Server - gRPC сервер сервиса авторизации
+type Server struct {
+	grpc     *grpc.Server
+	auth     *AuthServer
+	admin    *AdminServer
+	listener net.Listener
+	Health   *HealthChecker
+}
+
+// NewServer открывает порт и регистрирует сервисы: AuthService, AuthAdminService, grpc.health.v1
+// и (если включено) server reflection
+func NewServer(
+	db *gorm.DB, redisClient *redis.Client, cfg *config.Config, rbac *rbac.RBACLayer, abac *abac.Engine, svc *service.Service,
+) (*Server, error) {
+	auth := NewAuthServer(db, cfg, rbac, abac)
+	options, err := ServerOptions(cfg, auth)
+	if err != nil {
+		return nil, err
+	}
+
 	lis, err := net.Listen("tcp", fmt.Sprintf(":%d", cfg.GrpcPort))
 	if err != nil {
-		return fmt.Errorf("failed to listen: %v", err)
+		return nil, fmt.Errorf("failed to listen: %v", err)
 	}
 
-	s := grpc.NewServer()
-	authServer := NewAuthServer(cfg)
-	proto.RegisterAuthServiceServer(s, authServer)
+	s := &Server{
+		grpc:     grpc.NewServer(options...),
+		auth:     auth,
+		admin:    NewAdminServer(svc),
+		listener: lis,
+		Health:   NewHealthChecker(db, redisClient, cfg.RMQConnUrl, cfg.HealthCheckInterval),
+	}
+	proto.RegisterAuthServiceServer(s.grpc, s.auth)
+	proto.RegisterAuthAdminServiceServer(s.grpc, s.admin)
+	healthpb.RegisterHealthServer(s.grpc, s.Health.Server)
+	if cfg.GrpcReflectionEnabled {
+		reflection.Register(s.grpc)
+	}
+
+	return s, nil
+}
 
-	log.Info().Msgf("gRPC server listening on port %d", cfg.GrpcPort)
+// Serve обслуживает запросы до вызова Shutdown
+func (s *Server) Serve() error {
+	log.Info().Msgf("gRPC server listening on %v", s.listener.Addr())
 
-	if err := s.Serve(lis); err != nil {
+	if err := s.grpc.Serve(s.listener); err != nil {
 		return fmt.Errorf("failed to serve: %v", err)
 	}
 
 	return nil
 }
+
+// Shutdown сообщает клиентам о недоступности через сервис здоровья и дожидается завершения
+// текущих запросов (по истечении ctx оставшиеся соединения закрываются принудительно)
+func (s *Server) Shutdown(ctx context.Context) {
+	s.Health.Shutdown()
+	close(s.auth.shutdown)
+
+	stopped := make(chan struct{})
+	go func() {
+		s.grpc.GracefulStop()
+		close(stopped)
+	}()
+
+	select {
+	case <-stopped:
+	case <-ctx.Done():
+		log.Warn().Msg("gRPC graceful shutdown timed out, closing connections")
+		s.grpc.Stop()
+	}
+}
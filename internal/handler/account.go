@@ -0,0 +1,136 @@
+package handler
+
+import (
+	"github.com/G0tem/go-service-auth/internal/types"
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"github.com/rs/zerolog/log"
+)
+
+// Email change
+// @Summary Request email change
+// @Description Send confirmation link to the new email and notice to the current one, email is changed after confirmation
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param request body types.EmailChangeRequest true "new email and current password"
+// @Success 200 {object} types.SuccessResponse
+// @Failure 400 {object} types.FailureErrorResponse
+// @Failure 401 {object} types.FailureResponse
+// @Failure 500 {object} types.FailureErrorResponse
+// @Security ApiKeyAuth
+// @Router /auth/email/change [post]
+func (h *Handler) emailChange(c *fiber.Ctx) error {
+	userId, err := currentUserId(c)
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(types.FailureResponse{
+			Status:  "error",
+			Message: "Invalid user id in token",
+		})
+	}
+
+	input := new(types.EmailChangeRequest)
+	if err := c.BodyParser(input); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(types.FailureErrorResponse{
+			Status:  "error",
+			Message: "Error on email change request",
+			Error:   err.Error(),
+		})
+	}
+
+	if err = h.svc.RequestEmailChange(c.Context(), userId, input.NewEmail, input.Password); err != nil {
+		log.Error().Err(err).Str("user_id", userId.String()).Msg("Failed to request email change")
+		return serviceError(c, "Can't change email", err)
+	}
+
+	return c.Status(fiber.StatusOK).JSON(types.SuccessResponse{
+		Status:  "ok",
+		Message: "Confirmation link is sent to the new email.",
+	})
+}
+
+// Email change confirmation
+// @Summary Confirm email change
+// @Description Change email by the token from confirmation link, all sessions of the user are revoked
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param request body types.EmailChangeConfirmRequest true "token from confirmation link"
+// @Success 200 {object} types.SuccessResponse
+// @Failure 400 {object} types.FailureErrorResponse
+// @Failure 404 {object} types.FailureErrorResponse
+// @Failure 500 {object} types.FailureErrorResponse
+// @Router /auth/email/change/confirm [post]
+func (h *Handler) emailChangeConfirm(c *fiber.Ctx) error {
+	input := new(types.EmailChangeConfirmRequest)
+	if err := c.BodyParser(input); err != nil || input.Token == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(types.FailureResponse{
+			Status:  "error",
+			Message: "Token is required",
+		})
+	}
+
+	if _, err := h.svc.ConfirmEmailChange(c.Context(), input.Token); err != nil {
+		return serviceError(c, "Can't confirm email change", err)
+	}
+
+	return c.Status(fiber.StatusOK).JSON(types.SuccessResponse{
+		Status:  "ok",
+		Message: "Email changed, please log in again.",
+	})
+}
+
+// Username change
+// @Summary Change username
+// @Description Change username of the current user (repeated changes may be limited by cooldown), returns new token
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param request body types.UsernameChangeRequest true "new username"
+// @Success 200 {object} types.LoginSuccessResponse
+// @Failure 400 {object} types.FailureErrorResponse
+// @Failure 401 {object} types.FailureResponse
+// @Failure 500 {object} types.FailureErrorResponse
+// @Security ApiKeyAuth
+// @Router /auth/username/change [post]
+func (h *Handler) usernameChange(c *fiber.Ctx) error {
+	userId, err := currentUserId(c)
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(types.FailureResponse{
+			Status:  "error",
+			Message: "Invalid user id in token",
+		})
+	}
+
+	input := new(types.UsernameChangeRequest)
+	if err := c.BodyParser(input); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(types.FailureErrorResponse{
+			Status:  "error",
+			Message: "Error on username change request",
+			Error:   err.Error(),
+		})
+	}
+
+	user, err := h.svc.ChangeUsername(c.Context(), userId, input.Username)
+	if err != nil {
+		return serviceError(c, "Can't change username", err)
+	}
+
+	newToken, session, err := h.issueToken(c, user, currentSessionId(c))
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(types.FailureErrorResponse{
+			Status:  "error",
+			Message: "Internal Server Error",
+			Error:   err.Error(),
+		})
+	}
+	return c.Status(fiber.StatusOK).JSON(types.LoginSuccessResponse{
+		Status: "ok",
+		Data:   h.tokenData(c, newToken, session, cookieAuth(c)),
+	})
+}
+
+// currentUserId - id of the user authenticated by JWTMiddleware
+func currentUserId(c *fiber.Ctx) (uuid.UUID, error) {
+	return uuid.Parse(c.Locals("claims").(*JwtClaims).UserID)
+}
@@ -1,22 +1,20 @@
 package handler
 
 import (
-	"fmt"
-	"strings"
-
+	"github.com/G0tem/go-service-auth/internal"
+	"github.com/G0tem/go-service-auth/internal/events"
 	"github.com/G0tem/go-service-auth/internal/model"
+	"github.com/G0tem/go-service-auth/internal/service"
 	"github.com/G0tem/go-service-auth/internal/types"
 	"github.com/gofiber/fiber/v2"
-	"github.com/golang-jwt/jwt/v5"
 	"github.com/google/uuid"
-	"github.com/jackc/pgx/v5/pgconn"
 	"github.com/rs/zerolog/log"
 	"golang.org/x/crypto/bcrypt"
 )
 
 // Login
 // @Summary Login
-// @Description Login
+// @Description Login, with "mode": "cookie" the token is set in HttpOnly cookie and the response contains CSRF token
 // @Tags auth
 // @Accept json
 // @Produce json
@@ -36,6 +34,13 @@ func (h *Handler) login(c *fiber.Ctx) error {
 			Error:   err.Error(),
 		})
 	}
+	if err := validAuthMode(input.Mode); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(types.FailureErrorResponse{
+			Status:  "error",
+			Message: "Invalid auth mode",
+			Error:   err.Error(),
+		})
+	}
 
 	err := *new(error)
 
@@ -65,7 +70,7 @@ func (h *Handler) login(c *fiber.Ctx) error {
 		})
 	}
 
-	token, err := h.GetJWT(user)
+	token, session, err := h.issueToken(c, user, uuid.Nil)
 	if err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(types.FailureErrorResponse{
 			Status:  "error",
@@ -76,13 +81,13 @@ func (h *Handler) login(c *fiber.Ctx) error {
 
 	return c.Status(fiber.StatusOK).JSON(types.LoginSuccessResponse{
 		Status: "ok",
-		Data:   types.LoginSuccessData{Token: token},
+		Data:   h.tokenData(c, token, session, input.Mode == types.AuthModeCookie),
 	})
 }
 
 // Register
 // @Summary Register
-// @Description Register
+// @Description Register, with "mode": "cookie" the token is set in HttpOnly cookie and the response contains CSRF token
 // @Tags auth
 // @Accept json
 // @Produce json
@@ -101,6 +106,13 @@ func (h *Handler) register(c *fiber.Ctx) error {
 			Error:   err.Error(),
 		})
 	}
+	if err := validAuthMode(input.Mode); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(types.FailureErrorResponse{
+			Status:  "error",
+			Message: "Invalid auth mode",
+			Error:   err.Error(),
+		})
+	}
 
 	if !validateEmail(input.Email) {
 		return c.Status(fiber.StatusBadRequest).JSON(types.FailureResponse{
@@ -116,60 +128,44 @@ func (h *Handler) register(c *fiber.Ctx) error {
 		})
 	}
 
-	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(input.Password), bcrypt.DefaultCost)
-
-	if err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(types.FailureErrorResponse{
+	user, err := h.svc.CreateUser(c.Context(), types.CreateUserRequest{
+		Username: input.Username,
+		Email:    input.Email,
+		Password: input.Password,
+	})
+	switch service.Kind(err) {
+	case service.ErrAlreadyExists:
+		return c.Status(fiber.StatusBadRequest).JSON(types.FailureResponse{
 			Status:  "error",
-			Message: "Failed to hash password",
-			Error:   err.Error(),
+			Message: "Username or email already taken",
 		})
-	}
-
-	// Получаем роль по умолчанию
-	var defaultRole model.UserRole
-	if err := h.db.Where("name = ?", model.DefaultUserRole).First(&defaultRole).Error; err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(types.FailureErrorResponse{
+	case service.ErrInvalidArgument:
+		return c.Status(fiber.StatusBadRequest).JSON(types.FailureErrorResponse{
 			Status:  "error",
-			Message: "Failed to get default role",
+			Message: "Error on register request",
 			Error:   err.Error(),
 		})
 	}
-
-	user := model.User{
-		Username:     input.Username,
-		Email:        input.Email,
-		PasswordHash: string(hashedPassword),
-		RoleID:       defaultRole.ID,
-	}
-
-	tx := h.db.Create(&user)
-	if err := tx.Error; err != nil {
-		if err.(*pgconn.PgError).Code == "23505" {
-			return c.Status(fiber.StatusBadRequest).JSON(types.FailureResponse{
-				Status:  "error",
-				Message: "Username or email already taken",
-			})
-		}
+	if err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(types.FailureErrorResponse{
 			Status:  "error",
-			Message: "Internal Server Error (Create(&user))",
+			Message: "Internal Server Error (CreateUser)",
 			Error:   err.Error(),
 		})
 	}
 
-	token, err := h.GetJWT(&user)
+	token, session, err := h.issueToken(c, user, uuid.Nil)
 	if err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(types.FailureErrorResponse{
 			Status:  "error",
-			Message: "Internal Server Error (user.GetJWT())",
+			Message: "Internal Server Error (issueToken)",
 			Error:   err.Error(),
 		})
 	}
 
 	return c.Status(fiber.StatusCreated).JSON(types.LoginSuccessResponse{
 		Status: "Ok",
-		Data:   types.LoginSuccessData{Token: token},
+		Data:   h.tokenData(c, token, session, input.Mode == types.AuthModeCookie),
 	})
 }
 
@@ -209,7 +205,7 @@ func (h *Handler) passwordChange(c *fiber.Ctx) error {
 
 	claims := c.Locals("claims").(*JwtClaims)
 
-	tx := h.db.Where("email = ?", claims.Email).First(&user)
+	tx := h.db.Where("id = ?", claims.UserID).First(&user)
 	if err := tx.Error; err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(types.FailureErrorResponse{
 			Status:  "error",
@@ -236,6 +232,7 @@ func (h *Handler) passwordChange(c *fiber.Ctx) error {
 			Error:   err.Error(),
 		})
 	}
+	h.rbac.Events.Publish(c.Context(), events.UserPasswordChanged, user.ID, nil)
 
 	return c.Status(fiber.StatusOK).JSON(types.SuccessResponse{
 		Status:  "ok",
@@ -245,58 +242,36 @@ func (h *Handler) passwordChange(c *fiber.Ctx) error {
 
 // Refresh jwt token
 // @Summary Refresh jwt token
-// @Description Refresh jwt token
+// @Description Refresh jwt token, in cookie mode session cookies are renewed
 // @Tags auth
 // @Produce json
-// @Success 200 {object} types.SuccessResponse
-// @Failure 400 {object} types.FailureResponse
+// @Success 200 {object} types.LoginSuccessResponse
+// @Failure 401 {object} types.FailureResponse
 // @Failure 500 {object} types.FailureErrorResponse
 // @Security ApiKeyAuth
 // @Router /auth/refresh [post]
 func (h *Handler) refresh(c *fiber.Ctx) error {
-	authorization := string(c.Request().Header.Peek("Authorization"))
-	tokenString := strings.TrimSpace(strings.Replace(authorization, "Bearer", "", 1))
-	token, _, err := new(jwt.Parser).ParseUnverified(tokenString, jwt.MapClaims{})
+	userId, err := currentUserId(c)
 	if err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(types.FailureErrorResponse{
-			Status:  "error",
-			Message: "Internal server error (refresh)",
-			Error:   err.Error(),
-		})
-	}
-
-	claims, ok := token.Claims.(jwt.MapClaims)
-	if !ok {
-		return c.Status(fiber.StatusInternalServerError).JSON(types.FailureErrorResponse{
+		return c.Status(fiber.StatusUnauthorized).JSON(types.FailureResponse{
 			Status:  "error",
-			Message: "Internal server error (email field not present in jwt)",
-			Error:   "email field not present in jwt",
+			Message: "Invalid user id in token",
 		})
 	}
-	email := claims["email"]
 
-	var (
-		user model.User
-	)
-	// Find user by email
-	tx := h.db.Find(&user, "Email = ?", email)
-	if err := tx.Error; err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(types.FailureErrorResponse{
-			Status:  "error",
-			Message: fmt.Sprintf("Internal server error (user not found by email %v)", email),
-			Error:   err.Error(),
-		})
+	user, err := h.svc.GetUser(c.Context(), userId)
+	if err != nil {
+		return serviceError(c, "Can't get user", err)
 	}
 
-	if user.ID == uuid.Nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(types.FailureErrorResponse{
+	// The session of the token is renewed, tokens issued before session tracking get new session
+	newToken, session, err := h.issueToken(c, user, currentSessionId(c))
+	if service.Kind(err) == service.ErrNotFound {
+		return c.Status(fiber.StatusUnauthorized).JSON(types.FailureResponse{
 			Status:  "error",
-			Message: "Internal server error (user not found)",
-			Error:   fmt.Sprintf("user with email %v not found", email),
+			Message: internal.ErrInvalidToken,
 		})
 	}
-
-	newToken, err := h.GetJWT(&user)
 	if err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(types.FailureErrorResponse{
 			Status:  "error",
@@ -307,13 +282,13 @@ func (h *Handler) refresh(c *fiber.Ctx) error {
 
 	return c.Status(fiber.StatusOK).JSON(types.LoginSuccessResponse{
 		Status: "ok",
-		Data:   types.LoginSuccessData{Token: newToken},
+		Data:   h.tokenData(c, newToken, session, cookieAuth(c)),
 	})
 }
 
 // GetMe
 // @Summary Get current user info
-// @Description Get current user ID and email
+// @Description Get profile of the current user with roles, effective permissions and MFA status
 // @Tags auth
 // @Produce json
 // @Success 200 {object} types.GetMeResponse
@@ -322,16 +297,21 @@ func (h *Handler) refresh(c *fiber.Ctx) error {
 // @Security ApiKeyAuth
 // @Router /auth/get-me [get]
 func (h *Handler) getMe(c *fiber.Ctx) error {
-	var user model.User
-
 	claims := c.Locals("claims").(*JwtClaims)
 	log.Debug().
 		Str("email", claims.Email).
 		Time("exp", claims.Exp).
 		Msg("Attempting to get user")
 
-	tx := h.db.Where("email = ?", claims.Email).First(&user)
-	if err := tx.Error; err != nil {
+	userId, err := currentUserId(c)
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(types.FailureResponse{
+			Status:  "error",
+			Message: "Invalid user id in token",
+		})
+	}
+	user, err := h.svc.GetUser(c.Context(), userId)
+	if err != nil {
 		log.Error().
 			Err(err).
 			Str("email", claims.Email).
@@ -343,8 +323,95 @@ func (h *Handler) getMe(c *fiber.Ctx) error {
 		})
 	}
 
-	return c.Status(fiber.StatusOK).JSON(types.GetMeResponse{
-		ID:    user.ID.String(),
-		Email: user.Email,
-	})
+	response, err := h.meResponse(user)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(types.FailureErrorResponse{
+			Status:  "error",
+			Message: "Internal server error",
+			Error:   err.Error(),
+		})
+	}
+	return c.Status(fiber.StatusOK).JSON(response)
+}
+
+// UpdateMe
+// @Summary Update current user profile
+// @Description Change display name, locale (BCP 47), timezone (IANA) or custom attributes of the current user, only fields which are set are changed
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param request body types.UpdateMeRequest true "profile fields"
+// @Success 200 {object} types.GetMeResponse
+// @Failure 400 {object} types.FailureErrorResponse
+// @Failure 401 {object} types.FailureResponse
+// @Failure 500 {object} types.FailureErrorResponse
+// @Security ApiKeyAuth
+// @Router /auth/me [patch]
+func (h *Handler) updateMe(c *fiber.Ctx) error {
+	userId, err := currentUserId(c)
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(types.FailureResponse{
+			Status:  "error",
+			Message: "Invalid user id in token",
+		})
+	}
+
+	input := new(types.UpdateMeRequest)
+	if err := c.BodyParser(input); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(types.FailureErrorResponse{
+			Status:  "error",
+			Message: "Error on update profile request",
+			Error:   err.Error(),
+		})
+	}
+
+	user, err := h.svc.UpdateProfile(c.Context(), userId, *input)
+	if err != nil {
+		return serviceError(c, "Can't update profile", err)
+	}
+
+	response, err := h.meResponse(user)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(types.FailureErrorResponse{
+			Status:  "error",
+			Message: "Internal server error",
+			Error:   err.Error(),
+		})
+	}
+	return c.Status(fiber.StatusOK).JSON(response)
+}
+
+func (h *Handler) meResponse(user *model.User) (*types.GetMeResponse, error) {
+	roles, err := h.rbac.GetUserRoles(user.ID)
+	if err != nil {
+		return nil, err
+	}
+	permissions, err := h.rbac.GetUserPermissionList(user.ID)
+	if err != nil {
+		return nil, err
+	}
+	attributes := map[string]any(user.Attributes)
+	if attributes == nil {
+		attributes = map[string]any{}
+	}
+
+	return &types.GetMeResponse{
+		ID:             user.ID.String(),
+		Email:          user.Email,
+		Username:       user.Username,
+		EmailConfirmed: user.EmailConfirmed,
+		IsActive:       user.IsActive,
+		AvatarURL:      user.GetAvatarUrl(h.cfg.CdnPublicUrl),
+		CoverURL:       h.svc.CoverUrl(user),
+		DisplayName:    user.DisplayName,
+		Locale:         user.Locale,
+		Timezone:       user.Timezone,
+		Attributes:     attributes,
+		Role:           user.Role.Name,
+		Roles:          internal.Mapping(roles, func(x model.UserRole) string { return x.Name }),
+		Permissions:    permissions,
+		Mfa:            types.MfaStatus{Enabled: user.MfaEnabled},
+		CreatedAt:      user.CreatedAt,
+		UpdatedAt:      user.UpdatedAt,
+	}, nil
 }
@@ -0,0 +1,106 @@
+package handler
+
+import (
+	"io"
+
+	"github.com/G0tem/go-service-auth/internal/model"
+	"github.com/G0tem/go-service-auth/internal/types"
+	"github.com/gofiber/fiber/v2"
+)
+
+// Upload avatar
+// @Summary Upload avatar
+// @Description Set avatar of the current user. JPEG, PNG, GIF or WebP image from 32x32 to 4096x4096 pixels is cropped to square, resized to 512, 256 and 64 pixels and stored without metadata
+// @Tags auth
+// @Accept multipart/form-data
+// @Produce json
+// @Param avatar formData file true "image"
+// @Success 200 {object} types.AvatarResponse
+// @Failure 400 {object} types.FailureErrorResponse
+// @Failure 401 {object} types.FailureResponse
+// @Failure 500 {object} types.FailureErrorResponse
+// @Security ApiKeyAuth
+// @Router /auth/me/avatar [put]
+func (h *Handler) uploadAvatar(c *fiber.Ctx) error {
+	userId, err := currentUserId(c)
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(types.FailureResponse{
+			Status:  "error",
+			Message: "Invalid user id in token",
+		})
+	}
+
+	file, err := c.FormFile("avatar")
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(types.FailureErrorResponse{
+			Status:  "error",
+			Message: "Avatar file is required",
+			Error:   err.Error(),
+		})
+	}
+	if file.Size > int64(h.cfg.MaxFileUploadSizeInBytes) {
+		return c.Status(fiber.StatusBadRequest).JSON(types.FailureResponse{
+			Status:  "error",
+			Message: "Avatar file is too large",
+		})
+	}
+	reader, err := file.Open()
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(types.FailureErrorResponse{
+			Status:  "error",
+			Message: "Can't read avatar file",
+			Error:   err.Error(),
+		})
+	}
+	defer reader.Close()
+	data, err := io.ReadAll(io.LimitReader(reader, int64(h.cfg.MaxFileUploadSizeInBytes)))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(types.FailureErrorResponse{
+			Status:  "error",
+			Message: "Can't read avatar file",
+			Error:   err.Error(),
+		})
+	}
+
+	user, err := h.svc.SetAvatar(c.Context(), userId, data)
+	if err != nil {
+		return serviceError(c, "Can't set avatar", err)
+	}
+	return c.Status(fiber.StatusOK).JSON(h.avatarResponse(user))
+}
+
+// Delete avatar
+// @Summary Delete avatar
+// @Description Delete avatar of the current user
+// @Tags auth
+// @Produce json
+// @Success 200 {object} types.AvatarResponse
+// @Failure 401 {object} types.FailureResponse
+// @Failure 500 {object} types.FailureErrorResponse
+// @Security ApiKeyAuth
+// @Router /auth/me/avatar [delete]
+func (h *Handler) deleteAvatar(c *fiber.Ctx) error {
+	userId, err := currentUserId(c)
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(types.FailureResponse{
+			Status:  "error",
+			Message: "Invalid user id in token",
+		})
+	}
+
+	user, err := h.svc.DeleteAvatar(c.Context(), userId)
+	if err != nil {
+		return serviceError(c, "Can't delete avatar", err)
+	}
+	return c.Status(fiber.StatusOK).JSON(h.avatarResponse(user))
+}
+
+func (h *Handler) avatarResponse(user *model.User) types.AvatarResponse {
+	return types.AvatarResponse{
+		Status: "ok",
+		Data: types.AvatarData{
+			AvatarURL: user.GetAvatarUrl(h.cfg.CdnPublicUrl),
+			Variants:  h.svc.AvatarVariantUrls(user),
+		},
+	}
+}
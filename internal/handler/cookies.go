@@ -0,0 +1,88 @@
+package handler
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"time"
+
+	"github.com/G0tem/go-service-auth/internal/model"
+	"github.com/G0tem/go-service-auth/internal/types"
+	"github.com/gofiber/fiber/v2"
+)
+
+// Cookie mode of browser clients: the token is kept in HttpOnly cookie (it authenticates refresh as well),
+// JavaScript reads CSRF token from CsrfCookie (or login response) and sends it in CsrfHeader
+const (
+	TokenCookie = "auth_token"
+	CsrfCookie  = "csrf_token"
+	CsrfHeader  = "X-CSRF-Token"
+)
+
+// CsrfToken - CSRF token of the session, it is derived from the session id, so it can't be
+// forged without the secret and is valid only with tokens of the same session
+func CsrfToken(secret, sessionId string) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte("csrf:" + sessionId))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// validCsrf - request authenticated by cookie is safe (GET, HEAD, OPTIONS)
+// or its CSRF header matches the session of the token
+func validCsrf(c *fiber.Ctx, secret string, claims *JwtClaims) bool {
+	switch c.Method() {
+	case fiber.MethodGet, fiber.MethodHead, fiber.MethodOptions:
+		return true
+	}
+	if claims.SessionID == "" {
+		return false
+	}
+	return hmac.Equal([]byte(c.Get(CsrfHeader)), []byte(CsrfToken(secret, claims.SessionID)))
+}
+
+// cookieAuth - the request is authenticated by session cookie (see JWTMiddleware)
+func cookieAuth(c *fiber.Ctx) bool {
+	fromCookie, _ := c.Locals("cookie_auth").(bool)
+	return fromCookie
+}
+
+func validAuthMode(mode string) error {
+	switch mode {
+	case "", types.AuthModeToken, types.AuthModeCookie:
+		return nil
+	}
+	return fmt.Errorf("unknown mode %q, expected %q or %q", mode, types.AuthModeToken, types.AuthModeCookie)
+}
+
+// tokenData - response data with the token issued for the session, in cookie mode the token
+// is set in HttpOnly cookie instead and the response contains only CSRF token
+func (h *Handler) tokenData(c *fiber.Ctx, token string, session *model.UserSession, cookie bool) types.LoginSuccessData {
+	if !cookie {
+		return types.LoginSuccessData{Token: token}
+	}
+	csrf := CsrfToken(h.cfg.SecretKey, session.ID.String())
+	h.setCookie(c, TokenCookie, token, session.ExpiresAt, true)
+	h.setCookie(c, CsrfCookie, csrf, session.ExpiresAt, false)
+	return types.LoginSuccessData{CsrfToken: csrf}
+}
+
+// clearSessionCookies - remove cookies of cookie mode (logout)
+func (h *Handler) clearSessionCookies(c *fiber.Ctx) {
+	expired := time.Unix(0, 0)
+	h.setCookie(c, TokenCookie, "", expired, true)
+	h.setCookie(c, CsrfCookie, "", expired, false)
+}
+
+func (h *Handler) setCookie(c *fiber.Ctx, name, value string, expires time.Time, httpOnly bool) {
+	c.Cookie(&fiber.Cookie{
+		Name:     name,
+		Value:    value,
+		Path:     "/",
+		Domain:   h.cfg.AuthCookieDomain,
+		Expires:  expires,
+		Secure:   h.cfg.AuthCookieSecure,
+		HTTPOnly: httpOnly,
+		SameSite: h.cfg.AuthCookieSameSite,
+	})
+}
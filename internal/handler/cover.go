@@ -0,0 +1,120 @@
+package handler
+
+import (
+	"github.com/G0tem/go-service-auth/internal/model"
+	"github.com/G0tem/go-service-auth/internal/types"
+	"github.com/gofiber/fiber/v2"
+)
+
+// Cover upload
+// @Summary Start cover upload
+// @Description Issue short-lived presigned request uploading profile cover (JPEG, PNG or WebP) directly to the storage. The request must be sent with returned headers, then the upload is completed by /auth/me/cover/complete
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param request body types.CoverUploadRequest true "content type and size of the cover"
+// @Success 200 {object} types.CoverUploadResponse
+// @Failure 400 {object} types.FailureErrorResponse
+// @Failure 401 {object} types.FailureResponse
+// @Failure 500 {object} types.FailureErrorResponse
+// @Security ApiKeyAuth
+// @Router /auth/me/cover/upload [post]
+func (h *Handler) coverUpload(c *fiber.Ctx) error {
+	userId, err := currentUserId(c)
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(types.FailureResponse{
+			Status:  "error",
+			Message: "Invalid user id in token",
+		})
+	}
+
+	input := new(types.CoverUploadRequest)
+	if err := c.BodyParser(input); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(types.FailureErrorResponse{
+			Status:  "error",
+			Message: "Error on cover upload request",
+			Error:   err.Error(),
+		})
+	}
+
+	upload, err := h.svc.CreateCoverUpload(c.Context(), userId, input.ContentType, input.Size)
+	if err != nil {
+		return serviceError(c, "Can't start cover upload", err)
+	}
+	return c.Status(fiber.StatusOK).JSON(types.CoverUploadResponse{
+		Status: "ok",
+		Data:   *upload,
+	})
+}
+
+// Cover upload completion
+// @Summary Complete cover upload
+// @Description Copy the uploaded cover to its own key, verify its size and type and attach it to the profile. The upload and the previous cover are deleted, uploads not completed in time are deleted too
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param request body types.CoverCompleteRequest true "key of the upload"
+// @Success 200 {object} types.CoverResponse
+// @Failure 400 {object} types.FailureErrorResponse
+// @Failure 401 {object} types.FailureResponse
+// @Failure 404 {object} types.FailureErrorResponse
+// @Failure 500 {object} types.FailureErrorResponse
+// @Security ApiKeyAuth
+// @Router /auth/me/cover/complete [post]
+func (h *Handler) coverComplete(c *fiber.Ctx) error {
+	userId, err := currentUserId(c)
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(types.FailureResponse{
+			Status:  "error",
+			Message: "Invalid user id in token",
+		})
+	}
+
+	input := new(types.CoverCompleteRequest)
+	if err := c.BodyParser(input); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(types.FailureErrorResponse{
+			Status:  "error",
+			Message: "Error on cover completion request",
+			Error:   err.Error(),
+		})
+	}
+
+	user, err := h.svc.CompleteCoverUpload(c.Context(), userId, input.Key)
+	if err != nil {
+		return serviceError(c, "Can't complete cover upload", err)
+	}
+	return c.Status(fiber.StatusOK).JSON(h.coverResponse(user))
+}
+
+// Delete cover
+// @Summary Delete cover
+// @Description Delete profile cover of the current user
+// @Tags auth
+// @Produce json
+// @Success 200 {object} types.CoverResponse
+// @Failure 401 {object} types.FailureResponse
+// @Failure 500 {object} types.FailureErrorResponse
+// @Security ApiKeyAuth
+// @Router /auth/me/cover [delete]
+func (h *Handler) deleteCover(c *fiber.Ctx) error {
+	userId, err := currentUserId(c)
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(types.FailureResponse{
+			Status:  "error",
+			Message: "Invalid user id in token",
+		})
+	}
+
+	user, err := h.svc.DeleteCover(c.Context(), userId)
+	if err != nil {
+		return serviceError(c, "Can't delete cover", err)
+	}
+	return c.Status(fiber.StatusOK).JSON(h.coverResponse(user))
+}
+
+func (h *Handler) coverResponse(user *model.User) types.CoverResponse {
+	return types.CoverResponse{
+		Status: "ok",
+		Data:   types.CoverData{CoverURL: h.svc.CoverUrl(user)},
+	}
+}
@@ -1,38 +1,39 @@
 package handler
 
 import (
-	"fmt"
-	"log"
 	"time"
 
 	"github.com/G0tem/go-service-auth/internal"
+	"github.com/G0tem/go-service-auth/internal/abac"
 	"github.com/G0tem/go-service-auth/internal/config"
 	"github.com/G0tem/go-service-auth/internal/handler/rbac"
 	"github.com/G0tem/go-service-auth/internal/model"
+	"github.com/G0tem/go-service-auth/internal/service"
 	"github.com/go-redis/redis/v8"
 	"github.com/gofiber/fiber/v2"
 	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
 	fiberSwagger "github.com/swaggo/fiber-swagger"
 	"gorm.io/gorm"
 )
 
 type Handler struct {
 	rbac        *rbac.RBACLayer
+	abac        *abac.Engine
 	db          *gorm.DB
 	cfg         *config.Config
 	userService UserService
 	redis       *redis.Client
+	svc         *service.Service
 }
 
-func NewHandler(db *gorm.DB, rbac *rbac.RBACLayer, cfg *config.Config) *Handler {
-	redisClient := redis.NewClient(&redis.Options{
-		Addr: cfg.RedisAddr, // Адрес Redis (например, "localhost:6379")
-		DB:   cfg.RedisDB,   // Номер базы данных Redis
-	})
-
-	log.Println("Successfully connected to Redis")
+func NewHandler(
+	db *gorm.DB, rbac *rbac.RBACLayer, abac *abac.Engine, svc *service.Service, redisClient *redis.Client, cfg *config.Config,
+) *Handler {
 	return &Handler{
 		rbac:        rbac,
+		svc:         svc,
+		abac:        abac,
 		db:          db,
 		cfg:         cfg,
 		userService: NewHTTPUserService(cfg.UserServiceBaseUrl),
@@ -53,13 +54,28 @@ func (h *Handler) SetupRoutes(app *fiber.App) {
 	// Публичные маршруты - без проверки JWT
 	auth.Post("login", h.login)
 	auth.Post("register", h.register)
+	auth.Post("email/change/confirm", h.emailChangeConfirm)
 
 	// Защищенные маршруты - с middleware JWT
 	authProtected := auth.Group("/")
-	authProtected.Use(JWTMiddleware(cfg.SecretKey))
+	authProtected.Use(JWTMiddleware(cfg.SecretKey), h.rejectRevokedTokens)
 	authProtected.Get("get-me", h.getMe)
+	authProtected.Patch("me", h.updateMe)
+	authProtected.Put("me/avatar", h.uploadAvatar)
+	authProtected.Delete("me/avatar", h.deleteAvatar)
+	authProtected.Post("me/cover/upload", h.coverUpload)
+	authProtected.Post("me/cover/complete", h.coverComplete)
+	authProtected.Delete("me/cover", h.deleteCover)
 	authProtected.Post("password/change", h.passwordChange)
+	authProtected.Post("email/change", h.emailChange)
+	authProtected.Post("username/change", h.usernameChange)
 	authProtected.Post("refresh", h.refresh)
+	authProtected.Post("logout", h.logout)
+	authProtected.Get("sessions", h.listSessions)
+	authProtected.Delete("sessions/:id", h.revokeSession)
+
+	h.setupRbacRoutes(v1, cfg.SecretKey)
+	h.setupUserRoutes(v1, cfg.SecretKey)
 }
 
 func (h *Handler) ResetPassword(user *model.User, newPasswordHash string) error {
@@ -70,15 +86,24 @@ func (h *Handler) ResetPassword(user *model.User, newPasswordHash string) error
 	return nil
 }
 
-func (h *Handler) GetJWT(user *model.User) (string, error) {
+// TokenTTL - lifetime of issued tokens
+const TokenTTL = time.Hour * 72
+
+// GetJWT returns token of the session issued at issuedAt (see issueToken)
+func (h *Handler) GetJWT(user *model.User, sessionId uuid.UUID, issuedAt time.Time) (string, error) {
+	role, roles := h.GetRoles(user)
+
 	// Create the Claims
 	claims := jwt.MapClaims{
 		"user_id":     user.ID.String(),
+		"sid":         sessionId.String(),
 		"username":    user.Username,
 		"email":       user.Email,
-		"role":        user.Role.Name,
+		"role":        role,
+		"roles":       roles,
 		"permissions": h.GetPermissions(user),
-		"exp":         time.Now().Add(time.Hour * 72).Unix(),
+		"iat":         float64(issuedAt.UnixMilli()) / 1000,
+		"exp":         issuedAt.Add(TokenTTL).Unix(),
 	}
 
 	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
@@ -88,14 +113,29 @@ func (h *Handler) GetJWT(user *model.User) (string, error) {
 	return t, err
 }
 
+// GetRoles returns primary role name and names of all roles assigned to user
+func (h *Handler) GetRoles(user *model.User) (string, []string) {
+	roles, err := h.rbac.GetUserRoles(user.ID)
+	if err != nil || len(roles) == 0 {
+		return "", []string{}
+	}
+	primary := roles[0].Name
+	for _, role := range roles {
+		if role.ID == user.RoleID {
+			primary = role.Name
+		}
+	}
+	return primary, internal.Mapping(roles, func(x model.UserRole) string { return x.Name })
+}
+
+// GetPermissions returns effective user permissions (permissions of all assigned roles and direct ones),
+// explicitly denied permissions are prefixed with "!"
 func (h *Handler) GetPermissions(user *model.User) []string {
-	permissions, err := h.rbac.GetRolePermissions(user.Role.Name)
+	permissions, err := h.rbac.GetUserPermissionList(user.ID)
 	if err != nil {
 		return []string{}
 	}
-	return internal.Mapping(permissions, func(x model.UserPermission) string {
-		return fmt.Sprintf("%v:%v", x.Model, x.Action)
-	})
+	return permissions
 }
 
 func (h *Handler) GetPublicUrl() string {
@@ -1,84 +1,291 @@
 package handler
 
 import (
+	"context"
+	"math"
 	"strings"
 	"time"
 
+	"github.com/G0tem/go-service-auth/internal"
+	"github.com/G0tem/go-service-auth/internal/abac"
+	"github.com/G0tem/go-service-auth/internal/model"
+	"github.com/G0tem/go-service-auth/internal/service"
+	"github.com/G0tem/go-service-auth/internal/types"
 	"github.com/gofiber/fiber/v2"
 	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
 )
 
 // JwtClaims represents minimal set of fields extracted from JWT token
 // and propagated through Fiber context.
 type JwtClaims struct {
 	UserID      string    `json:"user_id"`
+	SessionID   string    `json:"sid"`
 	Username    string    `json:"username"`
 	Email       string    `json:"email"`
 	Role        string    `json:"role"`
+	Roles       []string  `json:"roles"`
 	Permissions []string  `json:"permissions"`
 	Exp         time.Time `json:"exp"`
+	IssuedAt    time.Time `json:"iat"`
+	// Audience contains ServiceAudience for tokens of services (user tokens have no audience)
+	Audience []string `json:"aud"`
 }
 
-// JWTMiddleware validates Authorization: Bearer <token>, parses claims,
-// and stores them in fiber context under key "claims".
+// ServiceAudience - audience of service tokens, only services may call administrative gRPC API
+const ServiceAudience = "service"
+
+// JWTMiddleware validates Authorization: Bearer <token> (or session cookie of cookie mode), parses claims,
+// and stores them in fiber context under key "claims" (user id under key "user_id").
+// State-changing requests authenticated by cookie must carry CSRF token of the session.
 func JWTMiddleware(secret string) fiber.Handler {
 	return func(c *fiber.Ctx) error {
 		authHeader := string(c.Request().Header.Peek("Authorization"))
-		if authHeader == "" {
+		token := ""
+		fromCookie := false
+		if authHeader != "" {
+			parts := strings.SplitN(authHeader, " ", 2)
+			if len(parts) != 2 || !strings.EqualFold(parts[0], "Bearer") || strings.TrimSpace(parts[1]) == "" {
+				return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+					"status":  "error",
+					"message": "invalid Authorization header",
+				})
+			}
+			token = strings.TrimSpace(parts[1])
+		} else if token = c.Cookies(TokenCookie); token != "" {
+			fromCookie = true
+		} else {
 			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
 				"status":  "error",
 				"message": "missing Authorization header",
 			})
 		}
-		parts := strings.SplitN(authHeader, " ", 2)
-		if len(parts) != 2 || !strings.EqualFold(parts[0], "Bearer") || strings.TrimSpace(parts[1]) == "" {
+
+		claims, err := ParseJWT(token, secret)
+		if err != nil {
 			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
 				"status":  "error",
-				"message": "invalid Authorization header",
+				"message": internal.ErrInvalidToken,
 			})
 		}
-
-		tokenStr := strings.TrimSpace(parts[1])
-		token, err := jwt.Parse(tokenStr, func(token *jwt.Token) (interface{}, error) {
-			if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-				return nil, fiber.ErrUnauthorized
-			}
-			return []byte(secret), nil
-		})
-		if err != nil || !token.Valid {
-			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+		if fromCookie && !validCsrf(c, secret, claims) {
+			return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
 				"status":  "error",
-				"message": "invalid or expired token",
+				"message": "invalid CSRF token",
 			})
 		}
 
-		claimsMap, ok := token.Claims.(jwt.MapClaims)
-		if !ok {
-			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
-				"status":  "error",
-				"message": "invalid claims",
+		c.Locals("claims", claims)
+		c.Locals("user_id", claims.UserID)
+		c.Locals("cookie_auth", fromCookie)
+		return c.Next()
+	}
+}
+
+// rejectRevokedTokens must follow JWTMiddleware, it rejects tokens of deleted or deactivated users,
+// tokens issued before revocation of user sessions, tokens of revoked or expired sessions
+// and tokens replaced by refresh of the session
+func (h *Handler) rejectRevokedTokens(c *fiber.Ctx) error {
+	claims, ok := c.Locals("claims").(*JwtClaims)
+	if !ok {
+		return c.Status(fiber.StatusUnauthorized).JSON(types.FailureResponse{
+			Status:  "error",
+			Message: internal.ErrInvalidToken,
+		})
+	}
+
+	var user model.User
+	res := h.db.Select("id", "is_active", "tokens_valid_after").Where("id = ?", claims.UserID).Limit(1).Find(&user)
+	if res.Error != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(types.FailureErrorResponse{
+			Status:  "error",
+			Message: internal.ErrGettingUser,
+			Error:   res.Error.Error(),
+		})
+	}
+	if res.RowsAffected == 0 || !user.IsActive || user.TokenRevoked(claims.IssuedAt) {
+		return c.Status(fiber.StatusUnauthorized).JSON(types.FailureResponse{
+			Status:  "error",
+			Message: internal.ErrInvalidToken,
+		})
+	}
+
+	// Tokens issued before session tracking have no session
+	if claims.SessionID != "" {
+		sessionId, err := uuid.Parse(claims.SessionID)
+		if err != nil {
+			return c.Status(fiber.StatusUnauthorized).JSON(types.FailureResponse{
+				Status:  "error",
+				Message: internal.ErrInvalidToken,
+			})
+		}
+		err = h.svc.TouchSession(c.Context(), user.ID, sessionId, claims.IssuedAt)
+		if service.Kind(err) == service.ErrNotFound {
+			return c.Status(fiber.StatusUnauthorized).JSON(types.FailureResponse{
+				Status:  "error",
+				Message: internal.ErrInvalidToken,
+			})
+		}
+		if err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(types.FailureErrorResponse{
+				Status:  "error",
+				Message: "Internal Server Error",
+				Error:   err.Error(),
 			})
 		}
+	}
+	return c.Next()
+}
+
+// GatewayPermission allows users to call the REST gateway (admins are always allowed)
+const GatewayPermission = "gateway:call"
+
+// GatewayGuards - checks of REST gateway requests: JWT, revocation of the token and GatewayPermission.
+// The gRPC server checks permissions of the called method too.
+func (h *Handler) GatewayGuards() []fiber.Handler {
+	return []fiber.Handler{
+		JWTMiddleware(h.cfg.SecretKey),
+		h.rejectRevokedTokens,
+		h.rbac.CheckAccess([]string{model.AdminRole, GatewayPermission}),
+	}
+}
 
-		var expTime time.Time
-		if exp, ok := claimsMap["exp"].(float64); ok {
-			expTime = time.Unix(int64(exp), 0)
+// ParseJWT verifies signature and expiration of the token signed with secret and extracts its claims
+func ParseJWT(tokenStr, secret string) (*JwtClaims, error) {
+	token, err := jwt.Parse(tokenStr, func(token *jwt.Token) (interface{}, error) {
+		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+			return nil, fiber.ErrUnauthorized
 		}
+		return []byte(secret), nil
+	})
+	if err != nil {
+		return nil, internal.PrintError(internal.ErrInvalidToken, err)
+	}
+	if !token.Valid {
+		return nil, internal.PrintError(internal.ErrInvalidToken, nil)
+	}
 
-		claims := &JwtClaims{
-			UserID:      asString(claimsMap["user_id"]),
-			Username:    asString(claimsMap["username"]),
-			Email:       asString(claimsMap["email"]),
-			Role:        asString(claimsMap["role"]),
-			Permissions: asStringSlice(claimsMap["permissions"]),
-			Exp:         expTime,
+	claimsMap, ok := token.Claims.(jwt.MapClaims)
+	if !ok {
+		return nil, internal.PrintError(internal.ErrInvalidClaims, nil)
+	}
+
+	var expTime time.Time
+	if exp, ok := claimsMap["exp"].(float64); ok {
+		expTime = time.Unix(int64(exp), 0)
+	}
+	// iat has millisecond precision to compare it with the time of user sessions revocation
+	var issuedAt time.Time
+	if iat, ok := claimsMap["iat"].(float64); ok {
+		issuedAt = time.UnixMilli(int64(math.Round(iat * 1000)))
+	}
+
+	audience, err := claimsMap.GetAudience()
+	if err != nil {
+		return nil, internal.PrintError(internal.ErrInvalidClaims, err)
+	}
+
+	return &JwtClaims{
+		UserID:      asString(claimsMap["user_id"]),
+		SessionID:   asString(claimsMap["sid"]),
+		Username:    asString(claimsMap["username"]),
+		Email:       asString(claimsMap["email"]),
+		Role:        asString(claimsMap["role"]),
+		Roles:       asStringSlice(claimsMap["roles"]),
+		Permissions: asStringSlice(claimsMap["permissions"]),
+		Exp:         expTime,
+		IssuedAt:    issuedAt,
+		Audience:    audience,
+	}, nil
+}
+
+// ResourceAttributes extracts attributes of the requested resource for ABAC policy
+type ResourceAttributes func(c *fiber.Ctx) abac.Attributes
+
+// ResourceFromParams uses route parameters as resource attributes (e.g. "id" of /users/:id)
+func ResourceFromParams(params ...string) ResourceAttributes {
+	return func(c *fiber.Ctx) abac.Attributes {
+		attributes := abac.Attributes{}
+		for _, param := range params {
+			attributes[param] = c.Params(param)
 		}
+		return attributes
+	}
+}
 
-		c.Locals("claims", claims)
+// SubjectLoader loads ABAC subject attributes of the user (see abac.SubjectFromUser)
+type SubjectLoader func(ctx context.Context, userId uuid.UUID) (abac.Attributes, error)
+
+// Authorize allows request only if ABAC policy allows current user (see JWTMiddleware) to perform
+// action ("model:action") on the resource. The subject is the stored user with current roles and permissions,
+// environment contains current time and client ip.
+func (h *Handler) Authorize(action string, resource ResourceAttributes) fiber.Handler {
+	return AuthorizeWith(h.abac, h.abacSubject, action, resource)
+}
+
+// AuthorizeWith is Authorize with the engine and the subject loader
+func AuthorizeWith(engine *abac.Engine, subject SubjectLoader, action string, resource ResourceAttributes) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		userId := uuid.Nil
+		if claims, ok := c.Locals("claims").(*JwtClaims); ok {
+			userId, _ = uuid.Parse(claims.UserID)
+		}
+		if userId == uuid.Nil || engine == nil {
+			return c.Status(fiber.StatusForbidden).JSON(types.FailureResponse{
+				Status:  "error",
+				Message: internal.ErrAccessNotPermitted,
+			})
+		}
+		attributes, err := subject(c.Context(), userId)
+		if service.Kind(err) == service.ErrNotFound {
+			return c.Status(fiber.StatusForbidden).JSON(types.FailureResponse{
+				Status:  "error",
+				Message: internal.ErrAccessNotPermitted,
+			})
+		}
+		if err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(types.FailureErrorResponse{
+				Status:  "error",
+				Message: internal.ErrGettingUser,
+				Error:   err.Error(),
+			})
+		}
+
+		environment := abac.Environment(time.Now())
+		environment["ip"] = c.IP()
+		decision := engine.Evaluate(abac.Request{
+			Action:      action,
+			Subject:     attributes,
+			Resource:    resource(c),
+			Environment: environment,
+		})
+		if !decision.Allowed {
+			return c.Status(fiber.StatusForbidden).JSON(types.FailureResponse{
+				Status:  "error",
+				Message: internal.ErrAccessNotPermitted,
+			})
+		}
 		return c.Next()
 	}
 }
 
+// abacSubject - subject attributes of the stored user, the same as of gRPC Authorize
+func (h *Handler) abacSubject(ctx context.Context, userId uuid.UUID) (abac.Attributes, error) {
+	user, err := h.svc.GetUser(ctx, userId)
+	if err != nil {
+		return nil, err
+	}
+	roles, err := h.rbac.GetUserRoles(userId)
+	if err != nil {
+		return nil, err
+	}
+	permissions, err := h.rbac.GetUserPermissionList(userId)
+	if err != nil {
+		return nil, err
+	}
+	return abac.SubjectFromUser(*user, internal.Mapping(roles, func(x model.UserRole) string { return x.Name }), permissions), nil
+}
+
 func asString(v any) string {
 	if v == nil {
 		return ""
@@ -0,0 +1,233 @@
+package rbac
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/google/uuid"
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	cacheVersionKey        = "rbac:cache:version"
+	cacheGenerationKey     = "rbac:cache:generation:"
+	cacheInvalidateChannel = "rbac:cache:invalidate"
+	// cacheInvalidateAll is the pub/sub message invalidating everything (otherwise message is user id)
+	cacheInvalidateAll = "*"
+)
+
+type cacheEntry struct {
+	value   []string
+	expires time.Time
+}
+
+// PermissionCache - two level cache of role and user effective permissions: in-process one and Redis.
+//
+// Redis keys contain version which is increased on any change of roles or permissions and generation
+// of the key which is increased on invalidation of the key (see InvalidateUser), so stale keys are never
+// read and just expire. Version and generation are read before the value is loaded, so the value loaded
+// before invalidation is written under the stale key. Invalidations are broadcast via Redis pub/sub
+// to in-process caches of other replicas (see Listen). Nil cache doesn't cache anything.
+type PermissionCache struct {
+	redis *redis.Client
+	ttl   time.Duration
+
+	mu      sync.RWMutex
+	version int64
+	local   map[string]cacheEntry
+	// generations - in-process invalidations of keys, values loaded before them are not cached locally
+	generations map[string]int64
+}
+
+func NewPermissionCache(ctx context.Context, redisClient *redis.Client, ttl time.Duration) *PermissionCache {
+	cache := &PermissionCache{
+		redis:       redisClient,
+		ttl:         ttl,
+		local:       map[string]cacheEntry{},
+		generations: map[string]int64{},
+	}
+	cache.refreshVersion(ctx)
+	return cache
+}
+
+func roleCacheKey(roleName string) string {
+	return "role:" + roleName
+}
+
+func userCacheKey(userId uuid.UUID) string {
+	return "user:" + userId.String()
+}
+
+func (cache *PermissionCache) redisKey(version int64, key string, generation int64) string {
+	return fmt.Sprintf("rbac:cache:v%d:%s:g%d", version, key, generation)
+}
+
+// generation - number of invalidations of the key (see InvalidateUser)
+func (cache *PermissionCache) generation(ctx context.Context, key string) int64 {
+	generation, err := cache.redis.Get(ctx, cacheGenerationKey+key).Int64()
+	if err != nil && err != redis.Nil {
+		log.Error().Err(err).Msgf("rbac cache: get generation of %v", key)
+	}
+	return generation
+}
+
+func (cache *PermissionCache) refreshVersion(ctx context.Context) {
+	version, err := cache.redis.Get(ctx, cacheVersionKey).Int64()
+	if err != nil && err != redis.Nil {
+		log.Error().Err(err).Msg("rbac cache: get version")
+		return
+	}
+
+	cache.mu.Lock()
+	cache.version = version
+	cache.local = map[string]cacheEntry{}
+	cache.generations = map[string]int64{}
+	cache.mu.Unlock()
+}
+
+// Get - cached value of key or value returned by load (which is cached then)
+func (cache *PermissionCache) Get(ctx context.Context, key string, load func() ([]string, error)) ([]string, error) {
+	if cache == nil {
+		return load()
+	}
+
+	cache.mu.RLock()
+	entry, ok := cache.local[key]
+	version, localGeneration := cache.version, cache.generations[key]
+	cache.mu.RUnlock()
+	if ok && time.Now().Before(entry.expires) {
+		return entry.value, nil
+	}
+
+	redisKey := cache.redisKey(version, key, cache.generation(ctx, key))
+	data, err := cache.redis.Get(ctx, redisKey).Bytes()
+	if err == nil {
+		var value []string
+		if err = json.Unmarshal(data, &value); err == nil {
+			cache.setLocal(version, localGeneration, key, value)
+			return value, nil
+		}
+	}
+	if err != redis.Nil {
+		log.Error().Err(err).Msgf("rbac cache: get %v", redisKey)
+	}
+
+	value, err := load()
+	if err != nil {
+		return nil, err
+	}
+
+	if data, err = json.Marshal(value); err == nil {
+		err = cache.redis.Set(ctx, redisKey, data, cache.ttl).Err()
+	}
+	if err != nil {
+		log.Error().Err(err).Msgf("rbac cache: set %v", redisKey)
+	}
+	cache.setLocal(version, localGeneration, key, value)
+
+	return value, nil
+}
+
+func (cache *PermissionCache) setLocal(version, generation int64, key string, value []string) {
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+
+	// Skip value loaded before invalidation
+	if cache.version == version && cache.generations[key] == generation {
+		cache.local[key] = cacheEntry{value: value, expires: time.Now().Add(cache.ttl)}
+	}
+}
+
+// InvalidateAll - drop everything cached (roles, permissions or links between them have changed)
+func (cache *PermissionCache) InvalidateAll(ctx context.Context) {
+	if cache == nil {
+		return
+	}
+
+	version, err := cache.redis.Incr(ctx, cacheVersionKey).Result()
+	if err != nil {
+		log.Error().Err(err).Msg("rbac cache: increase version")
+	}
+
+	cache.mu.Lock()
+	if err == nil {
+		cache.version = version
+	}
+	cache.local = map[string]cacheEntry{}
+	cache.generations = map[string]int64{}
+	cache.mu.Unlock()
+
+	cache.publish(ctx, cacheInvalidateAll)
+}
+
+// InvalidateUser - drop cached user permissions (roles or permissions of the user have changed)
+func (cache *PermissionCache) InvalidateUser(ctx context.Context, userId uuid.UUID) {
+	if cache == nil {
+		return
+	}
+
+	// New generation makes the cached value and values being loaded now unreachable,
+	// it is increased first so local cache isn't filled with the value of the old generation.
+	// Generation outlives values cached with the previous ones, so it can expire and restart from zero
+	key := userCacheKey(userId)
+	_, err := cache.redis.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.Incr(ctx, cacheGenerationKey+key)
+		pipe.Expire(ctx, cacheGenerationKey+key, 2*cache.ttl)
+		return nil
+	})
+	if err != nil {
+		log.Error().Err(err).Msg("rbac cache: increase user generation")
+	}
+	cache.invalidateLocal(key)
+
+	cache.publish(ctx, userId.String())
+}
+
+func (cache *PermissionCache) invalidateLocal(key string) {
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+
+	cache.generations[key]++
+	delete(cache.local, key)
+}
+
+func (cache *PermissionCache) publish(ctx context.Context, message string) {
+	if err := cache.redis.Publish(ctx, cacheInvalidateChannel, message).Err(); err != nil {
+		log.Error().Err(err).Msg("rbac cache: publish invalidation")
+	}
+}
+
+// Listen - apply invalidations published by other replicas to the in-process cache until ctx is done
+func (cache *PermissionCache) Listen(ctx context.Context) {
+	if cache == nil {
+		return
+	}
+
+	subscription := cache.redis.Subscribe(ctx, cacheInvalidateChannel)
+	defer subscription.Close()
+	messages := subscription.Channel()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case message, ok := <-messages:
+			if !ok {
+				return
+			}
+			if message.Payload == cacheInvalidateAll {
+				cache.refreshVersion(ctx)
+				continue
+			}
+			userId, err := uuid.Parse(message.Payload)
+			if err != nil {
+				continue
+			}
+			cache.invalidateLocal(userCacheKey(userId))
+		}
+	}
+}
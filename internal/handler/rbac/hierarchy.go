@@ -0,0 +1,255 @@
+package rbac
+
+import (
+	"fmt"
+	"slices"
+
+	"github.com/G0tem/go-service-auth/internal"
+	"github.com/G0tem/go-service-auth/internal/model"
+	"github.com/G0tem/go-service-auth/internal/types"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// RoleHierarchy - in-memory snapshot of roles and links to their parents
+type RoleHierarchy struct {
+	byName map[string]model.UserRole
+	byID   map[uuid.UUID]model.UserRole
+}
+
+func NewRoleHierarchy(roles []model.UserRole) *RoleHierarchy {
+	return &RoleHierarchy{
+		byName: internal.MappingToMap(roles, func(x model.UserRole) (string, model.UserRole) { return x.Name, x }),
+		byID:   internal.MappingToMap(roles, func(x model.UserRole) (uuid.UUID, model.UserRole) { return x.ID, x }),
+	}
+}
+
+// Lineage - role itself followed by its ancestors (nearest parent first)
+func (h *RoleHierarchy) Lineage(roleName string) ([]model.UserRole, error) {
+	role, ok := h.byName[roleName]
+	if !ok {
+		return nil, internal.PrintError(internal.ErrRoleNotFound, fmt.Errorf("%v", roleName))
+	}
+
+	lineage := []model.UserRole{role}
+	for role.ParentID != nil {
+		parent, ok := h.byID[*role.ParentID]
+		if !ok {
+			break
+		}
+		if slices.ContainsFunc(lineage, func(x model.UserRole) bool { return x.ID == parent.ID }) {
+			return nil, internal.PrintError(internal.ErrRoleHierarchyCycle, fmt.Errorf("%v", roleName))
+		}
+		lineage = append(lineage, parent)
+		role = parent
+	}
+
+	return lineage, nil
+}
+
+// Expand - given roles and all their ancestors without duplicates (unknown roles are skipped)
+func (h *RoleHierarchy) Expand(roleNames ...string) ([]model.UserRole, error) {
+	result := make([]model.UserRole, 0, len(roleNames))
+	for _, roleName := range roleNames {
+		if _, ok := h.byName[roleName]; !ok {
+			continue
+		}
+		lineage, err := h.Lineage(roleName)
+		if err != nil {
+			return nil, err
+		}
+		for _, role := range lineage {
+			if !slices.ContainsFunc(result, func(x model.UserRole) bool { return x.ID == role.ID }) {
+				result = append(result, role)
+			}
+		}
+	}
+
+	return result, nil
+}
+
+// ParentName - name of the parent role or empty string
+func (h *RoleHierarchy) ParentName(roleName string) string {
+	role, ok := h.byName[roleName]
+	if !ok || role.ParentID == nil {
+		return ""
+	}
+	return h.byID[*role.ParentID].Name
+}
+
+// CheckParent - returns error if parentName can't be set as parent of roleName (unknown role or cycle)
+func (h *RoleHierarchy) CheckParent(roleName, parentName string) error {
+	if _, ok := h.byName[roleName]; !ok {
+		return internal.PrintError(internal.ErrRoleNotFound, fmt.Errorf("%v", roleName))
+	}
+	if parentName == "" {
+		return nil
+	}
+
+	lineage, err := h.Lineage(parentName)
+	if err != nil {
+		return err
+	}
+	if slices.ContainsFunc(lineage, func(x model.UserRole) bool { return x.Name == roleName }) {
+		return internal.PrintError(internal.ErrRoleHierarchyCycle, fmt.Errorf("%v -> %v", roleName, parentName))
+	}
+
+	return nil
+}
+
+// GetRoleHierarchy - load roles hierarchy from the database
+func (layer *RBACLayer) GetRoleHierarchy() (*RoleHierarchy, error) {
+	roles, err := layer.GetRoles()
+	if err != nil {
+		return nil, err
+	}
+	return NewRoleHierarchy(roles), nil
+}
+
+// SetRoleParent - set (or clear with empty parentName) parent of the role in the database
+func (layer *RBACLayer) SetRoleParent(roleName, parentName string) (role model.UserRole, err error) {
+	err = layer.DB.Transaction(func(tx *gorm.DB) error {
+		var roles []model.UserRole
+		// Lock all roles to prevent concurrent changes producing a cycle
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Find(&roles).Error
+		if err != nil {
+			return internal.PrintError(internal.ErrGettingRoles, err)
+		}
+
+		hierarchy := NewRoleHierarchy(roles)
+		if err = hierarchy.CheckParent(roleName, parentName); err != nil {
+			return err
+		}
+
+		role = hierarchy.byName[roleName]
+		role.ParentID = nil
+		if parentName != "" {
+			parentID := hierarchy.byName[parentName].ID
+			role.ParentID = &parentID
+		}
+
+		err = tx.Model(&model.UserRole{}).Where("id = ?", role.ID).Update("parent_id", role.ParentID).Error
+		if err != nil {
+			return internal.PrintError(internal.ErrSettingRoleParent, err)
+		}
+
+		return nil
+	})
+	if err == nil {
+		layer.invalidateAll()
+	}
+
+	return
+}
+
+// InitHierarchy - set parents of roles, where hierarchy is map of role name to parent role name
+func (layer *RBACLayer) InitHierarchy(hierarchy map[string]string) error {
+	for roleName, parentName := range hierarchy {
+		if _, err := layer.SetRoleParent(roleName, parentName); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// ExplainRolePermissions - effective role permissions with roles contributed each of them
+func (layer *RBACLayer) ExplainRolePermissions(roleName string) ([]types.PermissionExplanation, error) {
+	hierarchy, err := layer.GetRoleHierarchy()
+	if err != nil {
+		return nil, err
+	}
+
+	explanations := []types.PermissionExplanation{}
+	err = layer.explainRole(hierarchy, roleName, &explanations)
+	if err != nil {
+		return nil, err
+	}
+
+	return explanations, nil
+}
+
+// ExplainUserPermissions - effective user permissions with roles (or direct grant) contributed each of them
+func (layer *RBACLayer) ExplainUserPermissions(userId uuid.UUID) ([]types.PermissionExplanation, error) {
+	hierarchy, err := layer.GetRoleHierarchy()
+	if err != nil {
+		return nil, err
+	}
+
+	roles, err := layer.GetUserRoles(userId)
+	if err != nil {
+		return nil, err
+	}
+
+	explanations := []types.PermissionExplanation{}
+	for _, role := range roles {
+		err = layer.explainRole(hierarchy, role.Name, &explanations)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	directPermissions, err := layer.GetUserDirectPermissions(userId)
+	if err != nil {
+		return nil, err
+	}
+	for _, permission := range directPermissions {
+		addExplanation(&explanations, DirectPermissionString(permission), types.PermissionSource{
+			Kind: types.PermissionSourceDirect,
+			Deny: permission.Deny,
+		})
+	}
+
+	return explanations, nil
+}
+
+func (layer *RBACLayer) explainRole(hierarchy *RoleHierarchy, roleName string, explanations *[]types.PermissionExplanation) error {
+	lineage, err := hierarchy.Lineage(roleName)
+	if err != nil {
+		return err
+	}
+
+	for i, role := range lineage {
+		rolePermissions, err := layer.getOwnRolePermissions(role.ID)
+		if err != nil {
+			return err
+		}
+		for _, rolePermission := range rolePermissions {
+			permission := PermissionString(*rolePermission.Permission)
+			if rolePermission.Deny {
+				permission = DenyPrefix + permission
+			}
+			addExplanation(explanations, permission, types.PermissionSource{
+				Kind: types.PermissionSourceRole,
+				Role: role.Name,
+				Via:  internal.Mapping(lineage[:i+1], func(x model.UserRole) string { return x.Name }),
+				Deny: rolePermission.Deny,
+			})
+		}
+	}
+
+	return nil
+}
+
+// getOwnRolePermissions - permissions linked to the role directly (without inherited ones)
+func (layer *RBACLayer) getOwnRolePermissions(roleId uuid.UUID) (rolePermissions []model.UserRolePermission, err error) {
+	err = layer.DB.Joins("Permission").
+		Where("user_role_permissions.role_id = ?", roleId).
+		Order(`user_role_permissions.deny, "Permission".model, "Permission".action`).
+		Find(&rolePermissions).Error
+	if err != nil {
+		return nil, internal.PrintError(internal.ErrGettingRolePermissions, err)
+	}
+
+	return
+}
+
+func addExplanation(explanations *[]types.PermissionExplanation, permission string, source types.PermissionSource) {
+	pos := slices.IndexFunc(*explanations, func(x types.PermissionExplanation) bool { return x.Permission == permission })
+	if pos < 0 {
+		*explanations = append(*explanations, types.PermissionExplanation{Permission: permission})
+		pos = len(*explanations) - 1
+	}
+	(*explanations)[pos].Sources = append((*explanations)[pos].Sources, source)
+}
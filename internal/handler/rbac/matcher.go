@@ -0,0 +1,134 @@
+package rbac
+
+import (
+	"strings"
+)
+
+const (
+	// Wildcard matches any model or action
+	Wildcard = "*"
+	// DenyPrefix marks explicitly denied permission ("!model:action")
+	DenyPrefix = "!"
+	// SuperuserPermission grants everything which is not explicitly denied
+	SuperuserPermission = "*:*"
+	// LegacySuperuserPermission is an alias of SuperuserPermission kept for compatibility
+	LegacySuperuserPermission = "admin:all"
+)
+
+// PermissionPattern - parsed "model:action" permission where both parts may be a wildcard
+type PermissionPattern struct {
+	Model  string
+	Action string
+	Deny   bool
+}
+
+// ParsePermission - parse "model:action", "!model:action", "orders:*", "*:read", "*:*"
+func ParsePermission(permission string) PermissionPattern {
+	permission = strings.TrimSpace(permission)
+	deny := strings.HasPrefix(permission, DenyPrefix)
+	permission = strings.TrimPrefix(permission, DenyPrefix)
+	if permission == LegacySuperuserPermission {
+		permission = SuperuserPermission
+	}
+
+	modelName, action, _ := strings.Cut(permission, ":")
+	return PermissionPattern{
+		Model:  strings.TrimSpace(modelName),
+		Action: strings.TrimSpace(action),
+		Deny:   deny,
+	}
+}
+
+func (p PermissionPattern) String() string {
+	result := p.Model + ":" + p.Action
+	if p.Deny {
+		return DenyPrefix + result
+	}
+	return result
+}
+
+// Matches - pattern covers required permission. A wildcard in the required permission
+// is matched only by a wildcard in the pattern, so "orders:read" doesn't cover "orders:*".
+func (p PermissionPattern) Matches(required PermissionPattern) bool {
+	return matchSegment(p.Model, required.Model) && matchSegment(p.Action, required.Action)
+}
+
+// Overlaps - pattern covers at least one permission covered by required, so "!orders:delete"
+// overlaps "orders:*" (every orders action) and "*:*"
+func (p PermissionPattern) Overlaps(required PermissionPattern) bool {
+	return overlapSegment(p.Model, required.Model) && overlapSegment(p.Action, required.Action)
+}
+
+func matchSegment(pattern, value string) bool {
+	return pattern == Wildcard || pattern == value
+}
+
+func overlapSegment(pattern, value string) bool {
+	return pattern == Wildcard || value == Wildcard || pattern == value
+}
+
+// PermissionSet - set of allowed and denied permission patterns.
+//
+// Explicit deny always wins: a permission is allowed when at least one allow pattern
+// matches it and no deny pattern does, regardless of how specific the patterns are.
+// So with "*:*" and "!orders:delete" everything except "orders:delete" is allowed and
+// with "orders:*" and "!*:delete" any orders action except "delete" is allowed. Wildcard requirement
+// means every action (or model) it covers, so it is denied by any deny pattern overlapping it:
+// with "orders:*" and "!orders:delete" "orders:*" is not allowed.
+type PermissionSet struct {
+	allow []PermissionPattern
+	deny  []PermissionPattern
+}
+
+func NewPermissionSet(permissions ...string) *PermissionSet {
+	set := &PermissionSet{}
+	for _, permission := range permissions {
+		if strings.TrimSpace(permission) == "" {
+			continue
+		}
+		pattern := ParsePermission(permission)
+		if pattern.Deny {
+			set.deny = append(set.deny, pattern)
+		} else {
+			set.allow = append(set.allow, pattern)
+		}
+	}
+	return set
+}
+
+// Allows - all required permissions are allowed
+func (set *PermissionSet) Allows(required ...string) bool {
+	for _, permission := range required {
+		if _, ok := set.Match(permission); !ok {
+			return false
+		}
+	}
+	return len(required) > 0
+}
+
+// AllowsAny - at least one of required permissions is allowed
+func (set *PermissionSet) AllowsAny(required ...string) bool {
+	for _, permission := range required {
+		if _, ok := set.Match(permission); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// Match - check required permission and return pattern decided the result
+// (matched deny pattern, matched allow pattern or empty pattern if nothing matched)
+func (set *PermissionSet) Match(required string) (PermissionPattern, bool) {
+	requiredPattern := ParsePermission(required)
+	for _, pattern := range set.deny {
+		if pattern.Overlaps(requiredPattern) {
+			return pattern, false
+		}
+	}
+	for _, pattern := range set.allow {
+		if pattern.Matches(requiredPattern) {
+			return pattern, true
+		}
+	}
+	return PermissionPattern{}, false
+}
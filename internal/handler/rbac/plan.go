@@ -0,0 +1,395 @@
+package rbac
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+
+	"github.com/G0tem/go-service-auth/internal"
+	"github.com/G0tem/go-service-auth/internal/model"
+	"github.com/G0tem/go-service-auth/internal/types"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	PlanFormatText = "text"
+	PlanFormatJSON = "json"
+
+	PlanFieldDescription = "description"
+	PlanFieldParent      = "parent"
+)
+
+// SyncState - snapshot of roles, permissions and links between them the sync plan is computed from
+type SyncState struct {
+	Roles           []model.UserRole
+	Permissions     []model.UserPermission
+	RolePermissions []model.UserRolePermission
+}
+
+func loadSyncState(tx *gorm.DB) (state SyncState, err error) {
+	if err = tx.Find(&state.Roles).Error; err != nil {
+		return state, internal.PrintError(internal.ErrGettingRoles, err)
+	}
+	if err = tx.Find(&state.Permissions).Error; err != nil {
+		return state, internal.PrintError(internal.ErrGettingPermissions, err)
+	}
+	if err = tx.Find(&state.RolePermissions).Error; err != nil {
+		return state, internal.PrintError(internal.ErrGettingRolePermissions, err)
+	}
+	return
+}
+
+// lockRoles - lock all roles to serialize synchronizations and hierarchy changes (see SetRoleParent)
+func lockRoles(tx *gorm.DB) error {
+	var roles []model.UserRole
+	err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Find(&roles).Error
+	if err != nil {
+		return internal.PrintError(internal.ErrGettingRoles, err)
+	}
+	return nil
+}
+
+// parseMatrixPermissions - comma separated permissions of the matrix role ("!" prefix denies permission),
+// a permission listed twice keeps the last deny flag
+func parseMatrixPermissions(rolePermissions string) ([]types.RbacPlanLink, error) {
+	var links []types.RbacPlanLink
+	for _, permission := range strings.Split(rolePermissions, ",") {
+		permission = strings.TrimSpace(permission)
+		// Role without permissions
+		if permission == "" {
+			continue
+		}
+		deny := strings.HasPrefix(permission, DenyPrefix)
+		modelName, action, ok := strings.Cut(strings.TrimPrefix(permission, DenyPrefix), ":")
+		modelName, action = strings.TrimSpace(modelName), strings.TrimSpace(action)
+		if !ok || modelName == "" || action == "" {
+			return nil, internal.PrintError(internal.ErrAddingPermissions, fmt.Errorf("invalid permission %v", permission))
+		}
+
+		link := types.RbacPlanLink{Permission: modelName + ":" + action, Deny: deny}
+		pos := slices.IndexFunc(links, func(x types.RbacPlanLink) bool { return x.Permission == link.Permission })
+		if pos < 0 {
+			links = append(links, link)
+		} else {
+			links[pos] = link
+		}
+	}
+	return links, nil
+}
+
+// Plan - changes required to synchronize the state with matrix (map of role name to comma separated permissions)
+func (state SyncState) Plan(matrix map[string]string, action SyncRbacModelAction) (*types.RbacSyncPlan, error) {
+	plan := &types.RbacSyncPlan{
+		SyncMode:          action.String(),
+		AddRoles:          []string{},
+		AddPermissions:    []string{},
+		AddLinks:          []types.RbacPlanLink{},
+		UpdateLinks:       []types.RbacPlanLink{},
+		UpdateRoles:       []types.RbacPlanRoleChange{},
+		DeleteLinks:       []types.RbacPlanLink{},
+		DeletePermissions: []string{},
+		DeleteRoles:       []string{},
+	}
+
+	rolesByName := internal.MappingToMap(state.Roles, func(x model.UserRole) (string, model.UserRole) { return x.Name, x })
+	permissionsByID := internal.MappingToMap(state.Permissions, func(x model.UserPermission) (uuid.UUID, string) { return x.ID, PermissionString(x) })
+	permissionsByName := internal.MappingToMap(state.Permissions, func(x model.UserPermission) (string, uuid.UUID) { return PermissionString(x), x.ID })
+	linksDeny := internal.MappingToMap(state.RolePermissions, func(x model.UserRolePermission) ([2]uuid.UUID, bool) {
+		return [2]uuid.UUID{x.RoleID, x.PermissionID}, x.Deny
+	})
+
+	wantedPermissions := map[string]bool{}
+	for _, roleName := range slices.Sorted(maps.Keys(matrix)) {
+		links, err := parseMatrixPermissions(matrix[roleName])
+		if err != nil {
+			return nil, err
+		}
+
+		role, roleExists := rolesByName[roleName]
+		if !roleExists {
+			plan.AddRoles = append(plan.AddRoles, roleName)
+		}
+
+		for _, link := range links {
+			link.Role = roleName
+			permissionID, permissionExists := permissionsByName[link.Permission]
+			if !permissionExists && !wantedPermissions[link.Permission] {
+				plan.AddPermissions = append(plan.AddPermissions, link.Permission)
+			}
+			wantedPermissions[link.Permission] = true
+
+			deny, linked := linksDeny[[2]uuid.UUID{role.ID, permissionID}]
+			switch {
+			case !roleExists || !permissionExists || !linked:
+				plan.AddLinks = append(plan.AddLinks, link)
+			case deny != link.Deny:
+				plan.UpdateLinks = append(plan.UpdateLinks, link)
+			}
+		}
+
+		if !roleExists || action == AddMissedOnly {
+			continue
+		}
+		for _, rolePermission := range state.RolePermissions {
+			permission := permissionsByID[rolePermission.PermissionID]
+			if rolePermission.RoleID != role.ID || slices.ContainsFunc(links, func(x types.RbacPlanLink) bool { return x.Permission == permission }) {
+				continue
+			}
+			plan.DeleteLinks = append(plan.DeleteLinks, types.RbacPlanLink{
+				Role:       roleName,
+				Permission: permission,
+				Deny:       rolePermission.Deny,
+			})
+		}
+	}
+	slices.SortFunc(plan.DeleteLinks, func(a, b types.RbacPlanLink) int {
+		return strings.Compare(a.Role+" "+a.Permission, b.Role+" "+b.Permission)
+	})
+
+	if action == DeleteOutdatedRolesAndPermissions {
+		for _, permission := range state.Permissions {
+			if !wantedPermissions[PermissionString(permission)] {
+				plan.DeletePermissions = append(plan.DeletePermissions, PermissionString(permission))
+			}
+		}
+		for _, role := range state.Roles {
+			if _, ok := matrix[role.Name]; !ok {
+				plan.DeleteRoles = append(plan.DeleteRoles, role.Name)
+			}
+		}
+		slices.Sort(plan.DeletePermissions)
+		slices.Sort(plan.DeleteRoles)
+	}
+
+	return plan, nil
+}
+
+// PlanPolicy - changes required to synchronize the state with the policy (including descriptions and parents of roles)
+func (state SyncState) PlanPolicy(policy *types.RbacPolicy, action SyncRbacModelAction) (*types.RbacSyncPlan, error) {
+	plan, err := state.Plan(PolicyMatrix(policy), action)
+	if err != nil {
+		return nil, err
+	}
+
+	hierarchy := NewRoleHierarchy(state.Roles)
+	for _, policyRole := range policy.Roles {
+		var description, parent string
+		if role, ok := hierarchy.byName[policyRole.Name]; ok {
+			description, parent = role.Description, hierarchy.ParentName(role.Name)
+		}
+		if description != policyRole.Description {
+			plan.UpdateRoles = append(plan.UpdateRoles, types.RbacPlanRoleChange{
+				Role: policyRole.Name, Field: PlanFieldDescription, From: description, To: policyRole.Description,
+			})
+		}
+		if parent != policyRole.Parent {
+			plan.UpdateRoles = append(plan.UpdateRoles, types.RbacPlanRoleChange{
+				Role: policyRole.Name, Field: PlanFieldParent, From: parent, To: policyRole.Parent,
+			})
+		}
+	}
+
+	return plan, nil
+}
+
+// PlanChanges - total number of changes in the plan
+func PlanChanges(plan *types.RbacSyncPlan) int {
+	return len(plan.AddRoles) + len(plan.AddPermissions) + len(plan.AddLinks) + len(plan.UpdateLinks) +
+		len(plan.UpdateRoles) + len(plan.DeleteLinks) + len(plan.DeletePermissions) + len(plan.DeleteRoles)
+}
+
+// FormatPlan - human-readable plan ("+" is added, "~" is changed and "-" is deleted)
+func FormatPlan(plan *types.RbacSyncPlan) string {
+	var text strings.Builder
+	fmt.Fprintf(&text, "RBAC sync plan (%v):\n", plan.SyncMode)
+
+	linkString := func(link types.RbacPlanLink) string {
+		if link.Deny {
+			return link.Role + " -> " + DenyPrefix + link.Permission
+		}
+		return link.Role + " -> " + link.Permission
+	}
+	for _, role := range plan.AddRoles {
+		fmt.Fprintf(&text, "  + role %v\n", role)
+	}
+	for _, permission := range plan.AddPermissions {
+		fmt.Fprintf(&text, "  + permission %v\n", permission)
+	}
+	for _, link := range plan.AddLinks {
+		fmt.Fprintf(&text, "  + link %v\n", linkString(link))
+	}
+	for _, link := range plan.UpdateLinks {
+		fmt.Fprintf(&text, "  ~ link %v\n", linkString(link))
+	}
+	for _, change := range plan.UpdateRoles {
+		fmt.Fprintf(&text, "  ~ role %v %v %q -> %q\n", change.Role, change.Field, change.From, change.To)
+	}
+	for _, link := range plan.DeleteLinks {
+		fmt.Fprintf(&text, "  - link %v\n", linkString(link))
+	}
+	for _, permission := range plan.DeletePermissions {
+		fmt.Fprintf(&text, "  - permission %v\n", permission)
+	}
+	for _, role := range plan.DeleteRoles {
+		fmt.Fprintf(&text, "  - role %v\n", role)
+	}
+
+	if changes := PlanChanges(plan); changes > 0 {
+		fmt.Fprintf(&text, "%v change(s)\n", changes)
+	} else {
+		text.WriteString("No changes\n")
+	}
+
+	return text.String()
+}
+
+// PlanSync - dry run of Init: changes required to synchronize the database with matrix, nothing is written
+func (layer *RBACLayer) PlanSync(matrix map[string]string, action SyncRbacModelAction) (*types.RbacSyncPlan, error) {
+	state, err := loadSyncState(layer.DB)
+	if err != nil {
+		return nil, err
+	}
+	return state.Plan(matrix, action)
+}
+
+// PlanPolicy - dry run of ApplyPolicy: changes required to synchronize the database with the policy
+func (layer *RBACLayer) PlanPolicy(policy *types.RbacPolicy, action SyncRbacModelAction) (*types.RbacSyncPlan, error) {
+	if err := ValidatePolicy(policy); err != nil {
+		return nil, err
+	}
+
+	state, err := loadSyncState(layer.DB)
+	if err != nil {
+		return nil, err
+	}
+	return state.PlanPolicy(policy, action)
+}
+
+// ApplyPlan - apply the plan in a single transaction, so a failure leaves no partial changes
+func (layer *RBACLayer) ApplyPlan(plan *types.RbacSyncPlan) error {
+	err := layer.DB.Transaction(func(tx *gorm.DB) error {
+		if err := lockRoles(tx); err != nil {
+			return err
+		}
+		return applyPlan(tx, plan)
+	})
+	if err != nil {
+		return err
+	}
+	layer.invalidateAll()
+
+	return nil
+}
+
+func applyPlan(tx *gorm.DB, plan *types.RbacSyncPlan) error {
+	for _, roleName := range plan.AddRoles {
+		if err := tx.Create(&model.UserRole{Name: roleName}).Error; err != nil {
+			return internal.PrintError(internal.ErrAddingRoles, err)
+		}
+	}
+	for _, permission := range plan.AddPermissions {
+		modelName, action, _ := strings.Cut(permission, ":")
+		if err := tx.Create(&model.UserPermission{Model: modelName, Action: action}).Error; err != nil {
+			return internal.PrintError(internal.ErrAddingPermissions, err)
+		}
+	}
+
+	// Resolve ids of roles and permissions (including just created ones)
+	state, err := loadSyncState(tx)
+	if err != nil {
+		return err
+	}
+	roleIDs := internal.MappingToMap(state.Roles, func(x model.UserRole) (string, uuid.UUID) { return x.Name, x.ID })
+	permissionIDs := internal.MappingToMap(state.Permissions, func(x model.UserPermission) (string, uuid.UUID) { return PermissionString(x), x.ID })
+	resolveLink := func(link types.RbacPlanLink) (model.UserRolePermission, error) {
+		roleID, roleOk := roleIDs[link.Role]
+		permissionID, permissionOk := permissionIDs[link.Permission]
+		if !roleOk || !permissionOk {
+			return model.UserRolePermission{}, internal.PrintError(internal.ErrRoleOrPermissionNotFound, fmt.Errorf("%v -> %v", link.Role, link.Permission))
+		}
+		return model.UserRolePermission{RoleID: roleID, PermissionID: permissionID, Deny: link.Deny}, nil
+	}
+
+	for _, link := range slices.Concat(plan.AddLinks, plan.UpdateLinks) {
+		userRolePermission, err := resolveLink(link)
+		if err != nil {
+			return err
+		}
+		err = tx.Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "role_id"}, {Name: "permission_id"}},
+			DoUpdates: clause.AssignmentColumns([]string{"deny"}),
+		}).Create(&userRolePermission).Error
+		if err != nil {
+			return internal.PrintError(internal.ErrAddingRolePermission, err)
+		}
+	}
+
+	for _, link := range plan.DeleteLinks {
+		userRolePermission, err := resolveLink(link)
+		if err != nil {
+			return err
+		}
+		err = tx.Where("role_id = ? AND permission_id = ?", userRolePermission.RoleID, userRolePermission.PermissionID).
+			Delete(&model.UserRolePermission{}).Error
+		if err != nil {
+			return internal.PrintError(internal.ErrDeletingRolePermission, err)
+		}
+	}
+
+	for _, change := range plan.UpdateRoles {
+		roleID, ok := roleIDs[change.Role]
+		if !ok {
+			return internal.PrintError(internal.ErrRoleNotFound, fmt.Errorf("%v", change.Role))
+		}
+
+		var value any = change.To
+		column := change.Field
+		if change.Field == PlanFieldParent {
+			column = "parent_id"
+			var parentID *uuid.UUID
+			if change.To != "" {
+				id, ok := roleIDs[change.To]
+				if !ok {
+					return internal.PrintError(internal.ErrRoleNotFound, fmt.Errorf("%v", change.To))
+				}
+				parentID = &id
+			}
+			value = parentID
+		}
+
+		if err := tx.Model(&model.UserRole{}).Where("id = ?", roleID).Update(column, value).Error; err != nil {
+			return internal.PrintError(internal.ErrUpdatingRoles, err)
+		}
+	}
+
+	for _, permission := range plan.DeletePermissions {
+		modelName, action, _ := strings.Cut(permission, ":")
+		err := tx.Where("model = ? AND action = ?", modelName, action).Delete(&model.UserPermission{}).Error
+		if err != nil {
+			return internal.PrintError(internal.ErrDeletingPermissions, err)
+		}
+	}
+
+	for _, roleName := range plan.DeleteRoles {
+		if err := tx.Where("name = ?", roleName).Delete(&model.UserRole{}).Error; err != nil {
+			return internal.PrintError(internal.ErrDeletingRoles, err)
+		}
+	}
+
+	// Changed parents must not produce a cycle with roles the plan doesn't touch
+	var roles []model.UserRole
+	if err := tx.Find(&roles).Error; err != nil {
+		return internal.PrintError(internal.ErrGettingRoles, err)
+	}
+	hierarchy := NewRoleHierarchy(roles)
+	for _, role := range roles {
+		if _, err := hierarchy.Lineage(role.Name); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,308 @@
+package rbac
+
+import (
+	"bytes"
+	"crypto/sha256"
+	_ "embed"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/G0tem/go-service-auth/internal"
+	"github.com/G0tem/go-service-auth/internal/database"
+	"github.com/G0tem/go-service-auth/internal/model"
+	"github.com/G0tem/go-service-auth/internal/types"
+	"github.com/google/uuid"
+	"github.com/rs/zerolog/log"
+	"github.com/santhosh-tekuri/jsonschema/v5"
+	"gopkg.in/yaml.v3"
+	"gorm.io/gorm"
+)
+
+const (
+	PolicyFormatYAML = "yaml"
+	PolicyFormatJSON = "json"
+
+	// DefaultPolicySource is the source name of the built-in policy
+	DefaultPolicySource = "default"
+)
+
+//go:embed policy.schema.json
+var policySchemaSource string
+
+var policySchema = jsonschema.MustCompileString("policy.schema.json", policySchemaSource)
+
+var syncActionNames = map[SyncRbacModelAction]string{
+	AddMissedOnly:                         "add_missed_only",
+	DeleteLinksBetweenRolesAndPermissions: "delete_links",
+	DeleteOutdatedRolesAndPermissions:     "delete_outdated",
+}
+
+func (action SyncRbacModelAction) String() string {
+	return syncActionNames[action]
+}
+
+// ParseSyncAction - parse sync action name ("add_missed_only", "delete_links", "delete_outdated"),
+// empty name means DeleteLinksBetweenRolesAndPermissions (see InitSafety)
+func ParseSyncAction(name string) (SyncRbacModelAction, error) {
+	name = strings.ToLower(strings.TrimSpace(name))
+	if name == "" {
+		return DeleteLinksBetweenRolesAndPermissions, nil
+	}
+	for action, actionName := range syncActionNames {
+		if actionName == name {
+			return action, nil
+		}
+	}
+	return 0, internal.PrintError(internal.ErrUnknownSyncAction, fmt.Errorf("%v", name))
+}
+
+// DefaultPolicy - policy applied when no policy file is configured
+func DefaultPolicy() *types.RbacPolicy {
+	return &types.RbacPolicy{
+		Version:     1,
+		Description: "Built-in roles",
+		Roles: []types.RbacPolicyRole{
+			{Name: model.DefaultUserRole, Permissions: []string{"user:read"}},
+			{Name: model.AdminRole, Parent: model.DefaultUserRole, Permissions: []string{SuperuserPermission}},
+		},
+	}
+}
+
+// LoadPolicyFile - read and validate policy file, format is chosen by extension (.json, .yaml or .yml)
+func LoadPolicyFile(path string) (*types.RbacPolicy, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, internal.PrintError(internal.ErrLoadingPolicy, err)
+	}
+
+	format := PolicyFormatYAML
+	if strings.EqualFold(filepath.Ext(path), ".json") {
+		format = PolicyFormatJSON
+	}
+
+	return ParsePolicy(data, format)
+}
+
+// ParsePolicy - decode policy (JSON is decoded as YAML too, format only affects error messages),
+// validate it against the schema and check role references
+func ParsePolicy(data []byte, format string) (*types.RbacPolicy, error) {
+	var document any
+	if err := yaml.Unmarshal(data, &document); err != nil {
+		return nil, internal.PrintError(internal.ErrInvalidPolicy, fmt.Errorf("%v: %w", format, err))
+	}
+
+	// Schema is validated against JSON representation of the document
+	jsonData, err := json.Marshal(document)
+	if err != nil {
+		return nil, internal.PrintError(internal.ErrInvalidPolicy, err)
+	}
+	var jsonDocument any
+	jsonDecoder := json.NewDecoder(bytes.NewReader(jsonData))
+	jsonDecoder.UseNumber()
+	if err = jsonDecoder.Decode(&jsonDocument); err != nil {
+		return nil, internal.PrintError(internal.ErrInvalidPolicy, err)
+	}
+	if err = policySchema.Validate(jsonDocument); err != nil {
+		return nil, internal.PrintError(internal.ErrInvalidPolicy, err)
+	}
+
+	policy := new(types.RbacPolicy)
+	decoder := json.NewDecoder(bytes.NewReader(jsonData))
+	decoder.DisallowUnknownFields()
+	if err = decoder.Decode(policy); err != nil {
+		return nil, internal.PrintError(internal.ErrInvalidPolicy, err)
+	}
+
+	if err = ValidatePolicy(policy); err != nil {
+		return nil, err
+	}
+
+	return policy, nil
+}
+
+// ValidatePolicy - check rules the schema can't express: unique role names,
+// existing parent roles and absence of inheritance cycles
+func ValidatePolicy(policy *types.RbacPolicy) error {
+	roles := make([]model.UserRole, 0, len(policy.Roles))
+	for _, role := range policy.Roles {
+		if slices.ContainsFunc(roles, func(x model.UserRole) bool { return x.Name == role.Name }) {
+			return internal.PrintError(internal.ErrInvalidPolicy, fmt.Errorf("duplicate role %v", role.Name))
+		}
+		roles = append(roles, model.UserRole{ID: policyRoleID(role.Name), Name: role.Name})
+	}
+
+	for i, role := range policy.Roles {
+		if role.Parent == "" {
+			continue
+		}
+		if !slices.ContainsFunc(roles, func(x model.UserRole) bool { return x.Name == role.Parent }) {
+			return internal.PrintError(internal.ErrInvalidPolicy, fmt.Errorf("unknown parent %v of role %v", role.Parent, role.Name))
+		}
+		parentID := policyRoleID(role.Parent)
+		roles[i].ParentID = &parentID
+	}
+
+	hierarchy := NewRoleHierarchy(roles)
+	for _, role := range policy.Roles {
+		if _, err := hierarchy.Lineage(role.Name); err != nil {
+			return internal.PrintError(internal.ErrInvalidPolicy, err)
+		}
+	}
+
+	return nil
+}
+
+// PolicyMatrix - policy roles and permissions in the format of Init matrix
+func PolicyMatrix(policy *types.RbacPolicy) map[string]string {
+	return internal.MappingToMap(policy.Roles, func(x types.RbacPolicyRole) (string, string) {
+		return x.Name, strings.Join(x.Permissions, ",")
+	})
+}
+
+// PolicyHash - sha256 of the policy with roles and permissions sorted, so reordering doesn't change it
+func PolicyHash(policy *types.RbacPolicy) string {
+	normalized := *policy
+	normalized.Roles = slices.Clone(policy.Roles)
+	slices.SortFunc(normalized.Roles, func(a, b types.RbacPolicyRole) int { return strings.Compare(a.Name, b.Name) })
+	for i := range normalized.Roles {
+		normalized.Roles[i].Permissions = slices.Clone(normalized.Roles[i].Permissions)
+		slices.Sort(normalized.Roles[i].Permissions)
+	}
+
+	data, _ := json.Marshal(normalized)
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// policyRoleID - stable fake id of the policy role used to build hierarchy before roles exist in the database
+func policyRoleID(roleName string) uuid.UUID {
+	return uuid.NewSHA1(uuid.Nil, []byte(roleName))
+}
+
+// ApplyPolicy - synchronize roles, permissions, descriptions and inheritance with the policy
+// in a single transaction (see PlanPolicy). Replicas apply policies one by one holding the cluster-wide
+// sync lock and the policy already applied with the same sync action is skipped unless force is set.
+func (layer *RBACLayer) ApplyPolicy(policy *types.RbacPolicy, source string, action SyncRbacModelAction, force bool) error {
+	if err := ValidatePolicy(policy); err != nil {
+		return err
+	}
+
+	layer.policyMu.Lock()
+	defer layer.policyMu.Unlock()
+
+	hash := PolicyHash(policy)
+	var revision model.RbacPolicyRevision
+	skipped := false
+
+	err := database.WithAdvisoryLock(layer.DB, database.RbacSyncLock, layer.LockTimeout, func() error {
+		res := layer.DB.Order("id DESC").Limit(1).Find(&revision)
+		if res.Error != nil {
+			return res.Error
+		}
+		if !force && res.RowsAffected > 0 && revision.Hash == hash && revision.SyncMode == action.String() {
+			skipped = true
+			return nil
+		}
+
+		return layer.DB.Transaction(func(tx *gorm.DB) error {
+			if err := lockRoles(tx); err != nil {
+				return err
+			}
+			state, err := loadSyncState(tx)
+			if err != nil {
+				return err
+			}
+			plan, err := state.PlanPolicy(policy, action)
+			if err != nil {
+				return err
+			}
+			if err = applyPlan(tx, plan); err != nil {
+				return err
+			}
+
+			revision = model.RbacPolicyRevision{
+				Version:   policy.Version,
+				Hash:      hash,
+				Source:    source,
+				SyncMode:  action.String(),
+				Changes:   PlanChanges(plan),
+				AppliedAt: time.Now(),
+			}
+			if err = tx.Create(&revision).Error; err != nil {
+				return err
+			}
+
+			log.Debug().Msgf("Apply rbac policy stat: %v change(s)", revision.Changes)
+			return nil
+		})
+	})
+	if err != nil {
+		return internal.PrintError(internal.ErrApplyingPolicy, err)
+	}
+	if skipped {
+		log.Debug().Msgf("Rbac policy %v (version %v) is already applied", source, policy.Version)
+	} else {
+		layer.invalidateAll()
+	}
+
+	layer.policy = &types.RbacPolicyInfo{
+		Source:    source,
+		Hash:      hash,
+		SyncMode:  action.String(),
+		AppliedAt: revision.AppliedAt,
+		Skipped:   skipped,
+		Policy:    *policy,
+	}
+
+	return nil
+}
+
+// readPolicy - PolicyFile or the built-in policy if it isn't set
+func (layer *RBACLayer) readPolicy() (*types.RbacPolicy, string, error) {
+	if layer.PolicyFile == "" {
+		return DefaultPolicy(), DefaultPolicySource, nil
+	}
+	policy, err := LoadPolicyFile(layer.PolicyFile)
+	if err != nil {
+		return nil, "", err
+	}
+	return policy, layer.PolicyFile, nil
+}
+
+// LoadPolicy - apply PolicyFile (or the built-in policy if it isn't set) with SyncAction
+// (unchanged policy is re-applied only if force is set)
+func (layer *RBACLayer) LoadPolicy(force bool) (*types.RbacPolicyInfo, error) {
+	policy, source, err := layer.readPolicy()
+	if err != nil {
+		return nil, err
+	}
+
+	if err := layer.ApplyPolicy(policy, source, layer.SyncAction, force); err != nil {
+		return nil, err
+	}
+
+	return layer.CurrentPolicy(), nil
+}
+
+// PlanLoadPolicy - dry run of LoadPolicy with the given sync action
+func (layer *RBACLayer) PlanLoadPolicy(action SyncRbacModelAction) (*types.RbacSyncPlan, error) {
+	policy, _, err := layer.readPolicy()
+	if err != nil {
+		return nil, err
+	}
+	return layer.PlanPolicy(policy, action)
+}
+
+// CurrentPolicy - last successfully applied policy (nil if no policy was applied)
+func (layer *RBACLayer) CurrentPolicy() *types.RbacPolicyInfo {
+	layer.policyMu.Lock()
+	defer layer.policyMu.Unlock()
+
+	return layer.policy
+}
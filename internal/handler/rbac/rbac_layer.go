@@ -6,8 +6,12 @@ import (
 	"fmt"
 	"slices"
 	"strings"
+	"sync"
+	"time"
 
 	"github.com/G0tem/go-service-auth/internal"
+	"github.com/G0tem/go-service-auth/internal/database"
+	"github.com/G0tem/go-service-auth/internal/events"
 	"github.com/G0tem/go-service-auth/internal/model"
 	"github.com/G0tem/go-service-auth/internal/types"
 	"github.com/gofiber/fiber/v2"
@@ -29,6 +33,19 @@ const (
 type RBACLayer struct {
 	DB  *gorm.DB
 	Ctx context.Context
+	// PolicyFile is the declarative policy (YAML or JSON) applied by LoadPolicy, built-in policy is used if empty
+	PolicyFile string
+	// SyncAction is applied by LoadPolicy
+	SyncAction SyncRbacModelAction
+	// LockTimeout limits waiting for other replicas synchronizing roles and permissions
+	LockTimeout time.Duration
+	// Cache of effective permissions, nil disables caching
+	Cache *PermissionCache
+	// Events of user roles and permissions changes, nil disables publishing
+	Events *events.Stream
+
+	policyMu sync.Mutex
+	policy   *types.RbacPolicyInfo
 }
 
 // Direct migrations
@@ -45,56 +62,91 @@ func (g *RBACLayer) MigrateTables() (err error) {
 		return
 	}
 
+	if err = g.DB.AutoMigrate(&model.UserRoleAssignment{}); err != nil {
+		return
+	}
+
+	if err = g.DB.AutoMigrate(&model.UserDirectPermission{}); err != nil {
+		return
+	}
+
+	if err = g.DB.AutoMigrate(&model.UserScopedRole{}); err != nil {
+		return
+	}
+
+	if err = g.DB.AutoMigrate(&model.UserScopedPermission{}); err != nil {
+		return
+	}
+
+	if err = g.DB.AutoMigrate(&model.RbacPolicyRevision{}); err != nil {
+		return
+	}
+
 	return
 }
 
+func (layer *RBACLayer) context() context.Context {
+	if layer.Ctx == nil {
+		return context.Background()
+	}
+	return layer.Ctx
+}
+
+// invalidateAll - drop cached permissions after changes of roles, permissions or links between them
+// and notify event consumers
+func (layer *RBACLayer) invalidateAll() {
+	layer.Cache.InvalidateAll(layer.context())
+	layer.Events.Publish(layer.context(), events.PermissionsChanged, uuid.Nil, nil)
+}
+
+// invalidateUser - drop cached user permissions after changes of user roles or permissions
+func (layer *RBACLayer) invalidateUser(userId uuid.UUID) {
+	layer.Cache.InvalidateUser(layer.context(), userId)
+}
+
+// userChanged - drop cached user permissions and publish the change event
+func (layer *RBACLayer) userChanged(userId uuid.UUID, eventType string, data map[string]string) {
+	layer.invalidateUser(userId)
+	layer.Events.Publish(layer.context(), eventType, userId, data)
+}
+
+// CheckAccess - middleware that permits request only if current user (fiber local "user_id")
+// has at least one of rbacList roles or permissions (matched with wildcards, see PermissionSet)
 func (layer *RBACLayer) CheckAccess(rbacList []string) fiber.Handler {
 	// Return middleware handler
 	return func(fiberCtx *fiber.Ctx) error {
-		var (
-			userId          uuid.UUID
-			userPermits     []string
-			permitted       bool
-			permits         string
-			rolePermissions []string
-		)
-
-		err := layer.DB.Table("users").
-			Select("role").
-			Where("id"+" = ?", userId).
-			Find(&permits).Error
+		userId, err := uuid.Parse(fmt.Sprint(fiberCtx.Locals("user_id")))
 		if err != nil {
-			log.Error().Err(err)
+			return accessNotPermitted(fiberCtx)
 		}
 
-		err = json.Unmarshal([]byte(permits), &userPermits)
+		permits, err := layer.GetUserPermits(userId)
 		if err != nil {
-			log.Error().Err(err)
+			log.Error().Err(err).Msg("check access")
 		}
 
-		_ = layer.DB.Model(&model.UserRolePermission{}).
-			Select("concat(permission.model, ':', permission.action)").
-			Joins("FULL JOIN user_roles as role ON role.id = user_role_permissions.role_id").
-			Joins("FULL JOIN user_permissions as permission ON permission.id = user_role_permissions.permission_id").
-			Where("role.name IN ?", userPermits).
-			Find(&rolePermissions)
-
-		userPermits = append(userPermits, rolePermissions...)
-
-		for _, u := range userPermits {
-			if internal.StringInSlice(u, rbacList) {
-				permitted = true
+		for _, role := range permits.Roles {
+			if internal.StringInSlice(role, rbacList) {
+				return fiberCtx.Next()
 			}
 		}
 
-		if permitted {
+		requiredPermissions := slices.DeleteFunc(slices.Clone(rbacList), func(x string) bool { return !strings.Contains(x, ":") })
+		if NewPermissionSet(permits.PermitList...).AllowsAny(requiredPermissions...) {
 			return fiberCtx.Next()
-		} else {
-			return fiberCtx.SendString(internal.ErrAccessNotPermitted)
 		}
+
+		return accessNotPermitted(fiberCtx)
 	}
 }
 
+func accessNotPermitted(fiberCtx *fiber.Ctx) error {
+	return fiberCtx.Status(fiber.StatusForbidden).JSON(types.FailureResponse{
+		Status:  "error",
+		Message: internal.ErrAccessNotPermitted,
+	})
+}
+
 func (layer *RBACLayer) ValidatePermits(userPermits datatypes.JSON) error {
 	roleList := []model.UserRole{}
 	layer.DB.Find(&roleList)
@@ -148,6 +200,7 @@ func (layer *RBACLayer) AddPermission(userPermission *model.UserPermission) (*mo
 	if err != nil {
 		return &model.UserPermission{}, internal.PrintError(internal.ErrAddingPermissions, err)
 	}
+	layer.invalidateAll()
 
 	return userPermission, err
 }
@@ -158,6 +211,7 @@ func (layer *RBACLayer) DeletePermission(permissionModel, permissionAction strin
 	if err != nil {
 		return internal.PrintError(internal.ErrDeletingPermissions, err)
 	}
+	layer.invalidateAll()
 
 	return
 }
@@ -178,6 +232,7 @@ func (layer *RBACLayer) AddRole(role model.UserRole) (model.UserRole, error) {
 	if err != nil {
 		return model.UserRole{}, internal.PrintError(internal.ErrAddingRoles, err)
 	}
+	layer.invalidateAll()
 
 	return role, err
 }
@@ -188,6 +243,7 @@ func (layer *RBACLayer) DeleteRole(roleName string) (err error) {
 	if err != nil {
 		return internal.PrintError(internal.ErrDeletingRoles, err)
 	}
+	layer.invalidateAll()
 
 	return
 }
@@ -202,11 +258,22 @@ func (layer *RBACLayer) GetAllRolePermissions() (rolePermissions []model.UserRol
 	return
 }
 
-// GetRolePermissions - get stored role permissions in the database
+// GetRolePermissions - get effective allowed permissions of roles (including ones inherited from parent roles) in the database
 func (layer *RBACLayer) GetRolePermissions(roles ...string) (permissions []model.UserPermission, err error) {
-	err = layer.DB.Model(&model.UserRolePermission{}).
-		Joins("JOIN user_roles ON user_roles.id = user_role_permissions.role_id AND user_roles.name IN ? JOIN user_permissions ON user_permissions.id = user_role_permissions.permission_id", roles).
-		Select("user_permissions.*").Find(&permissions).Error
+	hierarchy, err := layer.GetRoleHierarchy()
+	if err != nil {
+		return nil, internal.PrintError(internal.ErrGettingRolePermissions, err)
+	}
+
+	expandedRoles, err := hierarchy.Expand(roles...)
+	if err != nil {
+		return nil, internal.PrintError(internal.ErrGettingRolePermissions, err)
+	}
+
+	err = layer.DB.Where("id IN (?)", layer.DB.Model(&model.UserRolePermission{}).
+		Select("permission_id").
+		Where("role_id IN ? AND deny = ?", internal.Mapping(expandedRoles, func(x model.UserRole) uuid.UUID { return x.ID }), false),
+	).Order("model, action").Find(&permissions).Error
 	if err != nil {
 		return nil, internal.PrintError(internal.ErrGettingRolePermissions, err)
 	}
@@ -235,11 +302,16 @@ func (layer *RBACLayer) AddRolePermission(addRolePermission types.AddRolePermiss
 
 	userRolePermission.RoleID = role.ID
 	userRolePermission.PermissionID = permission.ID
+	userRolePermission.Deny = addRolePermission.Deny
 
-	err = layer.DB.Clauses(clause.OnConflict{DoNothing: true}).Create(&userRolePermission).Error
+	err = layer.DB.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "role_id"}, {Name: "permission_id"}},
+		DoUpdates: clause.AssignmentColumns([]string{"deny"}),
+	}).Create(&userRolePermission).Error
 	if err != nil {
 		return model.UserRolePermission{}, internal.PrintError(internal.ErrAddingRolePermission, err)
 	}
+	layer.invalidateAll()
 
 	return
 }
@@ -263,305 +335,383 @@ func (layer *RBACLayer) DeleteRolePermission(roleName, permissionModel, permissi
 	if err != nil {
 		return internal.PrintError(internal.ErrDeletingRolePermission, err)
 	}
+	layer.invalidateAll()
 
 	return
 }
 
-// GetUserPermits - get user roles and permissions in the database
-func (layer *RBACLayer) GetUserPermits(userId uuid.UUID) (permits types.Permits, err error) {
-	userPermits := &types.UserPermit{}
-
-	err = layer.DB.Table("users").
-		Select("id"+" as user_id", "roles"+" as permits").
-		Where("id"+" = ?", userId).
-		Find(&userPermits).Error
+// GetUserRoles - get roles assigned to user in the database
+func (layer *RBACLayer) GetUserRoles(userId uuid.UUID) (roles []model.UserRole, err error) {
+	err = layer.DB.Model(&model.UserRole{}).
+		Joins("JOIN user_role_assignments ON user_role_assignments.role_id = user_roles.id AND user_role_assignments.user_id = ?", userId).
+		Order("user_role_assignments.created_at").
+		Find(&roles).Error
 	if err != nil {
-		return permits, internal.PrintError(internal.ErrGettingUser, err)
-	}
-
-	if userPermits.UserID == "" {
-		return permits, internal.PrintError(internal.ErrUserNotFound, err)
+		return nil, internal.PrintError(internal.ErrGettingRoles, err)
 	}
 
-	permits.UserID = userPermits.UserID
-	permits.Permits = userPermits.Permits
+	return
+}
 
-	if len(permits.Permits) > 0 {
-		err = json.Unmarshal(permits.Permits, &permits.PermitList)
-		if err != nil {
-			return permits, internal.PrintError(internal.ErrUnmarshalingPermits, err)
-		}
+// GetUserDirectPermissions - get permissions granted (or denied) to user directly in the database
+func (layer *RBACLayer) GetUserDirectPermissions(userId uuid.UUID) (permissions []model.UserDirectPermission, err error) {
+	err = layer.DB.Preload("Permission").
+		Where("user_id = ?", userId).
+		Find(&permissions).Error
+	if err != nil {
+		return nil, internal.PrintError(internal.ErrGettingPermissions, err)
 	}
 
 	return
 }
 
-// GrantUserRole - grant user role in the database
-func (layer *RBACLayer) GrantUserRole(userId uuid.UUID, roleName string) (permits types.Permits, err error) {
-	res := layer.DB.Where("name = ?", roleName).First(&model.UserRole{})
-	if res.RowsAffected == 0 || res.Error != nil {
-		err = res.Error
-		return permits, internal.PrintError(internal.ErrRoleNotFound, err)
-	}
+// GetUserPermissions - get effective allowed user permissions (union of permissions of all assigned roles
+// including inherited ones and direct permissions)
+func (layer *RBACLayer) GetUserPermissions(userId uuid.UUID) (permissions []model.UserPermission, err error) {
+	return layer.getUserPermissions(userId, false)
+}
 
-	return layer.grantUserPermit(userId, roleName)
+// GetUserDeniedPermissions - get permissions explicitly denied to user by assigned roles (including inherited ones)
+// or directly
+func (layer *RBACLayer) GetUserDeniedPermissions(userId uuid.UUID) (permissions []model.UserPermission, err error) {
+	return layer.getUserPermissions(userId, true)
 }
 
-// GrantUserPermission - grant user permission in the database
-func (layer *RBACLayer) GrantUserPermission(userId uuid.UUID, permissionModel, permissionAction string) (permits types.Permits, err error) {
-	res := layer.DB.Where("model = ? and action = ?", permissionModel, permissionAction).First(&model.UserPermission{})
-	if res.RowsAffected == 0 || res.Error != nil {
-		err = res.Error
-		return permits, internal.PrintError(internal.ErrPermissionNotFound, err)
+// GetUserPermissionList - get effective user permissions as "model:action" list
+// where denied permissions are prefixed with "!" (the format of jwt "permissions" claim)
+func (layer *RBACLayer) GetUserPermissionList(userId uuid.UUID) ([]string, error) {
+	return layer.Cache.Get(layer.context(), userCacheKey(userId), func() ([]string, error) {
+		return layer.loadUserPermissionList(userId)
+	})
+}
+
+func (layer *RBACLayer) loadUserPermissionList(userId uuid.UUID) ([]string, error) {
+	allowed, err := layer.GetUserPermissions(userId)
+	if err != nil {
+		return nil, err
+	}
+	denied, err := layer.GetUserDeniedPermissions(userId)
+	if err != nil {
+		return nil, err
 	}
 
-	return layer.grantUserPermit(userId, permissionModel+":"+permissionAction)
+	return append(
+		internal.Mapping(allowed, PermissionString),
+		internal.Mapping(denied, func(x model.UserPermission) string { return DenyPrefix + PermissionString(x) })...,
+	), nil
 }
 
-// grantUserPermit - grant user role and permission in the database
-func (layer *RBACLayer) grantUserPermit(userId uuid.UUID, permit string) (permits types.Permits, err error) {
-	permits, err = layer.GetUserPermits(userId)
+func (layer *RBACLayer) getUserPermissions(userId uuid.UUID, deny bool) (permissions []model.UserPermission, err error) {
+	roles, err := layer.GetUserRoles(userId)
 	if err != nil {
-		return permits, internal.PrintError(internal.ErrGettingUserPermits, err)
+		return nil, internal.PrintError(internal.ErrGettingUserPermits, err)
 	}
 
-	if internal.ExistPermit(permits.PermitList, permit) {
-		return permits, internal.PrintError(internal.ErrUserAlreadyHaveThisPermit, err)
+	hierarchy, err := layer.GetRoleHierarchy()
+	if err != nil {
+		return nil, internal.PrintError(internal.ErrGettingUserPermits, err)
 	}
 
-	permits.PermitList = append(permits.PermitList, permit)
-
-	permits.Permits, err = json.Marshal(permits.PermitList)
+	expandedRoles, err := hierarchy.Expand(internal.Mapping(roles, func(x model.UserRole) string { return x.Name })...)
 	if err != nil {
-		return permits, internal.PrintError(internal.ErrMarshallingPermits, err)
+		return nil, internal.PrintError(internal.ErrGettingUserPermits, err)
 	}
 
-	err = layer.DB.Table("users").
-		Where("id"+" = ?", userId).
-		Updates(map[string]interface{}{"role": permits.Permits}).Error
+	rolePermissions := layer.DB.Model(&model.UserRolePermission{}).
+		Select("permission_id").
+		Where("role_id IN ? AND deny = ?", internal.Mapping(expandedRoles, func(x model.UserRole) uuid.UUID { return x.ID }), deny)
+	directPermissions := layer.DB.Model(&model.UserDirectPermission{}).
+		Select("permission_id").
+		Where("user_id = ? AND deny = ?", userId, deny)
+
+	err = layer.DB.Where("id IN (?) OR id IN (?)", rolePermissions, directPermissions).
+		Order("model, action").
+		Find(&permissions).Error
 	if err != nil {
-		return permits, internal.PrintError(internal.ErrGrantingPermit, err)
+		return nil, internal.PrintError(internal.ErrGettingUserPermits, err)
 	}
 
 	return
 }
 
-// RevokeUserRole - revoke user role in the database
-func (layer *RBACLayer) RevokeUserRole(userId uuid.UUID, roleName string) (permits types.Permits, err error) {
-	return layer.RevokeUserPermit(userId, roleName)
-}
+// GetUserPermits - get user roles and permissions in the database
+func (layer *RBACLayer) GetUserPermits(userId uuid.UUID) (permits types.Permits, err error) {
+	var user model.User
 
-// RevokeUserPermit - revoke user role and permission in the database
-func (layer *RBACLayer) RevokeUserPermit(userId uuid.UUID, permit string) (permits types.Permits, err error) {
-	var oldPermits []string
+	res := layer.DB.Select("id").Where("id = ?", userId).Limit(1).Find(&user)
+	if res.Error != nil {
+		return permits, internal.PrintError(internal.ErrGettingUser, res.Error)
+	}
+	if res.RowsAffected == 0 {
+		return permits, internal.PrintError(internal.ErrUserNotFound, nil)
+	}
 
-	permits, err = layer.GetUserPermits(userId)
+	roles, err := layer.GetUserRoles(userId)
+	if err != nil {
+		return permits, err
+	}
+	directPermissions, err := layer.GetUserDirectPermissions(userId)
+	if err != nil {
+		return permits, err
+	}
+	permissions, err := layer.GetUserPermissionList(userId)
 	if err != nil {
-		return permits, internal.PrintError(internal.ErrGettingUserPermits, err)
+		return permits, err
 	}
 
-	oldPermits = permits.PermitList
-	permits.PermitList = []string{}
+	permits.UserID = userId.String()
+	permits.Roles = internal.Mapping(roles, func(x model.UserRole) string { return x.Name })
+	permits.Permissions = internal.Mapping(directPermissions, DirectPermissionString)
+	permits.PermitList = permissions
 
-	for _, p := range oldPermits {
-		if p != permit {
-			permits.PermitList = append(permits.PermitList, p)
+	return
+}
+
+// GrantUserRole - grant user role in the database (granting an already assigned role does nothing)
+func (layer *RBACLayer) GrantUserRole(userId uuid.UUID, roleName string) (permits types.Permits, err error) {
+	err = layer.DB.Transaction(func(tx *gorm.DB) error {
+		user, err := lockUser(tx, userId)
+		if err != nil {
+			return err
 		}
-	}
 
-	permits.Permits, err = json.Marshal(permits.PermitList)
-	if err != nil {
-		return permits, internal.PrintError(internal.ErrMarshallingPermits, err)
-	}
+		var role model.UserRole
+		res := tx.Where("name = ?", roleName).Limit(1).Find(&role)
+		if res.Error != nil || res.RowsAffected == 0 {
+			return internal.PrintError(internal.ErrRoleNotFound, res.Error)
+		}
+
+		err = tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&model.UserRoleAssignment{
+			UserID: userId,
+			RoleID: role.ID,
+		}).Error
+		if err != nil {
+			return internal.PrintError(internal.ErrGrantingPermit, err)
+		}
 
-	err = layer.DB.Table("users").
-		Where("id"+" = ?", userId).
-		Updates(map[string]interface{}{"role": permits.Permits}).Error
+		// The first assigned role becomes the primary one
+		if user.RoleID == uuid.Nil {
+			err = tx.Model(&model.User{}).Where("id = ?", userId).Update("role_id", role.ID).Error
+			if err != nil {
+				return internal.PrintError(internal.ErrGrantingPermit, err)
+			}
+		}
+
+		return nil
+	})
 	if err != nil {
-		return permits, internal.PrintError(internal.ErrRevokingPermit, err)
+		return
 	}
+	layer.userChanged(userId, events.UserRoleGranted, map[string]string{"role": roleName})
 
-	return
+	return layer.GetUserPermits(userId)
 }
 
-func (rbac *RBACLayer) InitSafety(matrix map[string]string) error {
-	return rbac.Init(matrix, DeleteLinksBetweenRolesAndPermissions)
+// GrantUserPermission - grant user permission in the database (granting an already granted permission does nothing,
+// granting a denied permission replaces deny)
+func (layer *RBACLayer) GrantUserPermission(userId uuid.UUID, permissionModel, permissionAction string) (permits types.Permits, err error) {
+	return layer.setUserPermission(userId, permissionModel, permissionAction, false)
 }
 
-func (rbac *RBACLayer) Init(matrix map[string]string, action SyncRbacModelAction) error {
-	// Get roles, permissions and links between them from storage (database)
-	permissions, err := rbac.GetPermissions()
-	if err != nil {
-		return err
-	}
-	rolesList, err := rbac.GetRoles()
-	if err != nil {
-		return err
-	}
-	allUserRolePermissions, err := rbac.GetAllRolePermissions()
-	if err != nil {
-		return err
-	}
-	allUserRolePermissionsIndex := internal.MappingToMultiMap(allUserRolePermissions,
-		func(userRolePermission model.UserRolePermission) (string, *model.UserPermission) {
-			return userRolePermission.Role.Name, userRolePermission.Permission
-		})
-
-	// Collect changes
-	var addUserRoleEvents []model.UserRole
-	var addPermissionEvents []model.UserPermission
-	var addUserRolePermissionEvents []model.UserRolePermission
-	var removeUserRoleEvents []uuid.UUID
-	var removePermissionEvents []uuid.UUID
-	var removeUserRolePermissionEvents [][]uuid.UUID
-
-	rolesBucket := internal.Mapping(rolesList, func(ur model.UserRole) string { return ur.Name })
-	permissionsBucket := internal.Mapping(permissions,
-		func(userPermission model.UserPermission) string {
-			return fmt.Sprintf("%s:%s", userPermission.Model, userPermission.Action)
-		})
-	for role, rolePermissions := range matrix {
-		var userRole model.UserRole
-		if !slices.Contains(rolesBucket, role) {
-			addUserRoleEvents = append(addUserRoleEvents, model.UserRole{
-				Name: role,
-			})
-			// Hack for prevent double cycling for collect addUserRolePermissionEvents
-			userRole, err = rbac.AddRole(model.UserRole{
-				Name: role,
-			})
-			if err != nil {
-				return err
-			}
-			rolesList = append(rolesList, userRole)
+// DenyUserPermission - explicitly deny user permission in the database, deny wins over permissions of user roles
+func (layer *RBACLayer) DenyUserPermission(userId uuid.UUID, permissionModel, permissionAction string) (permits types.Permits, err error) {
+	return layer.setUserPermission(userId, permissionModel, permissionAction, true)
+}
 
-		} else {
-			userRole = internal.Any(rolesList, func(ur model.UserRole) bool { return ur.Name == role })
-			rolesBucket = internal.Remove(rolesBucket, role)
+func (layer *RBACLayer) setUserPermission(userId uuid.UUID, permissionModel, permissionAction string, deny bool) (permits types.Permits, err error) {
+	err = layer.DB.Transaction(func(tx *gorm.DB) error {
+		if _, err := lockUser(tx, userId); err != nil {
+			return err
 		}
 
-		// Snapshot for current role permissions
-		currentRolePermissions, ok := allUserRolePermissionsIndex[role]
-		if !ok {
-			currentRolePermissions = make([]*model.UserPermission, 0, 10)
+		var permission model.UserPermission
+		res := tx.Where("model = ? and action = ?", permissionModel, permissionAction).Limit(1).Find(&permission)
+		if res.Error != nil || res.RowsAffected == 0 {
+			return internal.PrintError(internal.ErrPermissionNotFound, res.Error)
 		}
-		rolePermissionsBucket := internal.Mapping(currentRolePermissions,
-			func(x *model.UserPermission) []uuid.UUID {
-				return []uuid.UUID{userRole.ID, x.ID}
-			})
-
-		for _, permission := range strings.Split(rolePermissions, ",") {
-			modelName, action := SplitAndTrim2(permission, ":", " ")
-			var userPermission model.UserPermission
-
-			if !slices.Contains(permissionsBucket, permission) {
-				userPermission = model.UserPermission{
-					Model:  modelName,
-					Action: action,
-				}
-				addPermissionEvents = append(addPermissionEvents, userPermission)
-				// Hack for prevent double cycling for collect addUserRolePermissionEvents
-				rolePermission, err := rbac.AddPermission(&userPermission)
-				if err != nil {
-					return err
-				}
-				userPermission.ID = rolePermission.ID
-				permissions = append(permissions, userPermission)
-
-			} else {
-				userPermission = internal.Any(permissions, func(x model.UserPermission) bool { return x.Model == modelName && x.Action == action })
-				permissionsBucket = internal.Remove(permissionsBucket, permission)
-			}
 
-			// Collect links between roles and permissions
-			if slices.IndexFunc(currentRolePermissions, func(x *model.UserPermission) bool { return x.ID == userPermission.ID }) < 0 {
-				addUserRolePermissionEvents = append(addUserRolePermissionEvents, model.UserRolePermission{
-					RoleID:       userRole.ID,
-					PermissionID: userPermission.ID,
-				})
-			} else {
-				rolePermissionsBucket = internal.RemoveFunc(rolePermissionsBucket, func(x []uuid.UUID) bool { return x[0] == userRole.ID && x[1] == userPermission.ID })
-			}
+		err := tx.Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "user_id"}, {Name: "permission_id"}},
+			DoUpdates: clause.AssignmentColumns([]string{"deny"}),
+		}).Create(&model.UserDirectPermission{
+			UserID:       userId,
+			PermissionID: permission.ID,
+			Deny:         deny,
+		}).Error
+		if err != nil {
+			return internal.PrintError(internal.ErrGrantingPermit, err)
 		}
 
-		// Hack for prevent double cycling for collect addUserRolePermissionEvents
-		removeUserRolePermissionEvents = append(removeUserRolePermissionEvents, rolePermissionsBucket...)
-	}
-	for _, roleName := range rolesBucket {
-		userRole := internal.Any(rolesList, func(x model.UserRole) bool { return x.Name == roleName })
-		removeUserRoleEvents = append(removeUserRoleEvents, userRole.ID)
+		return nil
+	})
+	if err != nil {
+		return
 	}
-	for _, permission := range permissionsBucket {
-		modelName, action := SplitAndTrim2(permission, ":", " ")
-		userPermission := internal.Any(permissions, func(x model.UserPermission) bool {
-			return x.Model == modelName && x.Action == action
-		})
-		removePermissionEvents = append(removePermissionEvents, userPermission.ID)
+	eventType := events.UserPermissionGranted
+	if deny {
+		eventType = events.UserPermissionDenied
 	}
+	layer.userChanged(userId, eventType, map[string]string{"permission": permissionModel + ":" + permissionAction})
 
-	// Process events
-	// For add events only links between roles and permissions process cause hacking above
-	for _, userRolePermission := range addUserRolePermissionEvents {
-		err = rbac.DB.Clauses(clause.OnConflict{DoNothing: true}).Create(&userRolePermission).Error
+	return layer.GetUserPermits(userId)
+}
+
+// RevokeUserRole - revoke user role in the database (revoking a not assigned role does nothing)
+func (layer *RBACLayer) RevokeUserRole(userId uuid.UUID, roleName string) (permits types.Permits, err error) {
+	err = layer.DB.Transaction(func(tx *gorm.DB) error {
+		user, err := lockUser(tx, userId)
 		if err != nil {
-			return internal.PrintError(internal.ErrAddingRolePermission, err)
+			return err
+		}
+
+		var role model.UserRole
+		res := tx.Where("name = ?", roleName).Limit(1).Find(&role)
+		if res.Error != nil || res.RowsAffected == 0 {
+			return internal.PrintError(internal.ErrRoleNotFound, res.Error)
 		}
-	}
 
-	// Optionally process remove events
-	switch action {
-	case DeleteLinksBetweenRolesAndPermissions:
+		err = tx.Where("user_id = ? and role_id = ?", userId, role.ID).Delete(&model.UserRoleAssignment{}).Error
+		if err != nil {
+			return internal.PrintError(internal.ErrRevokingPermit, err)
+		}
 
-		for _, removeEvent := range removeUserRolePermissionEvents {
-			var userRolePermission model.UserRolePermission
-			err = rbac.DB.Where(&model.UserRolePermission{
-				RoleID:       removeEvent[0],
-				PermissionID: removeEvent[1],
-			}).Delete(&userRolePermission).Error
+		// Pick the oldest remaining role as primary one (or none)
+		if user.RoleID == role.ID {
+			var next model.UserRoleAssignment
+			err = tx.Where("user_id = ?", userId).Order("created_at").Limit(1).Find(&next).Error
 			if err != nil {
-				return internal.PrintError(internal.ErrDeletingRolePermission, err)
+				return internal.PrintError(internal.ErrRevokingPermit, err)
 			}
 
+			var roleId any = nil
+			if next.RoleID != uuid.Nil {
+				roleId = next.RoleID
+			}
+			err = tx.Model(&model.User{}).Where("id = ?", userId).Update("role_id", roleId).Error
+			if err != nil {
+				return internal.PrintError(internal.ErrRevokingPermit, err)
+			}
 		}
 
-	case DeleteOutdatedRolesAndPermissions:
+		return nil
+	})
+	if err != nil {
+		return
+	}
+	layer.userChanged(userId, events.UserRoleRevoked, map[string]string{"role": roleName})
 
-		for _, removeEvent := range removeUserRolePermissionEvents {
-			var userRolePermission model.UserRolePermission
-			err = rbac.DB.Where(&model.UserRolePermission{
-				RoleID:       removeEvent[0],
-				PermissionID: removeEvent[1],
-			}).Delete(&userRolePermission).Error
-			if err != nil {
-				return internal.PrintError(internal.ErrDeletingRolePermission, err)
-			}
+	return layer.GetUserPermits(userId)
+}
 
+// RevokeUserPermission - revoke direct user permission (granted or denied) in the database
+// (revoking a not granted permission does nothing)
+func (layer *RBACLayer) RevokeUserPermission(userId uuid.UUID, permissionModel, permissionAction string) (permits types.Permits, err error) {
+	err = layer.DB.Transaction(func(tx *gorm.DB) error {
+		if _, err := lockUser(tx, userId); err != nil {
+			return err
 		}
 
-		for _, removeEvent := range removePermissionEvents {
-			var userPermission model.UserPermission
-			err = rbac.DB.Where(&model.UserPermission{
-				ID: removeEvent,
-			}).Delete(&userPermission).Error
-			if err != nil {
-				return internal.PrintError(internal.ErrDeletingPermissions, err)
-			}
+		var permission model.UserPermission
+		res := tx.Where("model = ? and action = ?", permissionModel, permissionAction).Limit(1).Find(&permission)
+		if res.Error != nil || res.RowsAffected == 0 {
+			return internal.PrintError(internal.ErrPermissionNotFound, res.Error)
+		}
 
+		err := tx.Where("user_id = ? and permission_id = ?", userId, permission.ID).Delete(&model.UserDirectPermission{}).Error
+		if err != nil {
+			return internal.PrintError(internal.ErrRevokingPermit, err)
 		}
 
-		for _, removeEvent := range removeUserRoleEvents {
-			var userRole model.UserRole
-			err = rbac.DB.Where(&model.UserRole{
-				ID: removeEvent,
-			}).Delete(&userRole).Error
-			if err != nil {
-				return internal.PrintError(internal.ErrDeletingRoles, err)
-			}
+		return nil
+	})
+	if err != nil {
+		return
+	}
+	layer.userChanged(userId, events.UserPermissionRevoked, map[string]string{"permission": permissionModel + ":" + permissionAction})
 
-		}
+	return layer.GetUserPermits(userId)
+}
+
+// RevokeUserPermit - revoke user role or permission ("model:action") in the database
+func (layer *RBACLayer) RevokeUserPermit(userId uuid.UUID, permit string) (permits types.Permits, err error) {
+	if strings.Contains(permit, ":") {
+		permissionModel, permissionAction := SplitAndTrim2(strings.TrimPrefix(permit, DenyPrefix), ":", " ")
+		return layer.RevokeUserPermission(userId, permissionModel, permissionAction)
+	}
+
+	return layer.RevokeUserRole(userId, permit)
+}
+
+// lockUser - get user row locked for update inside transaction
+func lockUser(tx *gorm.DB, userId uuid.UUID) (user model.User, err error) {
+	res := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Select("id", "role_id").
+		Where("id = ?", userId).
+		Limit(1).
+		Find(&user)
+	if res.Error != nil {
+		return user, internal.PrintError(internal.ErrGettingUser, res.Error)
+	}
+	if res.RowsAffected == 0 {
+		return user, internal.PrintError(internal.ErrUserNotFound, nil)
 	}
 
-	log.Debug().Msgf("Init rbac layer stat: added roles: %v, added permissions %v", len(addUserRoleEvents), len(addPermissionEvents))
+	return
+}
+
+// PermissionString - format permission as "model:action"
+func PermissionString(permission model.UserPermission) string {
+	return permission.Model + ":" + permission.Action
+}
+
+// DirectPermissionString - format direct permission as "model:action" or "!model:action" if denied
+func DirectPermissionString(permission model.UserDirectPermission) string {
+	if permission.Permission == nil {
+		return ""
+	}
+	if permission.Deny {
+		return DenyPrefix + PermissionString(*permission.Permission)
+	}
+	return PermissionString(*permission.Permission)
+}
+
+func (rbac *RBACLayer) InitSafety(matrix map[string]string) error {
+	return rbac.Init(matrix, DeleteLinksBetweenRolesAndPermissions)
+}
+
+// Init - synchronize roles, permissions and links between them with matrix (map of role name to
+// comma separated permissions, denied ones are prefixed with "!"). Plan is computed and applied
+// in a single transaction holding the cluster-wide sync lock, use PlanSync to preview changes.
+func (rbac *RBACLayer) Init(matrix map[string]string, action SyncRbacModelAction) error {
+	return database.WithAdvisoryLock(rbac.DB, database.RbacSyncLock, rbac.LockTimeout, func() error {
+		return rbac.init(matrix, action)
+	})
+}
+
+func (rbac *RBACLayer) init(matrix map[string]string, action SyncRbacModelAction) error {
+	err := rbac.DB.Transaction(func(tx *gorm.DB) error {
+		if err := lockRoles(tx); err != nil {
+			return err
+		}
+		state, err := loadSyncState(tx)
+		if err != nil {
+			return err
+		}
+		plan, err := state.Plan(matrix, action)
+		if err != nil {
+			return err
+		}
+		if err = applyPlan(tx, plan); err != nil {
+			return err
+		}
+
+		log.Debug().Msgf("Init rbac layer stat: added roles: %v, added permissions %v", len(plan.AddRoles), len(plan.AddPermissions))
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	rbac.invalidateAll()
 
 	return nil
 }
@@ -0,0 +1,376 @@
+package rbac
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/G0tem/go-service-auth/internal"
+	"github.com/G0tem/go-service-auth/internal/events"
+	"github.com/G0tem/go-service-auth/internal/model"
+	"github.com/G0tem/go-service-auth/internal/types"
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"github.com/rs/zerolog/log"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// MatchResource - resource id pattern covers resource id. Pattern is exact id,
+// "*" (any resource) or prefix ending with "*" (e.g. "team-a/*"). Empty resource id is not
+// a resource, no pattern covers it.
+func MatchResource(pattern, resourceId string) bool {
+	if resourceId == "" {
+		return false
+	}
+	if pattern == Wildcard || pattern == resourceId {
+		return true
+	}
+	if prefix, ok := strings.CutSuffix(pattern, Wildcard); ok {
+		return strings.HasPrefix(resourceId, prefix)
+	}
+	return false
+}
+
+func validateResource(resource types.Resource) error {
+	if strings.TrimSpace(resource.Type) == "" || strings.TrimSpace(resource.ID) == "" {
+		return internal.PrintError(internal.ErrInvalidResource, fmt.Errorf("%v:%v", resource.Type, resource.ID))
+	}
+	return nil
+}
+
+// Can - user is allowed to perform permission ("model:action") on the resource.
+// Global user permissions apply to every resource, scoped ones only to matching resources,
+// explicit deny (global or scoped) always wins.
+func (layer *RBACLayer) Can(userId uuid.UUID, permission string, resource types.Resource) (bool, error) {
+	permissions, err := layer.GetUserResourcePermissionList(userId, resource)
+	if err != nil {
+		return false, err
+	}
+
+	return NewPermissionSet(permissions...).Allows(permission), nil
+}
+
+// GetUserResourcePermissionList - effective user permissions for the resource (global and scoped ones)
+// in the jwt claim format (denied permissions are prefixed with "!"). Global permissions and permissions
+// of roles are cached, scoped grants of the user depend on the resource and are read from the database
+// on every call.
+func (layer *RBACLayer) GetUserResourcePermissionList(userId uuid.UUID, resource types.Resource) ([]string, error) {
+	permissions, err := layer.GetUserPermissionList(userId)
+	if err != nil {
+		return nil, err
+	}
+	if resource.Type == "" {
+		return permissions, nil
+	}
+
+	var scopedRoles []model.UserScopedRole
+	err = layer.DB.Preload("Role").
+		Where("user_id = ? AND resource_type = ?", userId, resource.Type).
+		Find(&scopedRoles).Error
+	if err != nil {
+		return nil, internal.PrintError(internal.ErrGettingUserPermits, err)
+	}
+
+	roleNames := []string{}
+	for _, scopedRole := range scopedRoles {
+		if scopedRole.Role != nil && MatchResource(scopedRole.ResourceID, resource.ID) {
+			roleNames = append(roleNames, scopedRole.Role.Name)
+		}
+	}
+	if len(roleNames) > 0 {
+		rolePermissions, err := layer.getRolesPermissionList(roleNames...)
+		if err != nil {
+			return nil, err
+		}
+		permissions = append(permissions, rolePermissions...)
+	}
+
+	var scopedPermissions []model.UserScopedPermission
+	err = layer.DB.Preload("Permission").
+		Where("user_id = ? AND resource_type = ?", userId, resource.Type).
+		Find(&scopedPermissions).Error
+	if err != nil {
+		return nil, internal.PrintError(internal.ErrGettingUserPermits, err)
+	}
+	for _, scopedPermission := range scopedPermissions {
+		if scopedPermission.Permission == nil || !MatchResource(scopedPermission.ResourceID, resource.ID) {
+			continue
+		}
+		permission := PermissionString(*scopedPermission.Permission)
+		if scopedPermission.Deny {
+			permission = DenyPrefix + permission
+		}
+		permissions = append(permissions, permission)
+	}
+
+	return permissions, nil
+}
+
+// getRolesPermissionList - effective permissions of roles (including inherited ones) in the jwt claim format
+func (layer *RBACLayer) getRolesPermissionList(roleNames ...string) ([]string, error) {
+	permissions := []string{}
+	for _, roleName := range roleNames {
+		rolePermissions, err := layer.Cache.Get(layer.context(), roleCacheKey(roleName), func() ([]string, error) {
+			return layer.loadRolesPermissionList(roleName)
+		})
+		if err != nil {
+			return nil, err
+		}
+		permissions = append(permissions, rolePermissions...)
+	}
+
+	return permissions, nil
+}
+
+func (layer *RBACLayer) loadRolesPermissionList(roleNames ...string) ([]string, error) {
+	hierarchy, err := layer.GetRoleHierarchy()
+	if err != nil {
+		return nil, err
+	}
+	expandedRoles, err := hierarchy.Expand(roleNames...)
+	if err != nil {
+		return nil, internal.PrintError(internal.ErrGettingRolePermissions, err)
+	}
+
+	var rolePermissions []model.UserRolePermission
+	err = layer.DB.Joins("Permission").
+		Where("user_role_permissions.role_id IN ?", internal.Mapping(expandedRoles, func(x model.UserRole) uuid.UUID { return x.ID })).
+		Find(&rolePermissions).Error
+	if err != nil {
+		return nil, internal.PrintError(internal.ErrGettingRolePermissions, err)
+	}
+
+	return internal.Mapping(rolePermissions, func(x model.UserRolePermission) string {
+		if x.Deny {
+			return DenyPrefix + PermissionString(*x.Permission)
+		}
+		return PermissionString(*x.Permission)
+	}), nil
+}
+
+// GetUserScopedGrants - roles and permissions granted to user for resources
+func (layer *RBACLayer) GetUserScopedGrants(userId uuid.UUID) ([]types.ScopedGrant, error) {
+	var scopedRoles []model.UserScopedRole
+	err := layer.DB.Preload("Role").Where("user_id = ?", userId).Find(&scopedRoles).Error
+	if err != nil {
+		return nil, internal.PrintError(internal.ErrGettingUserPermits, err)
+	}
+
+	var scopedPermissions []model.UserScopedPermission
+	err = layer.DB.Preload("Permission").Where("user_id = ?", userId).Find(&scopedPermissions).Error
+	if err != nil {
+		return nil, internal.PrintError(internal.ErrGettingUserPermits, err)
+	}
+
+	grants := make([]types.ScopedGrant, 0, len(scopedRoles)+len(scopedPermissions))
+	for _, scopedRole := range scopedRoles {
+		if scopedRole.Role == nil {
+			continue
+		}
+		grants = append(grants, types.ScopedGrant{
+			Role:     scopedRole.Role.Name,
+			Resource: types.Resource{Type: scopedRole.ResourceType, ID: scopedRole.ResourceID},
+		})
+	}
+	for _, scopedPermission := range scopedPermissions {
+		if scopedPermission.Permission == nil {
+			continue
+		}
+		grants = append(grants, types.ScopedGrant{
+			Permission: PermissionString(*scopedPermission.Permission),
+			Resource:   types.Resource{Type: scopedPermission.ResourceType, ID: scopedPermission.ResourceID},
+			Deny:       scopedPermission.Deny,
+		})
+	}
+
+	return grants, nil
+}
+
+// GrantUserScopedRole - assign role to user for resources (granting an already assigned role does nothing)
+func (layer *RBACLayer) GrantUserScopedRole(userId uuid.UUID, roleName string, resource types.Resource) error {
+	if err := validateResource(resource); err != nil {
+		return err
+	}
+
+	err := layer.DB.Transaction(func(tx *gorm.DB) error {
+		if _, err := lockUser(tx, userId); err != nil {
+			return err
+		}
+
+		var role model.UserRole
+		res := tx.Where("name = ?", roleName).Limit(1).Find(&role)
+		if res.Error != nil || res.RowsAffected == 0 {
+			return internal.PrintError(internal.ErrRoleNotFound, res.Error)
+		}
+
+		err := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&model.UserScopedRole{
+			UserID:       userId,
+			RoleID:       role.ID,
+			ResourceType: resource.Type,
+			ResourceID:   resource.ID,
+		}).Error
+		if err != nil {
+			return internal.PrintError(internal.ErrGrantingPermit, err)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	layer.userChanged(userId, events.UserScopedRoleGranted, map[string]string{
+		"role": roleName, "resource_type": resource.Type, "resource_id": resource.ID,
+	})
+
+	return nil
+}
+
+// RevokeUserScopedRole - revoke role assigned to user for resources (revoking a not assigned role does nothing)
+func (layer *RBACLayer) RevokeUserScopedRole(userId uuid.UUID, roleName string, resource types.Resource) error {
+	if err := validateResource(resource); err != nil {
+		return err
+	}
+
+	err := layer.DB.Transaction(func(tx *gorm.DB) error {
+		if _, err := lockUser(tx, userId); err != nil {
+			return err
+		}
+
+		var role model.UserRole
+		res := tx.Where("name = ?", roleName).Limit(1).Find(&role)
+		if res.Error != nil || res.RowsAffected == 0 {
+			return internal.PrintError(internal.ErrRoleNotFound, res.Error)
+		}
+
+		err := tx.Where("user_id = ? AND role_id = ? AND resource_type = ? AND resource_id = ?",
+			userId, role.ID, resource.Type, resource.ID,
+		).Delete(&model.UserScopedRole{}).Error
+		if err != nil {
+			return internal.PrintError(internal.ErrRevokingPermit, err)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	layer.userChanged(userId, events.UserScopedRoleRevoked, map[string]string{
+		"role": roleName, "resource_type": resource.Type, "resource_id": resource.ID,
+	})
+
+	return nil
+}
+
+// GrantUserScopedPermission - grant (or deny) user permission for resources
+// (granting an already granted permission does nothing, deny flag is replaced)
+func (layer *RBACLayer) GrantUserScopedPermission(userId uuid.UUID, permissionModel, permissionAction string, resource types.Resource, deny bool) error {
+	if err := validateResource(resource); err != nil {
+		return err
+	}
+
+	err := layer.DB.Transaction(func(tx *gorm.DB) error {
+		if _, err := lockUser(tx, userId); err != nil {
+			return err
+		}
+
+		var permission model.UserPermission
+		res := tx.Where("model = ? and action = ?", permissionModel, permissionAction).Limit(1).Find(&permission)
+		if res.Error != nil || res.RowsAffected == 0 {
+			return internal.PrintError(internal.ErrPermissionNotFound, res.Error)
+		}
+
+		err := tx.Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "user_id"}, {Name: "permission_id"}, {Name: "resource_type"}, {Name: "resource_id"}},
+			DoUpdates: clause.AssignmentColumns([]string{"deny"}),
+		}).Create(&model.UserScopedPermission{
+			UserID:       userId,
+			PermissionID: permission.ID,
+			ResourceType: resource.Type,
+			ResourceID:   resource.ID,
+			Deny:         deny,
+		}).Error
+		if err != nil {
+			return internal.PrintError(internal.ErrGrantingPermit, err)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	layer.userChanged(userId, events.UserScopedPermissionGranted, map[string]string{
+		"permission": permissionModel + ":" + permissionAction, "deny": strconv.FormatBool(deny), "resource_type": resource.Type, "resource_id": resource.ID,
+	})
+
+	return nil
+}
+
+// RevokeUserScopedPermission - revoke user permission granted (or denied) for resources
+// (revoking a not granted permission does nothing)
+func (layer *RBACLayer) RevokeUserScopedPermission(userId uuid.UUID, permissionModel, permissionAction string, resource types.Resource) error {
+	if err := validateResource(resource); err != nil {
+		return err
+	}
+
+	err := layer.DB.Transaction(func(tx *gorm.DB) error {
+		if _, err := lockUser(tx, userId); err != nil {
+			return err
+		}
+
+		var permission model.UserPermission
+		res := tx.Where("model = ? and action = ?", permissionModel, permissionAction).Limit(1).Find(&permission)
+		if res.Error != nil || res.RowsAffected == 0 {
+			return internal.PrintError(internal.ErrPermissionNotFound, res.Error)
+		}
+
+		err := tx.Where("user_id = ? AND permission_id = ? AND resource_type = ? AND resource_id = ?",
+			userId, permission.ID, resource.Type, resource.ID,
+		).Delete(&model.UserScopedPermission{}).Error
+		if err != nil {
+			return internal.PrintError(internal.ErrRevokingPermit, err)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	layer.userChanged(userId, events.UserScopedPermissionRevoked, map[string]string{
+		"permission": permissionModel + ":" + permissionAction, "resource_type": resource.Type, "resource_id": resource.ID,
+	})
+
+	return nil
+}
+
+// ResourceChecker - user is allowed to perform permission on the resource (see RBACLayer.Can)
+type ResourceChecker func(userId uuid.UUID, permission string, resource types.Resource) (bool, error)
+
+// CheckResourceAccess - middleware that permits request only if current user (fiber local "user_id")
+// can perform permission on the resource of resourceType with id from route parameter resourceIdParam
+func (layer *RBACLayer) CheckResourceAccess(permission, resourceType, resourceIdParam string) fiber.Handler {
+	return CheckResourceAccessWith(layer.Can, permission, resourceType, resourceIdParam)
+}
+
+// CheckResourceAccessWith is CheckResourceAccess with the checker
+func CheckResourceAccessWith(can ResourceChecker, permission, resourceType, resourceIdParam string) fiber.Handler {
+	return func(fiberCtx *fiber.Ctx) error {
+		userId, err := uuid.Parse(fmt.Sprint(fiberCtx.Locals("user_id")))
+		if err != nil {
+			return accessNotPermitted(fiberCtx)
+		}
+
+		allowed, err := can(userId, permission, types.Resource{
+			Type: resourceType,
+			ID:   fiberCtx.Params(resourceIdParam),
+		})
+		if err != nil {
+			log.Error().Err(err).Msg("check resource access")
+		}
+		if !allowed {
+			return accessNotPermitted(fiberCtx)
+		}
+
+		return fiberCtx.Next()
+	}
+}
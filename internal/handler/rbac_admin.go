@@ -0,0 +1,491 @@
+package handler
+
+import (
+	"strings"
+
+	"github.com/G0tem/go-service-auth/internal/handler/rbac"
+	"github.com/G0tem/go-service-auth/internal/model"
+	"github.com/G0tem/go-service-auth/internal/types"
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+)
+
+// Get roles
+// @Summary Get roles
+// @Description Get all roles with their parent roles
+// @Tags rbac
+// @Produce json
+// @Success 200 {object} types.RolesResponse
+// @Failure 403 {object} types.FailureResponse
+// @Failure 500 {object} types.FailureErrorResponse
+// @Security ApiKeyAuth
+// @Router /rbac/roles [get]
+func (h *Handler) getRoles(c *fiber.Ctx) error {
+	roles, err := h.rbac.GetRoles()
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(types.FailureErrorResponse{
+			Status:  "error",
+			Message: "Internal server error",
+			Error:   err.Error(),
+		})
+	}
+
+	hierarchy, err := h.rbac.GetRoleHierarchy()
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(types.FailureErrorResponse{
+			Status:  "error",
+			Message: "Internal server error",
+			Error:   err.Error(),
+		})
+	}
+
+	data := make([]types.RoleResponse, 0, len(roles))
+	for _, role := range roles {
+		data = append(data, types.RoleResponse{
+			Name:        role.Name,
+			Description: role.Description,
+			Parent:      hierarchy.ParentName(role.Name),
+		})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(types.RolesResponse{
+		Status: "ok",
+		Data:   data,
+	})
+}
+
+// Set role parent
+// @Summary Set role parent
+// @Description Set parent role which permissions are inherited by the role (empty parent removes inheritance)
+// @Tags rbac
+// @Accept json
+// @Produce json
+// @Param name path string true "role name"
+// @Param request body types.SetRoleParentRequest true "parent role"
+// @Success 200 {object} types.SuccessResponse
+// @Failure 400 {object} types.FailureErrorResponse
+// @Failure 403 {object} types.FailureResponse
+// @Security ApiKeyAuth
+// @Router /rbac/roles/{name}/parent [put]
+func (h *Handler) setRoleParent(c *fiber.Ctx) error {
+	input := new(types.SetRoleParentRequest)
+
+	if err := c.BodyParser(input); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(types.FailureErrorResponse{
+			Status:  "error",
+			Message: "Error on set role parent request",
+			Error:   err.Error(),
+		})
+	}
+
+	if _, err := h.rbac.SetRoleParent(c.Params("name"), input.Parent); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(types.FailureErrorResponse{
+			Status:  "error",
+			Message: "Can't set role parent",
+			Error:   err.Error(),
+		})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(types.SuccessResponse{
+		Status:  "ok",
+		Message: "Role parent changed.",
+	})
+}
+
+// Explain role permissions
+// @Summary Explain role permissions
+// @Description Effective role permissions and roles of the hierarchy contributed each of them
+// @Tags rbac
+// @Produce json
+// @Param name path string true "role name"
+// @Success 200 {object} types.ExplainPermissionsResponse
+// @Failure 400 {object} types.FailureErrorResponse
+// @Failure 403 {object} types.FailureResponse
+// @Security ApiKeyAuth
+// @Router /rbac/roles/{name}/permissions/explain [get]
+func (h *Handler) explainRolePermissions(c *fiber.Ctx) error {
+	explanations, err := h.rbac.ExplainRolePermissions(c.Params("name"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(types.FailureErrorResponse{
+			Status:  "error",
+			Message: "Can't explain role permissions",
+			Error:   err.Error(),
+		})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(types.ExplainPermissionsResponse{
+		Status: "ok",
+		Data:   explanations,
+	})
+}
+
+// Explain user permissions
+// @Summary Explain user permissions
+// @Description Effective user permissions and roles (or direct grants) contributed each of them
+// @Tags rbac
+// @Produce json
+// @Param id path string true "user id"
+// @Success 200 {object} types.ExplainPermissionsResponse
+// @Failure 400 {object} types.FailureErrorResponse
+// @Failure 403 {object} types.FailureResponse
+// @Security ApiKeyAuth
+// @Router /rbac/users/{id}/permissions/explain [get]
+func (h *Handler) explainUserPermissions(c *fiber.Ctx) error {
+	userId, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(types.FailureErrorResponse{
+			Status:  "error",
+			Message: "Invalid user id",
+			Error:   err.Error(),
+		})
+	}
+
+	explanations, err := h.rbac.ExplainUserPermissions(userId)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(types.FailureErrorResponse{
+			Status:  "error",
+			Message: "Can't explain user permissions",
+			Error:   err.Error(),
+		})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(types.ExplainPermissionsResponse{
+		Status: "ok",
+		Data:   explanations,
+	})
+}
+
+// Check user permission
+// @Summary Check user permission
+// @Description Check if user effective permissions allow the permission (wildcards like "orders:*" are supported, explicit deny wins)
+// @Tags rbac
+// @Produce json
+// @Param id path string true "user id"
+// @Param permission query string true "permission (model:action)"
+// @Success 200 {object} types.CheckPermissionResponse
+// @Failure 400 {object} types.FailureErrorResponse
+// @Failure 403 {object} types.FailureResponse
+// @Security ApiKeyAuth
+// @Router /rbac/users/{id}/permissions/check [get]
+func (h *Handler) checkUserPermission(c *fiber.Ctx) error {
+	userId, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(types.FailureErrorResponse{
+			Status:  "error",
+			Message: "Invalid user id",
+			Error:   err.Error(),
+		})
+	}
+
+	permission := c.Query("permission")
+	if !strings.Contains(permission, ":") {
+		return c.Status(fiber.StatusBadRequest).JSON(types.FailureErrorResponse{
+			Status:  "error",
+			Message: "Invalid permission",
+			Error:   "permission must be in model:action format",
+		})
+	}
+
+	permissions, err := h.rbac.GetUserPermissionList(userId)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(types.FailureErrorResponse{
+			Status:  "error",
+			Message: "Can't get user permissions",
+			Error:   err.Error(),
+		})
+	}
+
+	matchedBy, allowed := rbac.NewPermissionSet(permissions...).Match(permission)
+	response := types.CheckPermissionResponse{
+		Status:  "ok",
+		Allowed: allowed,
+	}
+	if matchedBy.Model != "" {
+		response.MatchedBy = matchedBy.String()
+	}
+
+	return c.Status(fiber.StatusOK).JSON(response)
+}
+
+// Get user scoped grants
+// @Summary Get user scoped grants
+// @Description Get roles and permissions granted to user for resources
+// @Tags rbac
+// @Produce json
+// @Param id path string true "user id"
+// @Success 200 {object} types.ScopedGrantsResponse
+// @Failure 400 {object} types.FailureErrorResponse
+// @Failure 403 {object} types.FailureResponse
+// @Security ApiKeyAuth
+// @Router /rbac/users/{id}/grants/scoped [get]
+func (h *Handler) getUserScopedGrants(c *fiber.Ctx) error {
+	userId, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(types.FailureErrorResponse{
+			Status:  "error",
+			Message: "Invalid user id",
+			Error:   err.Error(),
+		})
+	}
+
+	grants, err := h.rbac.GetUserScopedGrants(userId)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(types.FailureErrorResponse{
+			Status:  "error",
+			Message: "Can't get user scoped grants",
+			Error:   err.Error(),
+		})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(types.ScopedGrantsResponse{
+		Status: "ok",
+		Data:   grants,
+	})
+}
+
+// Grant user role or permission
+// @Summary Grant user role or permission
+// @Description Grant role or permission (model:action, optionally denied) to user globally or for resources
+// @Tags rbac
+// @Accept json
+// @Produce json
+// @Param id path string true "user id"
+// @Param request body types.UserGrantRequest true "grant"
+// @Success 200 {object} types.SuccessResponse
+// @Failure 400 {object} types.FailureErrorResponse
+// @Failure 403 {object} types.FailureResponse
+// @Security ApiKeyAuth
+// @Router /rbac/users/{id}/grants [post]
+func (h *Handler) grantUser(c *fiber.Ctx) error {
+	return h.changeUserGrant(c, false)
+}
+
+// Revoke user role or permission
+// @Summary Revoke user role or permission
+// @Description Revoke role or permission (model:action) granted to user globally or for resources
+// @Tags rbac
+// @Accept json
+// @Produce json
+// @Param id path string true "user id"
+// @Param request body types.UserGrantRequest true "grant"
+// @Success 200 {object} types.SuccessResponse
+// @Failure 400 {object} types.FailureErrorResponse
+// @Failure 403 {object} types.FailureResponse
+// @Security ApiKeyAuth
+// @Router /rbac/users/{id}/grants [delete]
+func (h *Handler) revokeUser(c *fiber.Ctx) error {
+	return h.changeUserGrant(c, true)
+}
+
+func (h *Handler) changeUserGrant(c *fiber.Ctx, revoke bool) error {
+	userId, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(types.FailureErrorResponse{
+			Status:  "error",
+			Message: "Invalid user id",
+			Error:   err.Error(),
+		})
+	}
+
+	input := new(types.UserGrantRequest)
+	if err := c.BodyParser(input); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(types.FailureErrorResponse{
+			Status:  "error",
+			Message: "Error on grant request",
+			Error:   err.Error(),
+		})
+	}
+
+	if err = h.svc.ChangeUserGrant(userId, *input, revoke); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(types.FailureErrorResponse{
+			Status:  "error",
+			Message: "Can't change user grants",
+			Error:   err.Error(),
+		})
+	}
+
+	message := "Granted."
+	if revoke {
+		message = "Revoked."
+	}
+	return c.Status(fiber.StatusOK).JSON(types.SuccessResponse{
+		Status:  "ok",
+		Message: message,
+	})
+}
+
+// Check user can
+// @Summary Check user can perform permission on resource
+// @Description Check if user global or resource scoped permissions allow the permission on the resource
+// @Tags rbac
+// @Produce json
+// @Param id path string true "user id"
+// @Param permission query string true "permission (model:action)"
+// @Param resource_type query string false "resource type"
+// @Param resource_id query string false "resource id"
+// @Success 200 {object} types.CanResponse
+// @Failure 400 {object} types.FailureErrorResponse
+// @Failure 403 {object} types.FailureResponse
+// @Security ApiKeyAuth
+// @Router /rbac/users/{id}/can [get]
+func (h *Handler) canUser(c *fiber.Ctx) error {
+	userId, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(types.FailureErrorResponse{
+			Status:  "error",
+			Message: "Invalid user id",
+			Error:   err.Error(),
+		})
+	}
+
+	resource := types.Resource{}
+	if err = c.QueryParser(&resource); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(types.FailureErrorResponse{
+			Status:  "error",
+			Message: "Invalid resource",
+			Error:   err.Error(),
+		})
+	}
+
+	permission := c.Query("permission")
+	if !strings.Contains(permission, ":") {
+		return c.Status(fiber.StatusBadRequest).JSON(types.FailureErrorResponse{
+			Status:  "error",
+			Message: "Invalid permission",
+			Error:   "permission must be in model:action format",
+		})
+	}
+
+	allowed, err := h.rbac.Can(userId, permission, resource)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(types.FailureErrorResponse{
+			Status:  "error",
+			Message: "Can't check user permission",
+			Error:   err.Error(),
+		})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(types.CanResponse{
+		Status:  "ok",
+		Allowed: allowed,
+	})
+}
+
+// Get RBAC policy
+// @Summary Get RBAC policy
+// @Description Get declarative RBAC policy applied to the database
+// @Tags rbac
+// @Produce json
+// @Success 200 {object} types.RbacPolicyResponse
+// @Failure 403 {object} types.FailureResponse
+// @Failure 404 {object} types.FailureResponse
+// @Security ApiKeyAuth
+// @Router /rbac/policy [get]
+func (h *Handler) getRbacPolicy(c *fiber.Ctx) error {
+	policy := h.rbac.CurrentPolicy()
+	if policy == nil {
+		return c.Status(fiber.StatusNotFound).JSON(types.FailureResponse{
+			Status:  "error",
+			Message: "RBAC policy is not applied",
+		})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(types.RbacPolicyResponse{
+		Status: "ok",
+		Data:   *policy,
+	})
+}
+
+// Plan RBAC policy reload
+// @Summary Plan RBAC policy reload
+// @Description Dry run of policy reload: changes synchronization with the policy file is going to make, nothing is written
+// @Tags rbac
+// @Produce json,plain
+// @Param sync_mode query string false "sync mode (configured one by default)" Enums(add_missed_only, delete_links, delete_outdated)
+// @Param format query string false "response format" Enums(json, text)
+// @Success 200 {object} types.RbacSyncPlanResponse
+// @Failure 400 {object} types.FailureErrorResponse
+// @Failure 403 {object} types.FailureResponse
+// @Failure 422 {object} types.FailureErrorResponse
+// @Security ApiKeyAuth
+// @Router /rbac/policy/plan [get]
+func (h *Handler) planRbacPolicy(c *fiber.Ctx) error {
+	action := h.rbac.SyncAction
+	if syncMode := c.Query("sync_mode"); syncMode != "" {
+		var err error
+		if action, err = rbac.ParseSyncAction(syncMode); err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(types.FailureErrorResponse{
+				Status:  "error",
+				Message: "Invalid sync mode",
+				Error:   err.Error(),
+			})
+		}
+	}
+
+	plan, err := h.rbac.PlanLoadPolicy(action)
+	if err != nil {
+		return c.Status(fiber.StatusUnprocessableEntity).JSON(types.FailureErrorResponse{
+			Status:  "error",
+			Message: "Error on plan rbac policy",
+			Error:   err.Error(),
+		})
+	}
+
+	if c.Query("format") == rbac.PlanFormatText {
+		return c.Status(fiber.StatusOK).SendString(rbac.FormatPlan(plan))
+	}
+
+	return c.Status(fiber.StatusOK).JSON(types.RbacSyncPlanResponse{
+		Status: "ok",
+		Data:   *plan,
+	})
+}
+
+// Reload RBAC policy
+// @Summary Reload RBAC policy
+// @Description Re-read policy file and synchronize roles and permissions with it (the same as SIGHUP).
+// @Description Already applied policy is skipped unless force is set.
+// @Tags rbac
+// @Produce json
+// @Param force query bool false "re-apply unchanged policy"
+// @Success 200 {object} types.RbacPolicyResponse
+// @Failure 403 {object} types.FailureResponse
+// @Failure 422 {object} types.FailureErrorResponse
+// @Security ApiKeyAuth
+// @Router /rbac/policy/reload [post]
+func (h *Handler) reloadRbacPolicy(c *fiber.Ctx) error {
+	policy, err := h.rbac.LoadPolicy(c.QueryBool("force"))
+	if err != nil {
+		return c.Status(fiber.StatusUnprocessableEntity).JSON(types.FailureErrorResponse{
+			Status:  "error",
+			Message: "Error on reload rbac policy",
+			Error:   err.Error(),
+		})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(types.RbacPolicyResponse{
+		Status: "ok",
+		Data:   *policy,
+	})
+}
+
+func (h *Handler) setupRbacRoutes(router fiber.Router, secretKey string) {
+	rbacGroup := router.Group("rbac")
+	rbacGroup.Use(JWTMiddleware(secretKey), h.rejectRevokedTokens)
+
+	canRead := h.rbac.CheckAccess([]string{model.AdminRole, "rbac:read"})
+	canManage := h.rbac.CheckAccess([]string{model.AdminRole, "rbac:manage"})
+
+	rbacGroup.Get("roles", canRead, h.getRoles)
+	rbacGroup.Put("roles/:name/parent", canManage, h.setRoleParent)
+	rbacGroup.Get("roles/:name/permissions/explain", canRead, h.explainRolePermissions)
+	rbacGroup.Get("users/:id/permissions/explain", canRead, h.explainUserPermissions)
+	rbacGroup.Get("users/:id/permissions/check", canRead, h.checkUserPermission)
+	rbacGroup.Get("users/:id/grants/scoped", canRead, h.getUserScopedGrants)
+	rbacGroup.Post("users/:id/grants", canManage, h.grantUser)
+	rbacGroup.Delete("users/:id/grants", canManage, h.revokeUser)
+	rbacGroup.Get("users/:id/can", canRead, h.canUser)
+	rbacGroup.Get("policy", canRead, h.getRbacPolicy)
+	rbacGroup.Get("policy/plan", canRead, h.planRbacPolicy)
+	rbacGroup.Post("policy/reload", canManage, h.reloadRbacPolicy)
+}
@@ -0,0 +1,245 @@
+package handler
+
+import (
+	"time"
+
+	"github.com/G0tem/go-service-auth/internal"
+	"github.com/G0tem/go-service-auth/internal/model"
+	"github.com/G0tem/go-service-auth/internal/service"
+	"github.com/G0tem/go-service-auth/internal/types"
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+)
+
+// issueToken returns new token of the user and its session, the token belongs to the session with sessionId
+// (the session is renewed) or to new session of the client if sessionId is nil
+func (h *Handler) issueToken(c *fiber.Ctx, user *model.User, sessionId uuid.UUID) (string, *model.UserSession, error) {
+	// iat of tokens has millisecond precision
+	issuedAt := time.Now().Truncate(time.Millisecond)
+	expiresAt := issuedAt.Add(TokenTTL)
+	client := service.SessionClient{UserAgent: c.Get(fiber.HeaderUserAgent), IP: c.IP()}
+
+	var (
+		session *model.UserSession
+		err     error
+	)
+	if sessionId == uuid.Nil {
+		session, err = h.svc.CreateSession(c.Context(), user.ID, client, model.MfaLevelPassword, issuedAt, expiresAt)
+	} else {
+		session, err = h.svc.RefreshSession(c.Context(), user.ID, sessionId, client, issuedAt, expiresAt)
+	}
+	if err != nil {
+		return "", nil, err
+	}
+	token, err := h.GetJWT(user, session.ID, issuedAt)
+	return token, session, err
+}
+
+// currentSessionId - id of the session of the token authenticated by JWTMiddleware
+// (nil for tokens issued before session tracking)
+func currentSessionId(c *fiber.Ctx) uuid.UUID {
+	sessionId, err := uuid.Parse(c.Locals("claims").(*JwtClaims).SessionID)
+	if err != nil {
+		return uuid.Nil
+	}
+	return sessionId
+}
+
+func sessionResponses(sessions []model.UserSession, current uuid.UUID) []types.SessionResponse {
+	return internal.Mapping(sessions, func(session model.UserSession) types.SessionResponse {
+		return types.SessionResponse{
+			ID:         session.ID.String(),
+			UserAgent:  session.UserAgent,
+			IP:         session.IP,
+			MfaLevel:   session.MfaLevel,
+			CreatedAt:  session.CreatedAt,
+			LastSeenAt: session.LastSeenAt,
+			ExpiresAt:  session.ExpiresAt,
+			Current:    session.ID == current,
+		}
+	})
+}
+
+// List sessions
+// @Summary List sessions
+// @Description Active sessions of the current user, recently used first
+// @Tags auth
+// @Produce json
+// @Success 200 {object} types.SessionListResponse
+// @Failure 401 {object} types.FailureResponse
+// @Failure 500 {object} types.FailureErrorResponse
+// @Security ApiKeyAuth
+// @Router /auth/sessions [get]
+func (h *Handler) listSessions(c *fiber.Ctx) error {
+	userId, err := currentUserId(c)
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(types.FailureResponse{
+			Status:  "error",
+			Message: "Invalid user id in token",
+		})
+	}
+
+	sessions, err := h.svc.ListSessions(c.Context(), userId)
+	if err != nil {
+		return serviceError(c, "Can't get sessions", err)
+	}
+
+	return c.Status(fiber.StatusOK).JSON(types.SessionListResponse{
+		Status: "ok",
+		Data:   sessionResponses(sessions, currentSessionId(c)),
+	})
+}
+
+// Revoke session
+// @Summary Revoke session
+// @Description Revoke session of the current user (log out the device), tokens of the session are rejected
+// @Tags auth
+// @Produce json
+// @Param id path string true "session id"
+// @Success 200 {object} types.SuccessResponse
+// @Failure 400 {object} types.FailureErrorResponse
+// @Failure 401 {object} types.FailureResponse
+// @Failure 404 {object} types.FailureErrorResponse
+// @Security ApiKeyAuth
+// @Router /auth/sessions/{id} [delete]
+func (h *Handler) revokeSession(c *fiber.Ctx) error {
+	userId, err := currentUserId(c)
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(types.FailureResponse{
+			Status:  "error",
+			Message: "Invalid user id in token",
+		})
+	}
+	sessionId, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(types.FailureErrorResponse{
+			Status:  "error",
+			Message: "Invalid session id",
+			Error:   err.Error(),
+		})
+	}
+
+	if err = h.svc.RevokeSession(c.Context(), userId, sessionId); err != nil {
+		return serviceError(c, "Can't revoke session", err)
+	}
+	if cookieAuth(c) && sessionId == currentSessionId(c) {
+		h.clearSessionCookies(c)
+	}
+
+	return c.Status(fiber.StatusOK).JSON(types.SuccessResponse{
+		Status:  "ok",
+		Message: "Session revoked.",
+	})
+}
+
+// Logout
+// @Summary Logout
+// @Description Revoke the current session and remove session cookies of cookie mode
+// @Tags auth
+// @Produce json
+// @Success 200 {object} types.SuccessResponse
+// @Failure 401 {object} types.FailureResponse
+// @Failure 500 {object} types.FailureErrorResponse
+// @Security ApiKeyAuth
+// @Router /auth/logout [post]
+func (h *Handler) logout(c *fiber.Ctx) error {
+	userId, err := currentUserId(c)
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(types.FailureResponse{
+			Status:  "error",
+			Message: "Invalid user id in token",
+		})
+	}
+
+	// Tokens issued before session tracking have no session to revoke
+	if sessionId := currentSessionId(c); sessionId != uuid.Nil {
+		if err = h.svc.RevokeSession(c.Context(), userId, sessionId); err != nil {
+			return serviceError(c, "Can't revoke session", err)
+		}
+	}
+	h.clearSessionCookies(c)
+
+	return c.Status(fiber.StatusOK).JSON(types.SuccessResponse{
+		Status:  "ok",
+		Message: "Logged out.",
+	})
+}
+
+// List sessions of user
+// @Summary List sessions of user
+// @Description Active sessions of the user, recently used first
+// @Tags users
+// @Produce json
+// @Param id path string true "user id"
+// @Success 200 {object} types.SessionListResponse
+// @Failure 400 {object} types.FailureErrorResponse
+// @Failure 403 {object} types.FailureResponse
+// @Failure 404 {object} types.FailureErrorResponse
+// @Security ApiKeyAuth
+// @Router /users/{id}/sessions [get]
+func (h *Handler) listUserSessions(c *fiber.Ctx) error {
+	userId, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(types.FailureErrorResponse{
+			Status:  "error",
+			Message: "Invalid user id",
+			Error:   err.Error(),
+		})
+	}
+
+	sessions, err := h.svc.ListSessions(c.Context(), userId)
+	if err != nil {
+		return serviceError(c, "Can't get sessions", err)
+	}
+
+	return c.Status(fiber.StatusOK).JSON(types.SessionListResponse{
+		Status: "ok",
+		Data:   sessionResponses(sessions, currentSessionId(c)),
+	})
+}
+
+// Revoke session of user
+// @Summary Revoke session of user
+// @Description Revoke session of the user, tokens of the session are rejected
+// @Tags users
+// @Produce json
+// @Param id path string true "user id"
+// @Param sessionId path string true "session id"
+// @Success 200 {object} types.SuccessResponse
+// @Failure 400 {object} types.FailureErrorResponse
+// @Failure 403 {object} types.FailureResponse
+// @Failure 404 {object} types.FailureErrorResponse
+// @Security ApiKeyAuth
+// @Router /users/{id}/sessions/{sessionId} [delete]
+func (h *Handler) revokeUserSession(c *fiber.Ctx) error {
+	userId, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(types.FailureErrorResponse{
+			Status:  "error",
+			Message: "Invalid user id",
+			Error:   err.Error(),
+		})
+	}
+	sessionId, err := uuid.Parse(c.Params("sessionId"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(types.FailureErrorResponse{
+			Status:  "error",
+			Message: "Invalid session id",
+			Error:   err.Error(),
+		})
+	}
+
+	if allowed, err := h.canChangeUsers(c, userId); err != nil {
+		return serviceError(c, "Can't revoke session", err)
+	} else if !allowed {
+		return privilegedUsersForbidden(c)
+	}
+	if err = h.svc.RevokeSession(c.Context(), userId, sessionId); err != nil {
+		return serviceError(c, "Can't revoke session", err)
+	}
+
+	return c.Status(fiber.StatusOK).JSON(types.SuccessResponse{
+		Status:  "ok",
+		Message: "Session revoked.",
+	})
+}
@@ -0,0 +1,301 @@
+package handler
+
+import (
+	"time"
+
+	"github.com/G0tem/go-service-auth/internal"
+	"github.com/G0tem/go-service-auth/internal/model"
+	"github.com/G0tem/go-service-auth/internal/service"
+	"github.com/G0tem/go-service-auth/internal/types"
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+)
+
+// List users
+// @Summary List users
+// @Description Page of users filtered by role, activity, email confirmation, creation time and search in username or email
+// @Tags users
+// @Produce json
+// @Param page query int false "page number (from 1)"
+// @Param page_size query int false "page size (20 by default, at most 100)"
+// @Param search query string false "part of username or email"
+// @Param sort query string false "sort fields: +field,-field (username, email, created_at, updated_at, is_active, email_confirmed)"
+// @Param role query string false "assigned role"
+// @Param is_active query bool false "active users"
+// @Param email_confirmed query bool false "users with confirmed email"
+// @Param created_from query string false "created at or after (RFC3339)"
+// @Param created_to query string false "created at or before (RFC3339)"
+// @Success 200 {object} types.UserListResponse
+// @Failure 400 {object} types.FailureErrorResponse
+// @Failure 403 {object} types.FailureResponse
+// @Security ApiKeyAuth
+// @Router /users [get]
+func (h *Handler) listUsers(c *fiber.Ctx) error {
+	input := new(types.UserListRequest)
+	if err := c.QueryParser(input); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(types.FailureErrorResponse{
+			Status:  "error",
+			Message: "Invalid query",
+			Error:   err.Error(),
+		})
+	}
+
+	filter := types.UserFilter{
+		Search:         input.Search,
+		Role:           input.Role,
+		IsActive:       input.IsActive,
+		EmailConfirmed: input.EmailConfirmed,
+	}
+	var err error
+	if filter.CreatedFrom, err = parseTimeParam(input.CreatedFrom); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(types.FailureErrorResponse{
+			Status:  "error",
+			Message: "Invalid created_from",
+			Error:   err.Error(),
+		})
+	}
+	if filter.CreatedTo, err = parseTimeParam(input.CreatedTo); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(types.FailureErrorResponse{
+			Status:  "error",
+			Message: "Invalid created_to",
+			Error:   err.Error(),
+		})
+	}
+
+	sort := internal.Mapping(parseSortString(input.Sort), func(x SortByField) types.SortField {
+		return types.SortField{Field: x.FieldName, Desc: x.SortDirection == SortDirectionDesc}
+	})
+
+	users, pagination, err := h.svc.ListUsers(c.Context(), input.PaginationRequest, filter, sort)
+	if err != nil {
+		return serviceError(c, "Can't list users", err)
+	}
+
+	return c.Status(fiber.StatusOK).JSON(types.UserListResponse{
+		Status:     "ok",
+		Data:       internal.Mapping(users, func(x model.User) types.UserResponse { return h.svc.ToUserResponse(&x) }),
+		Pagination: pagination,
+	})
+}
+
+// Get user
+// @Summary Get user
+// @Description Get user by id
+// @Tags users
+// @Produce json
+// @Param id path string true "user id"
+// @Success 200 {object} types.UserDetailResponse
+// @Failure 400 {object} types.FailureErrorResponse
+// @Failure 403 {object} types.FailureResponse
+// @Failure 404 {object} types.FailureErrorResponse
+// @Security ApiKeyAuth
+// @Router /users/{id} [get]
+func (h *Handler) getUser(c *fiber.Ctx) error {
+	userId, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(types.FailureErrorResponse{
+			Status:  "error",
+			Message: "Invalid user id",
+			Error:   err.Error(),
+		})
+	}
+
+	user, err := h.svc.GetUser(c.Context(), userId)
+	if err != nil {
+		return serviceError(c, "Can't get user", err)
+	}
+
+	return c.Status(fiber.StatusOK).JSON(types.UserDetailResponse{
+		Status: "ok",
+		Data:   h.svc.ToUserResponse(user),
+	})
+}
+
+// Update user
+// @Summary Update user
+// @Description Change fields which are set, deactivation and password change revoke user sessions. Admins and holders of rbac:manage are changed only by them.
+// @Tags users
+// @Accept json
+// @Produce json
+// @Param id path string true "user id"
+// @Param request body types.UpdateUserRequest true "changed fields"
+// @Success 200 {object} types.UserDetailResponse
+// @Failure 400 {object} types.FailureErrorResponse
+// @Failure 403 {object} types.FailureResponse
+// @Failure 404 {object} types.FailureErrorResponse
+// @Security ApiKeyAuth
+// @Router /users/{id} [patch]
+func (h *Handler) updateUser(c *fiber.Ctx) error {
+	userId, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(types.FailureErrorResponse{
+			Status:  "error",
+			Message: "Invalid user id",
+			Error:   err.Error(),
+		})
+	}
+
+	input := new(types.UpdateUserRequest)
+	if err := c.BodyParser(input); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(types.FailureErrorResponse{
+			Status:  "error",
+			Message: "Error on update user request",
+			Error:   err.Error(),
+		})
+	}
+	if input.IsActive != nil && !*input.IsActive && userId.String() == c.Locals("user_id") {
+		return c.Status(fiber.StatusBadRequest).JSON(types.FailureResponse{
+			Status:  "error",
+			Message: "You can't deactivate yourself",
+		})
+	}
+	if allowed, err := h.canChangeUsers(c, userId); err != nil {
+		return serviceError(c, "Can't update user", err)
+	} else if !allowed {
+		return privilegedUsersForbidden(c)
+	}
+
+	user, err := h.svc.UpdateUser(c.Context(), userId, *input)
+	if err != nil {
+		return serviceError(c, "Can't update user", err)
+	}
+
+	return c.Status(fiber.StatusOK).JSON(types.UserDetailResponse{
+		Status: "ok",
+		Data:   h.svc.ToUserResponse(user),
+	})
+}
+
+// Bulk user action
+// @Summary Apply action to users
+// @Description Activate, deactivate, confirm email, revoke sessions or delete up to 100 users, results are reported per user. Admins and holders of rbac:manage are changed only by them.
+// @Tags users
+// @Accept json
+// @Produce json
+// @Param request body types.BulkUserActionRequest true "users and action"
+// @Success 200 {object} types.BulkUserActionResponse
+// @Failure 400 {object} types.FailureErrorResponse
+// @Failure 403 {object} types.FailureResponse
+// @Security ApiKeyAuth
+// @Router /users/bulk [post]
+func (h *Handler) bulkUserAction(c *fiber.Ctx) error {
+	input := new(types.BulkUserActionRequest)
+	if err := c.BodyParser(input); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(types.FailureErrorResponse{
+			Status:  "error",
+			Message: "Error on bulk action request",
+			Error:   err.Error(),
+		})
+	}
+
+	userIds := make([]uuid.UUID, 0, len(input.IDs))
+	for _, id := range input.IDs {
+		userId, err := uuid.Parse(id)
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(types.FailureErrorResponse{
+				Status:  "error",
+				Message: "Invalid user id",
+				Error:   err.Error(),
+			})
+		}
+		userIds = append(userIds, userId)
+	}
+	actorId, err := currentUserId(c)
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(types.FailureResponse{
+			Status:  "error",
+			Message: "Invalid user id in token",
+		})
+	}
+	if err = service.CheckBulkTargets(actorId, userIds, input.Action); err != nil {
+		return serviceError(c, "Can't apply bulk action", err)
+	}
+	if allowed, err := h.canChangeUsers(c, userIds...); err != nil {
+		return serviceError(c, "Can't apply bulk action", err)
+	} else if !allowed {
+		return privilegedUsersForbidden(c)
+	}
+
+	results, err := h.svc.BulkUserAction(c.Context(), userIds, input.Action)
+	if err != nil {
+		return serviceError(c, "Can't apply bulk action", err)
+	}
+
+	return c.Status(fiber.StatusOK).JSON(types.BulkUserActionResponse{
+		Status: "ok",
+		Data:   results,
+	})
+}
+
+func (h *Handler) setupUserRoutes(router fiber.Router, secretKey string) {
+	usersGroup := router.Group("users")
+	usersGroup.Use(JWTMiddleware(secretKey), h.rejectRevokedTokens)
+
+	canList := h.rbac.CheckAccess([]string{model.AdminRole, "user:list"})
+	canManage := h.rbac.CheckAccess([]string{model.AdminRole, "user:manage"})
+
+	usersGroup.Get("/", canList, h.listUsers)
+	usersGroup.Post("bulk", canManage, h.bulkUserAction)
+	usersGroup.Get(":id", canList, h.getUser)
+	usersGroup.Patch(":id", canManage, h.updateUser)
+	usersGroup.Get(":id/sessions", canList, h.listUserSessions)
+	usersGroup.Delete(":id/sessions/:sessionId", canManage, h.revokeUserSession)
+}
+
+// parseTimeParam parses optional RFC3339 time
+func parseTimeParam(value string) (*time.Time, error) {
+	if value == "" {
+		return nil, nil
+	}
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return nil, err
+	}
+	return &t, nil
+}
+
+// canChangeUsers - current user can change the users: privileged users (see service.Privileged)
+// are changed only by privileged users. Missing users are left to the action to report.
+func (h *Handler) canChangeUsers(c *fiber.Ctx, userIds ...uuid.UUID) (bool, error) {
+	actorId, err := currentUserId(c)
+	if err != nil {
+		return false, nil
+	}
+	privileged, err := h.svc.Privileged(actorId)
+	if err != nil || privileged {
+		return privileged, err
+	}
+	for _, userId := range userIds {
+		privileged, err = h.svc.Privileged(userId)
+		if service.Kind(err) == service.ErrNotFound {
+			continue
+		}
+		if err != nil || privileged {
+			return false, err
+		}
+	}
+	return true, nil
+}
+
+func privilegedUsersForbidden(c *fiber.Ctx) error {
+	return c.Status(fiber.StatusForbidden).JSON(types.FailureResponse{
+		Status:  "error",
+		Message: "Only admins can change privileged users",
+	})
+}
+
+// serviceError responds with status matching the kind of service error
+func serviceError(c *fiber.Ctx, message string, err error) error {
+	status := fiber.StatusInternalServerError
+	switch service.Kind(err) {
+	case service.ErrInvalidArgument, service.ErrAlreadyExists:
+		status = fiber.StatusBadRequest
+	case service.ErrNotFound:
+		status = fiber.StatusNotFound
+	}
+	return c.Status(status).JSON(types.FailureErrorResponse{
+		Status:  "error",
+		Message: message,
+		Error:   err.Error(),
+	})
+}
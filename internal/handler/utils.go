@@ -57,6 +57,7 @@ type SortByField struct {
 	SortDirection SortDirection
 }
 
+// parseSortString parses "+field,-field,field" (unsigned fields are sorted ascending), "+" decoded from query as space is accepted
 func parseSortString(queryParam string) []SortByField {
 	result := make([]SortByField, 0, 10)
 	for _, sortByField := range strings.Split(queryParam, ",") {
@@ -64,14 +65,17 @@ func parseSortString(queryParam string) []SortByField {
 			continue
 		}
 		sortDirection := SortDirectionUnsorted
-		if sortByField[0] == "+"[0] {
+		switch sortByField[0] {
+		case '+', ' ':
 			sortDirection = SortDirectionAsc
-		}
-		if sortByField[0] == "-"[0] {
+		case '-':
 			sortDirection = SortDirectionDesc
 		}
+		if sortDirection != SortDirectionUnsorted {
+			sortByField = sortByField[1:]
+		}
 		result = append(result, SortByField{
-			FieldName:     sortByField[1:],
+			FieldName:     sortByField,
 			SortDirection: sortDirection,
 		})
 	}
@@ -0,0 +1,19 @@
+package model
+
+import "time"
+
+// RbacPolicyRevision represents the database model that stores history of RBAC policies applied to the database
+type RbacPolicyRevision struct {
+	ID      uint   `json:"id" gorm:"primaryKey"`
+	Version int    `json:"version" gorm:"not null"`
+	Hash    string `json:"hash" gorm:"not null;size:64;index"`
+	Source  string `json:"source" gorm:"not null"`
+	// SyncMode is the name of sync action the policy was applied with
+	SyncMode  string    `json:"sync_mode" gorm:"not null;size:50"`
+	Changes   int       `json:"changes" gorm:"not null"`
+	AppliedAt time.Time `json:"applied_at" gorm:"not null"`
+}
+
+func (rbacPolicyRevision *RbacPolicyRevision) TableName() string {
+	return "rbac_policy_revisions"
+}
@@ -4,6 +4,7 @@ import (
 	"time"
 
 	"github.com/google/uuid"
+	"gorm.io/datatypes"
 	"gorm.io/gorm"
 
 	"github.com/G0tem/go-service-auth/internal"
@@ -20,9 +21,21 @@ type User struct {
 	RoleID         uuid.UUID `gorm:"type:uuid;column:role_id" json:"role_id"`
 	Role           UserRole  `gorm:"foreignKey:RoleID;references:ID" json:"role"`
 	IsActive       bool      `gorm:"default:true" json:"is_active"`
-	CreatedAt      time.Time
-	UpdatedAt      time.Time
-	DeletedAt      gorm.DeletedAt `gorm:"index"`
+	// Profile fields editable by the user
+	DisplayName string            `gorm:"size:100;" validate:"max=100" json:"display_name"`
+	Locale      string            `gorm:"size:35;" validate:"omitempty,bcp47_language_tag" json:"locale"`
+	Timezone    string            `gorm:"size:64;" validate:"omitempty,timezone" json:"timezone"`
+	Attributes  datatypes.JSONMap `gorm:"type:jsonb;" json:"attributes"`
+	MfaEnabled  bool              `gorm:"not null;default:false;column:mfa_enabled;" json:"mfa_enabled"`
+	// CoverURL is the key of profile cover in covers bucket
+	CoverURL string `json:"cover_url"`
+	// UsernameChangedAt is time of the last username change by the user (for change cooldown)
+	UsernameChangedAt *time.Time `gorm:"column:username_changed_at" json:"-"`
+	// TokensValidAfter revokes tokens issued earlier (all sessions of the user)
+	TokensValidAfter *time.Time `gorm:"column:tokens_valid_after" json:"-"`
+	CreatedAt        time.Time
+	UpdatedAt        time.Time
+	DeletedAt        gorm.DeletedAt `gorm:"index"`
 }
 
 func (user *User) BeforeCreate(tx *gorm.DB) error {
@@ -38,6 +51,11 @@ func (user *User) GetAvatarUrl(cdnUrl string) string {
 	return internal.JoinUrl(cdnUrl, user.AvatarURL)
 }
 
+// TokenRevoked - token issued at issuedAt is revoked (zero issuedAt means token issued before revocation support)
+func (user *User) TokenRevoked(issuedAt time.Time) bool {
+	return user.TokensValidAfter != nil && (issuedAt.IsZero() || issuedAt.Before(*user.TokensValidAfter))
+}
+
 func (user *User) TableName() string {
 	return "users"
 }
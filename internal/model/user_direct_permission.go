@@ -0,0 +1,21 @@
+package model
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// UserDirectPermission represents the database model that stores permissions granted to users directly (bypassing roles)
+type UserDirectPermission struct {
+	UserID       uuid.UUID       `json:"user_id" gorm:"primaryKey;type:uuid"`
+	User         *User           `json:"-" gorm:"foreignKey:UserID;references:ID;constraint:OnUpdate:CASCADE,OnDelete:CASCADE;"`
+	PermissionID uuid.UUID       `json:"permission_id" gorm:"primaryKey;type:uuid"`
+	Permission   *UserPermission `json:"permission,omitempty" gorm:"foreignKey:PermissionID;references:ID;constraint:OnUpdate:CASCADE,OnDelete:CASCADE;"`
+	Deny         bool            `json:"deny" gorm:"not null;default:false"`
+	CreatedAt    time.Time       `json:"created_at"`
+}
+
+func (userDirectPermission *UserDirectPermission) TableName() string {
+	return "user_direct_permissions"
+}
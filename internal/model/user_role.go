@@ -7,11 +7,14 @@ import (
 	"gorm.io/gorm"
 )
 
-// Role represents the database model of roles
+// Role represents the database model of roles.
+// Role inherits all permissions of its parent role (and parent's ancestors).
 type UserRole struct {
-	ID          uuid.UUID `gorm:"primarykey;uniqueIndex;not null;type:uuid;" json:"id"`
-	Name        string    `gorm:"uniqueIndex;not null;size:50;" json:"name"`
-	Description string    `gorm:"size:255;nullable" json:"description"`
+	ID          uuid.UUID  `gorm:"primarykey;uniqueIndex;not null;type:uuid;" json:"id"`
+	Name        string     `gorm:"uniqueIndex;not null;size:50;" json:"name"`
+	Description string     `gorm:"size:255;nullable" json:"description"`
+	ParentID    *uuid.UUID `gorm:"type:uuid;column:parent_id" json:"parent_id"`
+	Parent      *UserRole  `gorm:"foreignKey:ParentID;references:ID;constraint:OnUpdate:CASCADE,OnDelete:SET NULL;" json:"-"`
 	CreatedAt   time.Time
 	UpdatedAt   time.Time
 	DeletedAt   gorm.DeletedAt `gorm:"index"`
@@ -0,0 +1,20 @@
+package model
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// UserRoleAssignment represents the database model that stores roles assigned to users
+type UserRoleAssignment struct {
+	UserID    uuid.UUID `json:"user_id" gorm:"primaryKey;type:uuid"`
+	User      *User     `json:"-" gorm:"foreignKey:UserID;references:ID;constraint:OnUpdate:CASCADE,OnDelete:CASCADE;"`
+	RoleID    uuid.UUID `json:"role_id" gorm:"primaryKey;type:uuid"`
+	Role      *UserRole `json:"role,omitempty" gorm:"foreignKey:RoleID;references:ID;constraint:OnUpdate:CASCADE,OnDelete:CASCADE;"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+func (userRoleAssignment *UserRoleAssignment) TableName() string {
+	return "user_role_assignments"
+}
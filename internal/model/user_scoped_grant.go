@@ -0,0 +1,40 @@
+package model
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// UserScopedRole represents the database model that stores roles assigned to users for resources only.
+// ResourceID may be exact id, "*" (any resource of the type) or prefix pattern like "team-a/*"
+type UserScopedRole struct {
+	UserID       uuid.UUID `json:"user_id" gorm:"primaryKey;type:uuid"`
+	User         *User     `json:"-" gorm:"foreignKey:UserID;references:ID;constraint:OnUpdate:CASCADE,OnDelete:CASCADE;"`
+	RoleID       uuid.UUID `json:"role_id" gorm:"primaryKey;type:uuid"`
+	Role         *UserRole `json:"role,omitempty" gorm:"foreignKey:RoleID;references:ID;constraint:OnUpdate:CASCADE,OnDelete:CASCADE;"`
+	ResourceType string    `json:"resource_type" gorm:"primaryKey;size:50"`
+	ResourceID   string    `json:"resource_id" gorm:"primaryKey;size:255"`
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+func (userScopedRole *UserScopedRole) TableName() string {
+	return "user_scoped_roles"
+}
+
+// UserScopedPermission represents the database model that stores permissions granted (or denied) to users for resources only.
+// ResourceID may be exact id, "*" (any resource of the type) or prefix pattern like "team-a/*"
+type UserScopedPermission struct {
+	UserID       uuid.UUID       `json:"user_id" gorm:"primaryKey;type:uuid"`
+	User         *User           `json:"-" gorm:"foreignKey:UserID;references:ID;constraint:OnUpdate:CASCADE,OnDelete:CASCADE;"`
+	PermissionID uuid.UUID       `json:"permission_id" gorm:"primaryKey;type:uuid"`
+	Permission   *UserPermission `json:"permission,omitempty" gorm:"foreignKey:PermissionID;references:ID;constraint:OnUpdate:CASCADE,OnDelete:CASCADE;"`
+	ResourceType string          `json:"resource_type" gorm:"primaryKey;size:50"`
+	ResourceID   string          `json:"resource_id" gorm:"primaryKey;size:255"`
+	Deny         bool            `json:"deny" gorm:"not null;default:false"`
+	CreatedAt    time.Time       `json:"created_at"`
+}
+
+func (userScopedPermission *UserScopedPermission) TableName() string {
+	return "user_scoped_permissions"
+}
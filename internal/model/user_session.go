@@ -0,0 +1,52 @@
+package model
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// Authentication levels of sessions
+const (
+	// MfaLevelPassword - the user logged in with password only
+	MfaLevelPassword = "password"
+)
+
+// UserSession represents the database model that stores logins of users, tokens carry session id ("sid" claim)
+type UserSession struct {
+	ID        uuid.UUID `json:"id" gorm:"primaryKey;type:uuid"`
+	UserID    uuid.UUID `json:"user_id" gorm:"not null;type:uuid;index"`
+	User      *User     `json:"-" gorm:"foreignKey:UserID;references:ID;constraint:OnUpdate:CASCADE,OnDelete:CASCADE;"`
+	UserAgent string    `json:"user_agent" gorm:"size:512"`
+	IP        string    `json:"ip" gorm:"size:64"`
+	MfaLevel  string    `json:"mfa_level" gorm:"not null;size:20"`
+	CreatedAt time.Time `json:"created_at"`
+	// LastSeenAt is updated on authenticated requests (at most once a minute) and refreshes
+	LastSeenAt time.Time `json:"last_seen_at" gorm:"not null"`
+	// TokenIssuedAt and ExpiresAt belong to the last token issued for the session
+	TokenIssuedAt time.Time  `json:"-" gorm:"not null"`
+	ExpiresAt     time.Time  `json:"expires_at" gorm:"not null;index"`
+	RevokedAt     *time.Time `json:"-"`
+}
+
+func (session *UserSession) BeforeCreate(tx *gorm.DB) error {
+	if session.ID == uuid.Nil {
+		session.ID = uuid.New()
+	}
+	return nil
+}
+
+// Active - the session is neither revoked nor expired
+func (session *UserSession) Active(now time.Time) bool {
+	return session.RevokedAt == nil && now.Before(session.ExpiresAt)
+}
+
+// Current - the token issued at issuedAt is the last token of the session (tokens replaced by refresh are not)
+func (session *UserSession) Current(issuedAt time.Time) bool {
+	return !issuedAt.Before(session.TokenIssuedAt)
+}
+
+func (session *UserSession) TableName() string {
+	return "user_sessions"
+}
@@ -0,0 +1,84 @@
+package queue
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+
+	"github.com/G0tem/go-service-auth/internal/types"
+	amqp "github.com/rabbitmq/amqp091-go"
+)
+
+// MailRoutingKey is the routing key of messages published to the mail exchange
+const MailRoutingKey = "mail"
+
+// MailPublisher publishes mail messages to RabbitMQ exchange, connection is opened on first message
+// and reopened after it is closed
+type MailPublisher struct {
+	url        string
+	exchange   string
+	autocreate bool
+
+	mu   sync.Mutex
+	conn *amqp.Connection
+}
+
+func NewMailPublisher(url, exchange string, autocreate bool) *MailPublisher {
+	return &MailPublisher{url: url, exchange: exchange, autocreate: autocreate}
+}
+
+// Send publishes persistent JSON message to the mail exchange
+func (p *MailPublisher) Send(ctx context.Context, message types.MailMessage) error {
+	body, err := json.Marshal(message)
+	if err != nil {
+		return err
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if err = p.connect(); err != nil {
+		return fmt.Errorf("mail queue connection: %v", err)
+	}
+	ch, err := p.conn.Channel()
+	if err != nil {
+		return fmt.Errorf("mail queue channel: %v", err)
+	}
+	defer ch.Close()
+
+	return ch.PublishWithContext(ctx, p.exchange, MailRoutingKey, false, false, amqp.Publishing{
+		ContentType:  "application/json",
+		DeliveryMode: amqp.Persistent,
+		Body:         body,
+	})
+}
+
+// Close closes connection to RabbitMQ
+func (p *MailPublisher) Close() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.conn == nil || p.conn.IsClosed() {
+		return nil
+	}
+	return p.conn.Close()
+}
+
+func (p *MailPublisher) connect() error {
+	if p.conn != nil && !p.conn.IsClosed() {
+		return nil
+	}
+	conn, err := amqp.Dial(p.url)
+	if err != nil {
+		return err
+	}
+	if p.autocreate {
+		if err = autocreateExchange(conn, p.exchange); err != nil {
+			_ = conn.Close()
+			return err
+		}
+	}
+	p.conn = conn
+	return nil
+}
@@ -0,0 +1,233 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/G0tem/go-service-auth/internal/events"
+	"github.com/G0tem/go-service-auth/internal/model"
+	"github.com/G0tem/go-service-auth/internal/types"
+	"github.com/go-redis/redis/v8"
+	"github.com/google/uuid"
+	"github.com/rs/zerolog/log"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// Templates of emails sent on email change
+const (
+	// MailEmailChangeConfirmation is sent to the new address with confirmation link
+	MailEmailChangeConfirmation = "email_change_confirmation"
+	// MailEmailChangeRequested notifies the old address about requested change
+	MailEmailChangeRequested = "email_change_requested"
+	// MailEmailChanged notifies the old address that the email was changed
+	MailEmailChanged = "email_changed"
+)
+
+// emailChangePrefix - Redis keys of pending email changes (by token hash and by user)
+const emailChangePrefix = "auth:email-change:"
+
+// pendingEmailChange - email change waiting for confirmation from the new address
+type pendingEmailChange struct {
+	UserID   uuid.UUID `json:"user_id"`
+	NewEmail string    `json:"new_email"`
+}
+
+func emailChangeTokenKey(token string) string {
+	hash := sha256.Sum256([]byte(token))
+	return emailChangePrefix + hex.EncodeToString(hash[:])
+}
+
+func emailChangeUserKey(userId uuid.UUID) string {
+	return emailChangePrefix + "user:" + userId.String()
+}
+
+// EmailChangeLink - link of the confirmation page with the token
+func EmailChangeLink(pageUrl, token string) (string, error) {
+	link, err := url.Parse(pageUrl)
+	if err != nil {
+		return "", err
+	}
+	query := link.Query()
+	query.Set("token", token)
+	link.RawQuery = query.Encode()
+	return link.String(), nil
+}
+
+// RequestEmailChange - send confirmation link to the new email and notice to the current one,
+// the email is changed only after confirmation (previous pending change of the user is cancelled)
+func (s *Service) RequestEmailChange(ctx context.Context, userId uuid.UUID, newEmail, password string) error {
+	user, err := s.GetUser(ctx, userId)
+	if err != nil {
+		return err
+	}
+	if bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(password)) != nil {
+		return invalidArgument("invalid password")
+	}
+
+	newEmail = strings.TrimSpace(newEmail)
+	if err = ValidateEmail(newEmail); err != nil {
+		return err
+	}
+	if strings.EqualFold(newEmail, user.Email) {
+		return invalidArgument("new email is the same as current one")
+	}
+	if err = s.checkEmailFree(ctx, newEmail); err != nil {
+		return err
+	}
+
+	tokenBytes := make([]byte, 32)
+	if _, err = rand.Read(tokenBytes); err != nil {
+		return err
+	}
+	token := hex.EncodeToString(tokenBytes)
+	link, err := EmailChangeLink(s.Cfg.PublicEmailChangeConfirmationUrl, token)
+	if err != nil {
+		return err
+	}
+
+	value, err := json.Marshal(pendingEmailChange{UserID: user.ID, NewEmail: newEmail})
+	if err != nil {
+		return err
+	}
+	tokenKey, userKey := emailChangeTokenKey(token), emailChangeUserKey(user.ID)
+	previousKey, err := s.Redis.Get(ctx, userKey).Result()
+	if err != nil && !errors.Is(err, redis.Nil) {
+		return err
+	}
+	_, err = s.Redis.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		if previousKey != "" {
+			pipe.Del(ctx, previousKey)
+		}
+		pipe.Set(ctx, tokenKey, value, s.Cfg.EmailChangeTTL)
+		pipe.Set(ctx, userKey, tokenKey, s.Cfg.EmailChangeTTL)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	err = s.Mail.Send(ctx, types.MailMessage{
+		To:       newEmail,
+		Template: MailEmailChangeConfirmation,
+		Data:     map[string]string{"username": user.Username, "link": link},
+	})
+	if err != nil {
+		s.Redis.Del(ctx, tokenKey, userKey)
+		return err
+	}
+	err = s.Mail.Send(ctx, types.MailMessage{
+		To:       user.Email,
+		Template: MailEmailChangeRequested,
+		Data:     map[string]string{"username": user.Username, "new_email": newEmail},
+	})
+	if err != nil {
+		log.Warn().Err(err).Str("user_id", user.ID.String()).Msg("Failed to notify old email about email change")
+	}
+	return nil
+}
+
+// ConfirmEmailChange - swap email of the user by confirmation token, the new email is confirmed
+// and all tokens of the user (carrying the old email) are revoked
+func (s *Service) ConfirmEmailChange(ctx context.Context, token string) (*model.User, error) {
+	tokenKey := emailChangeTokenKey(token)
+	value, err := s.Redis.Get(ctx, tokenKey).Bytes()
+	if errors.Is(err, redis.Nil) {
+		return nil, notFound("email change request is not found or expired")
+	}
+	if err != nil {
+		return nil, err
+	}
+	var pending pendingEmailChange
+	if err = json.Unmarshal(value, &pending); err != nil {
+		return nil, err
+	}
+
+	user, err := s.GetUser(ctx, pending.UserID)
+	if err != nil {
+		return nil, err
+	}
+	if err = s.checkEmailFree(ctx, pending.NewEmail); err != nil {
+		return nil, err
+	}
+	oldEmail := user.Email
+	err = s.db(ctx).Model(user).Updates(map[string]any{
+		"email":              pending.NewEmail,
+		"email_confirmed":    true,
+		"tokens_valid_after": time.Now(),
+	}).Error
+	if err != nil {
+		return nil, classify(err)
+	}
+	s.Redis.Del(ctx, tokenKey, emailChangeUserKey(user.ID))
+
+	s.Rbac.Events.Publish(ctx, events.UserUpdated, user.ID, nil)
+	s.Rbac.Events.Publish(ctx, events.UserSessionsRevoked, user.ID, nil)
+	err = s.Mail.Send(ctx, types.MailMessage{
+		To:       oldEmail,
+		Template: MailEmailChanged,
+		Data:     map[string]string{"username": user.Username, "new_email": pending.NewEmail},
+	})
+	if err != nil {
+		log.Warn().Err(err).Str("user_id", user.ID.String()).Msg("Failed to notify old email about email change")
+	}
+	return user, nil
+}
+
+// ChangeUsername - change username of the user, repeated changes are limited by UsernameChangeCooldown
+func (s *Service) ChangeUsername(ctx context.Context, userId uuid.UUID, username string) (*model.User, error) {
+	user, err := s.GetUser(ctx, userId)
+	if err != nil {
+		return nil, err
+	}
+	if err = ValidateUsername(username); err != nil {
+		return nil, err
+	}
+	if username == user.Username {
+		return user, nil
+	}
+
+	now := time.Now()
+	if next := NextUsernameChange(user, s.Cfg.UsernameChangeCooldown); now.Before(next) {
+		return nil, invalidArgument("username can be changed again after %v", next.Format(time.RFC3339))
+	}
+	var count int64
+	if err = s.db(ctx).Model(&model.User{}).Where("username = ? AND id <> ?", username, user.ID).Count(&count).Error; err != nil {
+		return nil, err
+	}
+	if count > 0 {
+		return nil, alreadyExists("username is already taken")
+	}
+
+	err = s.db(ctx).Model(user).Updates(map[string]any{"username": username, "username_changed_at": now}).Error
+	if err != nil {
+		return nil, classify(err)
+	}
+	s.Rbac.Events.Publish(ctx, events.UserUpdated, user.ID, nil)
+	return user, nil
+}
+
+// NextUsernameChange - time since the user may change username again
+func NextUsernameChange(user *model.User, cooldown time.Duration) time.Time {
+	if cooldown <= 0 || user.UsernameChangedAt == nil {
+		return time.Time{}
+	}
+	return user.UsernameChangedAt.Add(cooldown)
+}
+
+func (s *Service) checkEmailFree(ctx context.Context, email string) error {
+	var count int64
+	if err := s.db(ctx).Model(&model.User{}).Where("lower(email) = lower(?)", email).Count(&count).Error; err != nil {
+		return err
+	}
+	if count > 0 {
+		return alreadyExists("email is already taken")
+	}
+	return nil
+}
@@ -0,0 +1,105 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"errors"
+
+	"github.com/G0tem/go-service-auth/internal/avatar"
+	"github.com/G0tem/go-service-auth/internal/events"
+	"github.com/G0tem/go-service-auth/internal/model"
+	"github.com/G0tem/go-service-auth/internal/storage"
+	"github.com/google/uuid"
+	"github.com/rs/zerolog/log"
+)
+
+// SetAvatar - store variants of uploaded image as the avatar of the user and delete the previous one
+func (s *Service) SetAvatar(ctx context.Context, userId uuid.UUID, data []byte) (*model.User, error) {
+	user, err := s.GetUser(ctx, userId)
+	if err != nil {
+		return nil, err
+	}
+
+	images, err := avatar.Process(user.ID.String(), data)
+	var validationErr *avatar.ValidationError
+	if errors.As(err, &validationErr) {
+		return nil, invalidArgument("%v", validationErr.Message)
+	}
+	if err != nil {
+		return nil, err
+	}
+	key := images[0].Key
+	if key == user.AvatarURL {
+		return user, nil
+	}
+
+	for _, image := range images {
+		err = s.Storage.Put(ctx, s.Cfg.S3AvatarsBucketName, image.Key, bytes.NewReader(image.Data), int64(len(image.Data)),
+			storage.PutOptions{
+				ContentType: avatar.ContentType,
+				// Keys change with the content, so objects are never updated
+				CacheControl: "public, max-age=31536000, immutable",
+			})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	previous := user.AvatarURL
+	if err = s.db(ctx).Model(user).Update("avatar_url", key).Error; err != nil {
+		return nil, classify(err)
+	}
+	s.Rbac.Events.Publish(ctx, events.UserUpdated, user.ID, nil)
+	s.removeAvatar(ctx, previous)
+	return user, nil
+}
+
+// DeleteAvatar - delete avatar of the user
+func (s *Service) DeleteAvatar(ctx context.Context, userId uuid.UUID) (*model.User, error) {
+	user, err := s.GetUser(ctx, userId)
+	if err != nil {
+		return nil, err
+	}
+	if user.AvatarURL == "" {
+		return user, nil
+	}
+
+	previous := user.AvatarURL
+	if err = s.db(ctx).Model(user).Update("avatar_url", "").Error; err != nil {
+		return nil, classify(err)
+	}
+	s.Rbac.Events.Publish(ctx, events.UserUpdated, user.ID, nil)
+	s.removeAvatar(ctx, previous)
+	return user, nil
+}
+
+// AvatarVariantUrls - CDN links of avatar variants by their names (empty if the user has no avatar)
+func (s *Service) AvatarVariantUrls(user *model.User) map[string]string {
+	urls := map[string]string{}
+	if user.AvatarURL == "" {
+		return urls
+	}
+	for name, key := range avatar.VariantKeys(user.AvatarURL) {
+		urls[name] = (&model.User{AvatarURL: key}).GetAvatarUrl(s.Cfg.CdnPublicUrl)
+	}
+	return urls
+}
+
+// removeAvatar - delete objects of the avatar (failures are only logged, the avatar is already replaced)
+func (s *Service) removeAvatar(ctx context.Context, key string) {
+	if key == "" {
+		return
+	}
+	// Avatars uploaded before variants were generated are single objects
+	keys := []string{key}
+	for _, variantKey := range avatar.VariantKeys(key) {
+		if variantKey != key {
+			keys = append(keys, variantKey)
+		}
+	}
+	for _, objectKey := range keys {
+		if err := s.Storage.Delete(ctx, s.Cfg.S3AvatarsBucketName, objectKey); err != nil {
+			log.Warn().Err(err).Str("key", objectKey).Msg("Failed to delete avatar object")
+		}
+	}
+}
@@ -0,0 +1,281 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"image"
+	"io"
+	"maps"
+	"net/http"
+	"path"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/G0tem/go-service-auth/internal"
+	"github.com/G0tem/go-service-auth/internal/events"
+	"github.com/G0tem/go-service-auth/internal/model"
+	"github.com/G0tem/go-service-auth/internal/storage"
+	"github.com/G0tem/go-service-auth/internal/types"
+	"github.com/go-redis/redis/v8"
+	"github.com/google/uuid"
+	"github.com/rs/zerolog/log"
+)
+
+// CoverMaxDimension - max width and height of covers
+const CoverMaxDimension = 8192
+
+// CoverTypes - content types of covers and extensions of their keys
+var CoverTypes = map[string]string{
+	"image/jpeg": ".jpg",
+	"image/png":  ".png",
+	"image/webp": ".webp",
+}
+
+const (
+	// coverUploadPrefix - Redis keys of issued cover uploads waiting for completion
+	coverUploadPrefix = "auth:cover-upload:"
+	// coverUploadsKey - Redis sorted set of staging keys of issued uploads scored by their expiration
+	coverUploadsKey = "auth:cover-uploads"
+)
+
+// CoverStagingPrefix - prefix of keys presigned cover uploads are sent to (see CoverStagingKey)
+const CoverStagingPrefix = "uploads/"
+
+// pendingCoverUpload - constraints the uploaded object must satisfy
+type pendingCoverUpload struct {
+	ContentType string `json:"content_type"`
+	Size        int64  `json:"size"`
+}
+
+func coverUploadKey(key string) string {
+	return coverUploadPrefix + key
+}
+
+// CoverKey - new key of the cover of the user, keys of all covers of the user share "<user id>/" prefix
+func CoverKey(userId uuid.UUID, contentType string) string {
+	return path.Join(userId.String(), uuid.New().String()+CoverTypes[contentType])
+}
+
+// CoverStagingKey - new key presigned upload of the cover is sent to. The object is copied to a fresh
+// key (CoverKey) before it is verified, so the upload URL, valid until expiration, can't change the cover.
+func CoverStagingKey(userId uuid.UUID, contentType string) string {
+	return CoverStagingPrefix + CoverKey(userId, contentType)
+}
+
+// CoverUrl - public link of the cover (empty if the user has no cover)
+func (s *Service) CoverUrl(user *model.User) string {
+	if user.CoverURL == "" {
+		return ""
+	}
+	return internal.JoinUrl(s.Cfg.CdnCoversUrl, user.CoverURL)
+}
+
+// CreateCoverUpload - presigned request uploading the cover of the user directly to the storage,
+// the cover is attached by CompleteCoverUpload after the upload
+func (s *Service) CreateCoverUpload(
+	ctx context.Context, userId uuid.UUID, contentType string, size int64,
+) (*types.CoverUploadData, error) {
+	if _, ok := CoverTypes[contentType]; !ok {
+		allowed := strings.Join(slices.Sorted(maps.Keys(CoverTypes)), ", ")
+		return nil, invalidArgument("unsupported cover type %q, allowed %v", contentType, allowed)
+	}
+	if size <= 0 || size > s.Cfg.CoverMaxSizeInBytes {
+		return nil, invalidArgument("cover size must be from 1 to %d bytes", s.Cfg.CoverMaxSizeInBytes)
+	}
+	if _, err := s.GetUser(ctx, userId); err != nil {
+		return nil, err
+	}
+
+	key := CoverStagingKey(userId, contentType)
+	expiresAt := time.Now().Add(s.Cfg.CoverUploadTTL)
+	request, err := s.Storage.PresignPut(ctx, s.Cfg.S3CoversBucketName, key, s.Cfg.CoverUploadTTL, storage.PresignOptions{
+		ContentType:   contentType,
+		ContentLength: size,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	value, err := json.Marshal(pendingCoverUpload{ContentType: contentType, Size: size})
+	if err != nil {
+		return nil, err
+	}
+	// Upload started just before expiration may complete a bit later, staging objects of uploads
+	// which are not completed by then are deleted by CleanupCoverUploads
+	completeBefore := time.Now().Add(2 * s.Cfg.CoverUploadTTL)
+	if err = s.Redis.Set(ctx, coverUploadKey(key), value, 2*s.Cfg.CoverUploadTTL).Err(); err != nil {
+		return nil, err
+	}
+	err = s.Redis.ZAdd(ctx, coverUploadsKey, &redis.Z{Score: float64(completeBefore.Unix()), Member: key}).Err()
+	if err != nil {
+		return nil, err
+	}
+
+	return &types.CoverUploadData{
+		Key:       key,
+		Method:    request.Method,
+		URL:       request.URL,
+		Headers:   request.Headers,
+		ExpiresAt: expiresAt,
+	}, nil
+}
+
+// CompleteCoverUpload - copy uploaded object to a fresh key, verify the copy (size, type detected from
+// the content, dimensions) and attach it as the cover of the user. The staging object and the previous
+// cover are deleted.
+func (s *Service) CompleteCoverUpload(ctx context.Context, userId uuid.UUID, key string) (*model.User, error) {
+	if !strings.HasPrefix(key, CoverStagingPrefix+userId.String()+"/") {
+		return nil, notFound("cover upload %q is not found", key)
+	}
+	value, err := s.Redis.Get(ctx, coverUploadKey(key)).Bytes()
+	if errors.Is(err, redis.Nil) {
+		return nil, notFound("cover upload %q is not found or expired", key)
+	}
+	if err != nil {
+		return nil, err
+	}
+	var pending pendingCoverUpload
+	if err = json.Unmarshal(value, &pending); err != nil {
+		return nil, err
+	}
+
+	user, err := s.GetUser(ctx, userId)
+	if err != nil {
+		return nil, err
+	}
+	// Only the copy is verified and attached, the staging object can be replaced by the upload URL
+	cover := CoverKey(userId, pending.ContentType)
+	err = s.Storage.Copy(ctx, s.Cfg.S3CoversBucketName, key, cover)
+	if errors.Is(err, storage.ErrNotFound) {
+		return nil, invalidArgument("cover is not uploaded")
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err = s.verifyCover(ctx, cover, pending); err != nil {
+		s.deleteCover(ctx, cover)
+		if Kind(err) == ErrInvalidArgument {
+			s.finishCoverUpload(ctx, key)
+		}
+		return nil, err
+	}
+
+	previous := user.CoverURL
+	if err = s.db(ctx).Model(user).Update("cover_url", cover).Error; err != nil {
+		s.deleteCover(ctx, cover)
+		return nil, classify(err)
+	}
+	s.finishCoverUpload(ctx, key)
+	s.Rbac.Events.Publish(ctx, events.UserUpdated, user.ID, nil)
+	s.deleteCover(ctx, previous)
+	return user, nil
+}
+
+// finishCoverUpload - forget the upload and delete its staging object
+func (s *Service) finishCoverUpload(ctx context.Context, key string) {
+	s.deleteCover(ctx, key)
+	s.Redis.Del(ctx, coverUploadKey(key))
+	s.Redis.ZRem(ctx, coverUploadsKey, key)
+}
+
+// CleanupCoverUploads - delete staging objects of uploads which were not completed in time
+func (s *Service) CleanupCoverUploads(ctx context.Context) error {
+	keys, err := s.Redis.ZRangeByScore(ctx, coverUploadsKey, &redis.ZRangeBy{
+		Min: "-inf",
+		Max: strconv.FormatInt(time.Now().Unix(), 10),
+	}).Result()
+	if err != nil {
+		return err
+	}
+	for _, key := range keys {
+		s.finishCoverUpload(ctx, key)
+	}
+	return nil
+}
+
+// RunCoverUploadCleanup - run CleanupCoverUploads with the interval until ctx is done
+func (s *Service) RunCoverUploadCleanup(ctx context.Context, interval time.Duration) {
+	if interval <= 0 {
+		return
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		if err := s.CleanupCoverUploads(ctx); err != nil {
+			log.Error().Err(err).Msg("Failed to clean up cover uploads")
+		}
+	}
+}
+
+// DeleteCover - delete cover of the user
+func (s *Service) DeleteCover(ctx context.Context, userId uuid.UUID) (*model.User, error) {
+	user, err := s.GetUser(ctx, userId)
+	if err != nil {
+		return nil, err
+	}
+	if user.CoverURL == "" {
+		return user, nil
+	}
+
+	previous := user.CoverURL
+	if err = s.db(ctx).Model(user).Update("cover_url", "").Error; err != nil {
+		return nil, classify(err)
+	}
+	s.Rbac.Events.Publish(ctx, events.UserUpdated, user.ID, nil)
+	s.deleteCover(ctx, previous)
+	return user, nil
+}
+
+func (s *Service) verifyCover(ctx context.Context, key string, pending pendingCoverUpload) error {
+	reader, info, err := s.Storage.Get(ctx, s.Cfg.S3CoversBucketName, key)
+	if errors.Is(err, storage.ErrNotFound) {
+		return invalidArgument("cover is not uploaded")
+	}
+	if err != nil {
+		return err
+	}
+	defer reader.Close()
+
+	if info.Size != pending.Size {
+		return invalidArgument("uploaded cover has size %d, expected %d", info.Size, pending.Size)
+	}
+	head := make([]byte, 512)
+	n, err := io.ReadFull(reader, head)
+	if err != nil && !errors.Is(err, io.ErrUnexpectedEOF) {
+		return err
+	}
+	if detected := http.DetectContentType(head[:n]); detected != pending.ContentType {
+		return invalidArgument("uploaded cover is %v, expected %v", detected, pending.ContentType)
+	}
+
+	// Only the header of the image is read
+	config, _, err := image.DecodeConfig(io.MultiReader(bytes.NewReader(head[:n]), reader))
+	if err != nil {
+		return invalidArgument("invalid cover image: %v", err)
+	}
+	if config.Width > CoverMaxDimension || config.Height > CoverMaxDimension {
+		return invalidArgument("cover must be at most %dx%d pixels, got %dx%d",
+			CoverMaxDimension, CoverMaxDimension, config.Width, config.Height)
+	}
+	return nil
+}
+
+// deleteCover - delete cover object (failures are only logged)
+func (s *Service) deleteCover(ctx context.Context, key string) {
+	if key == "" {
+		return
+	}
+	if err := s.Storage.Delete(ctx, s.Cfg.S3CoversBucketName, key); err != nil {
+		log.Warn().Err(err).Str("key", key).Msg("Failed to delete cover object")
+	}
+}
@@ -0,0 +1,111 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"reflect"
+	"strings"
+	_ "time/tzdata" // timezones are validated in images without system tzdata
+
+	"github.com/G0tem/go-service-auth/internal/events"
+	"github.com/G0tem/go-service-auth/internal/model"
+	"github.com/G0tem/go-service-auth/internal/types"
+	"github.com/go-playground/validator/v10"
+	"github.com/google/uuid"
+	"gorm.io/datatypes"
+)
+
+// MaxAttributesSize - limit of custom user attributes encoded as JSON (bytes)
+const MaxAttributesSize = 4096
+
+// validate checks `validate` tags of models, errors refer to fields by their json names
+var validate = newValidator()
+
+func newValidator() *validator.Validate {
+	v := validator.New(validator.WithRequiredStructEnabled())
+	v.RegisterTagNameFunc(func(field reflect.StructField) string {
+		name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+		if name == "-" {
+			return ""
+		}
+		return name
+	})
+	return v
+}
+
+// ValidateFields - check `validate` tags of the listed fields of the model
+func ValidateFields(value any, fields ...string) error {
+	err := validate.StructPartial(value, fields...)
+	var validationErrors validator.ValidationErrors
+	if !errors.As(err, &validationErrors) {
+		return err
+	}
+
+	messages := make([]string, 0, len(validationErrors))
+	for _, fieldError := range validationErrors {
+		message := fieldError.Field() + " failed " + fieldError.Tag()
+		if fieldError.Param() != "" {
+			message += "=" + fieldError.Param()
+		}
+		messages = append(messages, message)
+	}
+	return invalidArgument("%v", strings.Join(messages, ", "))
+}
+
+// ValidateAttributes - custom attributes must fit into MaxAttributesSize when encoded
+func ValidateAttributes(attributes map[string]any) error {
+	data, err := json.Marshal(attributes)
+	if err != nil {
+		return invalidArgument("invalid attributes: %v", err)
+	}
+	if len(data) > MaxAttributesSize {
+		return invalidArgument("attributes must be at most %d bytes", MaxAttributesSize)
+	}
+	return nil
+}
+
+// UpdateProfile - change profile fields of the user which are set
+func (s *Service) UpdateProfile(ctx context.Context, userId uuid.UUID, input types.UpdateMeRequest) (*model.User, error) {
+	user, err := s.GetUser(ctx, userId)
+	if err != nil {
+		return nil, err
+	}
+
+	updates := map[string]any{}
+	fields := []string{}
+	if input.DisplayName != nil {
+		user.DisplayName = strings.TrimSpace(*input.DisplayName)
+		updates["display_name"] = user.DisplayName
+		fields = append(fields, "DisplayName")
+	}
+	if input.Locale != nil {
+		user.Locale = *input.Locale
+		updates["locale"] = user.Locale
+		fields = append(fields, "Locale")
+	}
+	if input.Timezone != nil {
+		user.Timezone = *input.Timezone
+		updates["timezone"] = user.Timezone
+		fields = append(fields, "Timezone")
+	}
+	if input.Attributes != nil {
+		if err = ValidateAttributes(input.Attributes); err != nil {
+			return nil, err
+		}
+		user.Attributes = datatypes.JSONMap(input.Attributes)
+		updates["attributes"] = user.Attributes
+	}
+	if len(updates) == 0 {
+		return user, nil
+	}
+	if err = ValidateFields(user, fields...); err != nil {
+		return nil, err
+	}
+
+	if err = s.db(ctx).Model(user).Updates(updates).Error; err != nil {
+		return nil, classify(err)
+	}
+	s.Rbac.Events.Publish(ctx, events.UserUpdated, user.ID, nil)
+	return user, nil
+}
@@ -0,0 +1,98 @@
+package service
+
+import (
+	"strings"
+
+	"github.com/G0tem/go-service-auth/internal/model"
+	"github.com/G0tem/go-service-auth/internal/types"
+	"github.com/google/uuid"
+)
+
+// ChangeUserGrant - grant or revoke user role or permission (model:action), globally or for the resource
+func (s *Service) ChangeUserGrant(userId uuid.UUID, input types.UserGrantRequest, revoke bool) (err error) {
+	if (input.Role == "") == (input.Permission == "") {
+		return invalidArgument("either role or permission must be set")
+	}
+	permissionModel, permissionAction, ok := strings.Cut(input.Permission, ":")
+	if input.Permission != "" && (!ok || permissionModel == "" || permissionAction == "") {
+		return invalidArgument("permission must be model:action")
+	}
+
+	switch {
+	case input.Resource != nil && input.Role != "" && revoke:
+		err = s.Rbac.RevokeUserScopedRole(userId, input.Role, *input.Resource)
+	case input.Resource != nil && input.Role != "":
+		err = s.Rbac.GrantUserScopedRole(userId, input.Role, *input.Resource)
+	case input.Resource != nil && revoke:
+		err = s.Rbac.RevokeUserScopedPermission(userId, permissionModel, permissionAction, *input.Resource)
+	case input.Resource != nil:
+		err = s.Rbac.GrantUserScopedPermission(userId, permissionModel, permissionAction, *input.Resource, input.Deny)
+	case input.Role != "" && revoke:
+		_, err = s.Rbac.RevokeUserRole(userId, input.Role)
+	case input.Role != "":
+		_, err = s.Rbac.GrantUserRole(userId, input.Role)
+	case revoke:
+		_, err = s.Rbac.RevokeUserPermission(userId, permissionModel, permissionAction)
+	case input.Deny:
+		_, err = s.Rbac.DenyUserPermission(userId, permissionModel, permissionAction)
+	default:
+		_, err = s.Rbac.GrantUserPermission(userId, permissionModel, permissionAction)
+	}
+
+	return classify(err)
+}
+
+func (s *Service) ListRoles() ([]model.UserRole, error) {
+	return s.Rbac.GetRoles()
+}
+
+func (s *Service) CreateRole(name, description string) (model.UserRole, error) {
+	if name == "" {
+		return model.UserRole{}, invalidArgument("role name is required")
+	}
+	role, err := s.Rbac.AddRole(model.UserRole{Name: name, Description: description})
+	return role, classify(err)
+}
+
+func (s *Service) DeleteRole(name string) error {
+	return classify(s.Rbac.DeleteRole(name))
+}
+
+// SetRoleParent - inherit permissions of parent role (empty parent detaches the role)
+func (s *Service) SetRoleParent(name, parent string) (model.UserRole, error) {
+	role, err := s.Rbac.SetRoleParent(name, parent)
+	return role, classify(err)
+}
+
+func (s *Service) ListPermissions() ([]model.UserPermission, error) {
+	return s.Rbac.GetPermissions()
+}
+
+func (s *Service) CreatePermission(permissionModel, permissionAction string) (*model.UserPermission, error) {
+	if permissionModel == "" || permissionAction == "" {
+		return nil, invalidArgument("permission model and action are required")
+	}
+	permission, err := s.Rbac.AddPermission(&model.UserPermission{
+		Model:  permissionModel,
+		Action: permissionAction,
+	})
+	return permission, classify(err)
+}
+
+func (s *Service) DeletePermission(permissionModel, permissionAction string) error {
+	return classify(s.Rbac.DeletePermission(permissionModel, permissionAction))
+}
+
+func (s *Service) AddRolePermission(roleName, permissionModel, permissionAction string, deny bool) error {
+	_, err := s.Rbac.AddRolePermission(types.AddRolePermission{
+		Role:             roleName,
+		PermissionModel:  permissionModel,
+		PermissionAction: permissionAction,
+		Deny:             deny,
+	})
+	return classify(err)
+}
+
+func (s *Service) RemoveRolePermission(roleName, permissionModel, permissionAction string) error {
+	return classify(s.Rbac.DeleteRolePermission(roleName, permissionModel, permissionAction))
+}
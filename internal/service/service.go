@@ -0,0 +1,105 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/G0tem/go-service-auth/internal"
+	"github.com/G0tem/go-service-auth/internal/config"
+	"github.com/G0tem/go-service-auth/internal/handler/rbac"
+	"github.com/G0tem/go-service-auth/internal/storage"
+	"github.com/G0tem/go-service-auth/internal/types"
+	"github.com/go-redis/redis/v8"
+	"github.com/jackc/pgx/v5/pgconn"
+	"gorm.io/gorm"
+)
+
+// Kinds of service errors, transports map them to their status codes (see Kind)
+var (
+	ErrInvalidArgument = errors.New("invalid argument")
+	ErrNotFound        = errors.New("not found")
+	ErrAlreadyExists   = errors.New("already exists")
+)
+
+// Service - user and RBAC management shared by HTTP handlers and gRPC services
+type Service struct {
+	DB    *gorm.DB
+	Rbac  *rbac.RBACLayer
+	Redis *redis.Client
+	Mail  Mailer
+	// Storage keeps avatars (S3AvatarsBucketName) and covers (S3CoversBucketName)
+	Storage storage.Storage
+	Cfg     *config.Config
+}
+
+// Mailer sends emails (see queue.MailPublisher)
+type Mailer interface {
+	Send(ctx context.Context, message types.MailMessage) error
+}
+
+func New(
+	db *gorm.DB, rbac *rbac.RBACLayer, redisClient *redis.Client, mail Mailer, store storage.Storage, cfg *config.Config,
+) *Service {
+	return &Service{DB: db, Rbac: rbac, Redis: redisClient, Mail: mail, Storage: store, Cfg: cfg}
+}
+
+// Kind - kind of the service error (ErrInvalidArgument, ErrNotFound or ErrAlreadyExists), nil for other errors
+func Kind(err error) error {
+	for _, kind := range []error{ErrInvalidArgument, ErrNotFound, ErrAlreadyExists} {
+		if errors.Is(err, kind) {
+			return kind
+		}
+	}
+	return nil
+}
+
+func invalidArgument(format string, args ...any) error {
+	return fmt.Errorf("%w: %v", ErrInvalidArgument, fmt.Sprintf(format, args...))
+}
+
+func notFound(format string, args ...any) error {
+	return fmt.Errorf("%w: %v", ErrNotFound, fmt.Sprintf(format, args...))
+}
+
+func alreadyExists(format string, args ...any) error {
+	return fmt.Errorf("%w: %v", ErrAlreadyExists, fmt.Sprintf(format, args...))
+}
+
+// notFoundMessages - errors of RBAC layer meaning missing user, role or permission
+var notFoundMessages = []string{
+	internal.ErrUserNotFound,
+	internal.ErrRoleNotFound,
+	internal.ErrPermissionNotFound,
+	internal.ErrRoleOrPermissionNotFound,
+}
+
+// classify - attach kind to errors of the database and RBAC layer
+func classify(err error) error {
+	if err == nil || Kind(err) != nil {
+		return err
+	}
+
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && pgErr.Code == "23505" {
+		return fmt.Errorf("%w: %v", ErrAlreadyExists, pgErr.Detail)
+	}
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return fmt.Errorf("%w: %v", ErrNotFound, err)
+	}
+	for _, message := range notFoundMessages {
+		if strings.HasPrefix(err.Error(), message) {
+			return fmt.Errorf("%w: %v", ErrNotFound, err)
+		}
+	}
+	if strings.HasPrefix(err.Error(), internal.ErrInvalidResource) ||
+		strings.HasPrefix(err.Error(), internal.ErrRoleHierarchyCycle) {
+		return fmt.Errorf("%w: %v", ErrInvalidArgument, err)
+	}
+	return err
+}
+
+func (s *Service) db(ctx context.Context) *gorm.DB {
+	return s.DB.WithContext(ctx)
+}
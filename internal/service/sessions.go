@@ -0,0 +1,134 @@
+package service
+
+import (
+	"context"
+	"time"
+
+	"github.com/G0tem/go-service-auth/internal/events"
+	"github.com/G0tem/go-service-auth/internal/model"
+	"github.com/google/uuid"
+)
+
+// sessionTouchInterval - last_seen_at of sessions is updated at most once per interval
+const sessionTouchInterval = time.Minute
+
+// SessionClient - device of the session
+type SessionClient struct {
+	UserAgent string
+	IP        string
+}
+
+// CreateSession - new session of the user for token issued at issuedAt and valid until expiresAt
+func (s *Service) CreateSession(
+	ctx context.Context, userId uuid.UUID, client SessionClient, mfaLevel string, issuedAt, expiresAt time.Time,
+) (*model.UserSession, error) {
+	session := model.UserSession{
+		UserID:        userId,
+		UserAgent:     truncate(client.UserAgent, 512),
+		IP:            truncate(client.IP, 64),
+		MfaLevel:      mfaLevel,
+		LastSeenAt:    issuedAt,
+		TokenIssuedAt: issuedAt,
+		ExpiresAt:     expiresAt,
+	}
+	if err := s.db(ctx).Create(&session).Error; err != nil {
+		return nil, classify(err)
+	}
+	return &session, nil
+}
+
+// RefreshSession - attach token issued at issuedAt and valid until expiresAt to the active session of the user
+func (s *Service) RefreshSession(
+	ctx context.Context, userId, sessionId uuid.UUID, client SessionClient, issuedAt, expiresAt time.Time,
+) (*model.UserSession, error) {
+	session, err := s.activeSession(ctx, userId, sessionId, issuedAt)
+	if err != nil {
+		return nil, err
+	}
+	err = s.db(ctx).Model(session).Updates(map[string]any{
+		"ip":              truncate(client.IP, 64),
+		"last_seen_at":    issuedAt,
+		"token_issued_at": issuedAt,
+		"expires_at":      expiresAt,
+	}).Error
+	if err != nil {
+		return nil, classify(err)
+	}
+	return session, nil
+}
+
+// TouchSession - check that the session of the user is active (not revoked nor expired) and the token
+// issued at issuedAt is its current token, then record its activity. Revoked and expired sessions
+// are not found, as well as sessions refreshed after the token was issued.
+func (s *Service) TouchSession(ctx context.Context, userId, sessionId uuid.UUID, issuedAt time.Time) error {
+	now := time.Now()
+	session, err := s.activeSession(ctx, userId, sessionId, now)
+	if err != nil {
+		return err
+	}
+	if !session.Current(issuedAt) {
+		return notFound("session %v not found", sessionId)
+	}
+	if now.Sub(session.LastSeenAt) < sessionTouchInterval {
+		return nil
+	}
+	return classify(s.db(ctx).Model(session).UpdateColumn("last_seen_at", now).Error)
+}
+
+// ListSessions - active sessions of the user, recently used first
+func (s *Service) ListSessions(ctx context.Context, userId uuid.UUID) ([]model.UserSession, error) {
+	user, err := s.GetUser(ctx, userId)
+	if err != nil {
+		return nil, err
+	}
+
+	query := s.db(ctx).Where("user_id = ? AND revoked_at IS NULL AND expires_at > ?", userId, time.Now())
+	// Sessions whose tokens were revoked at once (see RevokeUserSessions) are not active
+	if user.TokensValidAfter != nil {
+		query = query.Where("token_issued_at >= ?", *user.TokensValidAfter)
+	}
+	sessions := []model.UserSession{}
+	if err = query.Order("last_seen_at DESC").Find(&sessions).Error; err != nil {
+		return nil, classify(err)
+	}
+	return sessions, nil
+}
+
+// RevokeSession - revoke the session of the user, tokens of the session are rejected
+func (s *Service) RevokeSession(ctx context.Context, userId, sessionId uuid.UUID) error {
+	res := s.db(ctx).Model(&model.UserSession{}).
+		Where("id = ? AND user_id = ? AND revoked_at IS NULL", sessionId, userId).
+		Update("revoked_at", time.Now())
+	if res.Error != nil {
+		return classify(res.Error)
+	}
+	if res.RowsAffected == 0 {
+		return notFound("session %v not found", sessionId)
+	}
+	s.Rbac.Events.Publish(ctx, events.UserSessionsRevoked, userId, map[string]string{"session_id": sessionId.String()})
+	return nil
+}
+
+// activeSession - session of the user active at the time, it is not found otherwise
+func (s *Service) activeSession(ctx context.Context, userId, sessionId uuid.UUID, at time.Time) (*model.UserSession, error) {
+	var session model.UserSession
+	res := s.db(ctx).Where("id = ? AND user_id = ?", sessionId, userId).Limit(1).Find(&session)
+	if res.Error != nil {
+		return nil, classify(res.Error)
+	}
+	if res.RowsAffected == 0 || !session.Active(at) {
+		return nil, notFound("session %v not found", sessionId)
+	}
+	return &session, nil
+}
+
+func truncate(value string, size int) string {
+	if len(value) <= size {
+		return value
+	}
+	// Don't cut multibyte characters
+	for size > 0 && value[size]&0xC0 == 0x80 {
+		size--
+	}
+	return value[:size]
+}
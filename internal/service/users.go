@@ -0,0 +1,417 @@
+package service
+
+import (
+	"context"
+	"math"
+	"net/mail"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/G0tem/go-service-auth/internal/events"
+	"github.com/G0tem/go-service-auth/internal/handler/rbac"
+	"github.com/G0tem/go-service-auth/internal/model"
+	"github.com/G0tem/go-service-auth/internal/types"
+	"github.com/google/uuid"
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
+)
+
+const (
+	DefaultPageSize = 20
+	MaxPageSize     = 100
+)
+
+// ToUserResponse - representation of the user for admins
+func (s *Service) ToUserResponse(user *model.User) types.UserResponse {
+	return types.UserResponse{
+		ID:             user.ID.String(),
+		Username:       user.Username,
+		Email:          user.Email,
+		EmailConfirmed: user.EmailConfirmed,
+		IsActive:       user.IsActive,
+		Role:           user.Role.Name,
+		AvatarURL:      user.GetAvatarUrl(s.Cfg.CdnPublicUrl),
+		CreatedAt:      user.CreatedAt,
+		UpdatedAt:      user.UpdatedAt,
+	}
+}
+
+// ValidateUsername - username must be 3 to 50 characters without spaces
+func ValidateUsername(username string) error {
+	if len(username) < 3 || len(username) > 50 || strings.ContainsAny(username, " \t\r\n") {
+		return invalidArgument("username must be 3 to 50 characters without spaces")
+	}
+	return nil
+}
+
+// ValidateEmail - email must be a valid address
+func ValidateEmail(email string) error {
+	if _, err := mail.ParseAddress(email); err != nil {
+		return invalidArgument("invalid email address")
+	}
+	return nil
+}
+
+// ValidatePassword - password must be 6 to 50 characters
+func ValidatePassword(password string) error {
+	if len(password) < 6 || len(password) > 50 {
+		return invalidArgument("password must be 6 to 50 characters")
+	}
+	return nil
+}
+
+// CreateUser - create user with roles (default role if none), the first role is primary
+func (s *Service) CreateUser(ctx context.Context, input types.CreateUserRequest) (*model.User, error) {
+	if err := ValidateUsername(input.Username); err != nil {
+		return nil, err
+	}
+	if err := ValidateEmail(input.Email); err != nil {
+		return nil, err
+	}
+	if err := ValidatePassword(input.Password); err != nil {
+		return nil, err
+	}
+
+	roleNames := input.Roles
+	if len(roleNames) == 0 {
+		roleNames = []string{model.DefaultUserRole}
+	}
+
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(input.Password), bcrypt.DefaultCost)
+	if err != nil {
+		return nil, err
+	}
+
+	user := model.User{
+		Username:       input.Username,
+		Email:          input.Email,
+		EmailConfirmed: input.EmailConfirmed,
+		PasswordHash:   string(hashedPassword),
+		IsActive:       true,
+	}
+
+	err = s.db(ctx).Transaction(func(tx *gorm.DB) error {
+		var roles []model.UserRole
+		if err := tx.Where("name in ?", roleNames).Find(&roles).Error; err != nil {
+			return err
+		}
+		byName := make(map[string]model.UserRole, len(roles))
+		for _, role := range roles {
+			byName[role.Name] = role
+		}
+		for _, name := range roleNames {
+			if _, ok := byName[name]; !ok {
+				return notFound("role %v not found", name)
+			}
+		}
+
+		user.RoleID = byName[roleNames[0]].ID
+		user.Role = byName[roleNames[0]]
+		if err := tx.Omit("Role").Create(&user).Error; err != nil {
+			return err
+		}
+		for _, role := range roles {
+			err := tx.Create(&model.UserRoleAssignment{UserID: user.ID, RoleID: role.ID}).Error
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, classify(err)
+	}
+
+	return &user, nil
+}
+
+// GetUser - user with primary role
+func (s *Service) GetUser(ctx context.Context, userId uuid.UUID) (*model.User, error) {
+	var user model.User
+	if err := s.db(ctx).Preload("Role").Where("id = ?", userId).First(&user).Error; err != nil {
+		return nil, classify(err)
+	}
+	return &user, nil
+}
+
+// UserSortFields - fields users can be sorted by and their columns
+var UserSortFields = map[string]string{
+	"username":        "username",
+	"email":           "email",
+	"created_at":      "created_at",
+	"updated_at":      "updated_at",
+	"is_active":       "is_active",
+	"email_confirmed": "email_confirmed",
+}
+
+// ListUsers - page of users matching the filter, ordered by sort fields (newest first by default)
+func (s *Service) ListUsers(
+	ctx context.Context, pagination types.PaginationRequest, filter types.UserFilter, sort []types.SortField,
+) ([]model.User, types.PaginationResponse, error) {
+	page, pageSize := NormalizePage(pagination.Page, pagination.PageSize)
+	order, err := UserOrder(sort)
+	if err != nil {
+		return nil, types.PaginationResponse{}, err
+	}
+
+	query := s.UsersQuery(ctx, filter)
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, types.PaginationResponse{}, err
+	}
+
+	var users []model.User
+	err = query.Preload("Role").
+		Order(order).
+		Offset((page - 1) * pageSize).
+		Limit(pageSize).
+		Find(&users).Error
+	if err != nil {
+		return nil, types.PaginationResponse{}, err
+	}
+
+	return users, types.PaginationResponse{
+		CurrentPage:  page,
+		PageSize:     pageSize,
+		TotalPages:   int(math.Ceil(float64(total) / float64(pageSize))),
+		TotalRecords: total,
+	}, nil
+}
+
+// UserOrder - order clause of sort fields (only UserSortFields are allowed), newest first by default
+func UserOrder(sort []types.SortField) (string, error) {
+	order := make([]string, 0, len(sort)+1)
+	for _, field := range sort {
+		column, ok := UserSortFields[field.Field]
+		if !ok {
+			return "", invalidArgument("users can't be sorted by %q", field.Field)
+		}
+		if field.Desc {
+			column += " desc"
+		}
+		order = append(order, column)
+	}
+	if len(order) == 0 {
+		order = append(order, "created_at desc")
+	}
+	// Unique last key keeps pages stable
+	order = append(order, "id")
+	return strings.Join(order, ", "), nil
+}
+
+// UsersQuery - query of users matching the filter
+func (s *Service) UsersQuery(ctx context.Context, filter types.UserFilter) *gorm.DB {
+	query := s.db(ctx).Model(&model.User{})
+	if search := strings.TrimSpace(filter.Search); search != "" {
+		pattern := "%" + escapeLike(search) + "%"
+		query = query.Where("username ilike ? or email ilike ?", pattern, pattern)
+	}
+	if filter.Role != "" {
+		query = query.Where("id in (?)", s.db(ctx).
+			Model(&model.UserRoleAssignment{}).
+			Select("user_role_assignments.user_id").
+			Joins("join user_roles on user_roles.id = user_role_assignments.role_id").
+			Where("user_roles.name = ?", filter.Role))
+	}
+	if filter.IsActive != nil {
+		query = query.Where("is_active = ?", *filter.IsActive)
+	}
+	if filter.EmailConfirmed != nil {
+		query = query.Where("email_confirmed = ?", *filter.EmailConfirmed)
+	}
+	if filter.CreatedFrom != nil {
+		query = query.Where("created_at >= ?", *filter.CreatedFrom)
+	}
+	if filter.CreatedTo != nil {
+		query = query.Where("created_at <= ?", *filter.CreatedTo)
+	}
+	return query
+}
+
+// UpdateUser - change fields which are set, deactivation and password change revoke user tokens
+func (s *Service) UpdateUser(ctx context.Context, userId uuid.UUID, input types.UpdateUserRequest) (*model.User, error) {
+	user, err := s.GetUser(ctx, userId)
+	if err != nil {
+		return nil, err
+	}
+
+	updates := map[string]any{}
+	if input.Username != nil && *input.Username != user.Username {
+		if err := ValidateUsername(*input.Username); err != nil {
+			return nil, err
+		}
+		updates["username"] = *input.Username
+	}
+	if input.Email != nil && *input.Email != user.Email {
+		if err := ValidateEmail(*input.Email); err != nil {
+			return nil, err
+		}
+		updates["email"] = *input.Email
+		// New address is not confirmed (unless set explicitly), tokens carry the old email
+		updates["email_confirmed"] = false
+		updates["tokens_valid_after"] = time.Now()
+	}
+	if input.Password != nil {
+		if err := ValidatePassword(*input.Password); err != nil {
+			return nil, err
+		}
+		hashedPassword, err := bcrypt.GenerateFromPassword([]byte(*input.Password), bcrypt.DefaultCost)
+		if err != nil {
+			return nil, err
+		}
+		updates["password_hash"] = string(hashedPassword)
+		updates["tokens_valid_after"] = time.Now()
+	}
+	if input.EmailConfirmed != nil && (*input.EmailConfirmed != user.EmailConfirmed || updates["email"] != nil) {
+		updates["email_confirmed"] = *input.EmailConfirmed
+	}
+	activityChanged := input.IsActive != nil && *input.IsActive != user.IsActive
+	if activityChanged {
+		updates["is_active"] = *input.IsActive
+		if !*input.IsActive {
+			updates["tokens_valid_after"] = time.Now()
+		}
+	}
+	if len(updates) == 0 {
+		return user, nil
+	}
+
+	if err := s.db(ctx).Model(user).Updates(updates).Error; err != nil {
+		return nil, classify(err)
+	}
+	s.Rbac.Events.Publish(ctx, events.UserUpdated, user.ID, nil)
+	if input.Password != nil {
+		s.Rbac.Events.Publish(ctx, events.UserPasswordChanged, user.ID, nil)
+	}
+	if activityChanged && *input.IsActive {
+		s.Rbac.Events.Publish(ctx, events.UserActivated, user.ID, nil)
+	} else if activityChanged {
+		s.Rbac.Events.Publish(ctx, events.UserDeactivated, user.ID, nil)
+	}
+
+	return s.GetUser(ctx, userId)
+}
+
+// DeleteUser - soft delete user, tokens of deleted users are rejected
+func (s *Service) DeleteUser(ctx context.Context, userId uuid.UUID) error {
+	res := s.db(ctx).Where("id = ?", userId).Delete(&model.User{})
+	if res.Error != nil {
+		return classify(res.Error)
+	}
+	if res.RowsAffected == 0 {
+		return notFound("user %v not found", userId)
+	}
+	s.Rbac.Events.Publish(ctx, events.UserDeleted, userId, nil)
+	return nil
+}
+
+// RevokeUserSessions - revoke all tokens issued to the user before now
+func (s *Service) RevokeUserSessions(ctx context.Context, userId uuid.UUID) error {
+	now := time.Now()
+	res := s.db(ctx).Model(&model.User{}).Where("id = ?", userId).Update("tokens_valid_after", now)
+	if res.Error != nil {
+		return classify(res.Error)
+	}
+	if res.RowsAffected == 0 {
+		return notFound("user %v not found", userId)
+	}
+	err := s.db(ctx).Model(&model.UserSession{}).
+		Where("user_id = ? AND revoked_at IS NULL", userId).
+		Update("revoked_at", now).Error
+	if err != nil {
+		return classify(err)
+	}
+	s.Rbac.Events.Publish(ctx, events.UserSessionsRevoked, userId, nil)
+	return nil
+}
+
+// Bulk user actions
+const (
+	BulkActivate       = "activate"
+	BulkDeactivate     = "deactivate"
+	BulkConfirmEmail   = "confirm_email"
+	BulkRevokeSessions = "revoke_sessions"
+	BulkDelete         = "delete"
+)
+
+// CheckBulkTargets - the actor can't deactivate or delete themselves by bulk action
+func CheckBulkTargets(actorId uuid.UUID, userIds []uuid.UUID, action string) error {
+	if (action == BulkDeactivate || action == BulkDelete) && slices.Contains(userIds, actorId) {
+		return invalidArgument("you can't deactivate or delete yourself")
+	}
+	return nil
+}
+
+// PrivilegedPermission - users holding it (admins hold every permission) manage RBAC, only they
+// can change other privileged users (password, email, activity, sessions)
+const PrivilegedPermission = "rbac:manage"
+
+// Privileged - the user holds AdminRole or PrivilegedPermission
+func (s *Service) Privileged(userId uuid.UUID) (bool, error) {
+	permits, err := s.Rbac.GetUserPermits(userId)
+	if err != nil {
+		return false, classify(err)
+	}
+	return slices.Contains(permits.Roles, model.AdminRole) ||
+		rbac.NewPermissionSet(permits.PermitList...).Allows(PrivilegedPermission), nil
+}
+
+// BulkUserAction - apply action to every user (at most MaxPageSize), failures are reported per user
+func (s *Service) BulkUserAction(ctx context.Context, userIds []uuid.UUID, action string) ([]types.BulkUserActionResult, error) {
+	active, confirmed := true, true
+	inactive := false
+	var apply func(uuid.UUID) error
+	switch action {
+	case BulkActivate:
+		apply = func(id uuid.UUID) error {
+			_, err := s.UpdateUser(ctx, id, types.UpdateUserRequest{IsActive: &active})
+			return err
+		}
+	case BulkDeactivate:
+		apply = func(id uuid.UUID) error {
+			_, err := s.UpdateUser(ctx, id, types.UpdateUserRequest{IsActive: &inactive})
+			return err
+		}
+	case BulkConfirmEmail:
+		apply = func(id uuid.UUID) error {
+			_, err := s.UpdateUser(ctx, id, types.UpdateUserRequest{EmailConfirmed: &confirmed})
+			return err
+		}
+	case BulkRevokeSessions:
+		apply = func(id uuid.UUID) error { return s.RevokeUserSessions(ctx, id) }
+	case BulkDelete:
+		apply = func(id uuid.UUID) error { return s.DeleteUser(ctx, id) }
+	default:
+		return nil, invalidArgument("unknown bulk action %q", action)
+	}
+	if len(userIds) == 0 || len(userIds) > MaxPageSize {
+		return nil, invalidArgument("from 1 to %v users are required", MaxPageSize)
+	}
+
+	results := make([]types.BulkUserActionResult, 0, len(userIds))
+	for _, id := range userIds {
+		result := types.BulkUserActionResult{ID: id.String(), Status: "ok"}
+		if err := apply(id); err != nil {
+			result.Status = "error"
+			result.Error = err.Error()
+		}
+		results = append(results, result)
+	}
+	return results, nil
+}
+
+// NormalizePage - page from 1 and page size from 1 to MaxPageSize (DefaultPageSize if not set)
+func NormalizePage(page, pageSize int) (int, int) {
+	if page < 1 {
+		page = 1
+	}
+	if pageSize < 1 {
+		pageSize = DefaultPageSize
+	}
+	return page, min(pageSize, MaxPageSize)
+}
+
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(s)
+}
@@ -0,0 +1,257 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// LocalPrefix is the path objects of local storage are served under (see Local.Mount)
+const LocalPrefix = "/api/v1/storage"
+
+// tempPrefix - files of objects being written, they are never served
+const tempPrefix = ".upload-"
+
+// Local stores objects in directories of buckets under the root directory (for development and tests).
+// Objects are served by the service itself: GET is public like CDN of S3 buckets, PUT requires
+// signature of presigned request. Content type of objects is detected from their content.
+type Local struct {
+	root      string
+	publicUrl string
+	secret    []byte
+}
+
+// NewLocal creates storage in the root directory, publicUrl is the external address of LocalPrefix
+func NewLocal(root, publicUrl, secret string) (*Local, error) {
+	if err := os.MkdirAll(root, 0o755); err != nil {
+		return nil, err
+	}
+	return &Local{root: root, publicUrl: strings.TrimRight(publicUrl, "/"), secret: []byte(secret)}, nil
+}
+
+// path - file of the object, keys can't point outside of the bucket
+func (l *Local) path(bucket, key string) (string, error) {
+	if bucket == "" || strings.ContainsAny(bucket, `/\`) || bucket == "." || bucket == ".." {
+		return "", fmt.Errorf("invalid bucket %q", bucket)
+	}
+	cleaned := path.Clean("/" + key)
+	if cleaned == "/" {
+		return "", fmt.Errorf("invalid key %q", key)
+	}
+	return filepath.Join(l.root, bucket, filepath.FromSlash(cleaned)), nil
+}
+
+func (l *Local) Put(ctx context.Context, bucket, key string, reader io.Reader, size int64, options PutOptions) error {
+	file, err := l.path(bucket, key)
+	if err != nil {
+		return err
+	}
+	if err = os.MkdirAll(filepath.Dir(file), 0o755); err != nil {
+		return err
+	}
+
+	// The object is replaced atomically, readers never see partially written file
+	tmp, err := os.CreateTemp(filepath.Dir(file), tempPrefix+"*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	written, err := io.Copy(tmp, io.LimitReader(reader, size+1))
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return err
+	}
+	if written != size {
+		return fmt.Errorf("object size is %d, expected %d", written, size)
+	}
+	return os.Rename(tmp.Name(), file)
+}
+
+func (l *Local) Get(ctx context.Context, bucket, key string) (io.ReadCloser, ObjectInfo, error) {
+	file, err := l.path(bucket, key)
+	if err != nil {
+		return nil, ObjectInfo{}, err
+	}
+	reader, err := os.Open(file)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, ObjectInfo{}, ErrNotFound
+	}
+	if err != nil {
+		return nil, ObjectInfo{}, err
+	}
+	info, err := objectInfo(reader)
+	if err != nil {
+		_ = reader.Close()
+		return nil, ObjectInfo{}, err
+	}
+	return reader, info, nil
+}
+
+func (l *Local) Stat(ctx context.Context, bucket, key string) (ObjectInfo, error) {
+	reader, info, err := l.Get(ctx, bucket, key)
+	if err != nil {
+		return ObjectInfo{}, err
+	}
+	_ = reader.Close()
+	return info, nil
+}
+
+func (l *Local) Copy(ctx context.Context, bucket, srcKey, dstKey string) error {
+	reader, info, err := l.Get(ctx, bucket, srcKey)
+	if err != nil {
+		return err
+	}
+	defer reader.Close()
+	return l.Put(ctx, bucket, dstKey, reader, info.Size, PutOptions{})
+}
+
+func (l *Local) Delete(ctx context.Context, bucket, key string) error {
+	file, err := l.path(bucket, key)
+	if err != nil {
+		return err
+	}
+	if err = os.Remove(file); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+	return nil
+}
+
+func (l *Local) PresignPut(
+	ctx context.Context, bucket, key string, expires time.Duration, options PresignOptions,
+) (*PresignedRequest, error) {
+	link, err := l.presign(http.MethodPut, bucket, key, expires, options)
+	if err != nil {
+		return nil, err
+	}
+	return &PresignedRequest{Method: http.MethodPut, URL: link, Headers: presignHeaders(options)}, nil
+}
+
+func (l *Local) PresignGet(ctx context.Context, bucket, key string, expires time.Duration) (string, error) {
+	return l.presign(http.MethodGet, bucket, key, expires, PresignOptions{})
+}
+
+func (l *Local) presign(method, bucket, key string, expires time.Duration, options PresignOptions) (string, error) {
+	if _, err := l.path(bucket, key); err != nil {
+		return "", err
+	}
+	expiresAt := strconv.FormatInt(time.Now().Add(expires).Unix(), 10)
+	query := url.Values{}
+	query.Set("expires", expiresAt)
+	query.Set("signature", l.signature(method, bucket, key, expiresAt, options))
+	return l.publicUrl + "/" + url.PathEscape(bucket) + "/" + escapeKey(key) + "?" + query.Encode(), nil
+}
+
+// signature - HMAC of request parameters, so the request can't be used for other object or constraints
+func (l *Local) signature(method, bucket, key, expires string, options PresignOptions) string {
+	mac := hmac.New(sha256.New, l.secret)
+	fmt.Fprintf(mac, "%s\n%s\n%s\n%s\n%s\n%d", method, bucket, key, expires, options.ContentType, options.ContentLength)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// Mount serves objects under LocalPrefix: GET /<bucket>/<key> and presigned PUT /<bucket>/<key>
+func (l *Local) Mount(app *fiber.App) {
+	app.Get(LocalPrefix+"/:bucket/*", l.serveGet)
+	app.Put(LocalPrefix+"/:bucket/*", l.servePut)
+}
+
+// serveGet serves objects from the API origin, so only images are displayed by browsers
+// (other content is downloaded as attachment and is never sniffed)
+func (l *Local) serveGet(c *fiber.Ctx) error {
+	bucket, key, err := objectParams(c)
+	if err != nil {
+		return c.SendStatus(fiber.StatusBadRequest)
+	}
+	if strings.HasPrefix(path.Base(key), tempPrefix) {
+		return c.SendStatus(fiber.StatusNotFound)
+	}
+	reader, info, err := l.Get(c.UserContext(), bucket, key)
+	if errors.Is(err, ErrNotFound) {
+		return c.SendStatus(fiber.StatusNotFound)
+	}
+	if err != nil {
+		return c.SendStatus(fiber.StatusBadRequest)
+	}
+	c.Set(fiber.HeaderXContentTypeOptions, "nosniff")
+	if strings.HasPrefix(info.ContentType, "image/") && !strings.HasPrefix(info.ContentType, "image/svg") {
+		c.Set(fiber.HeaderContentType, info.ContentType)
+	} else {
+		c.Set(fiber.HeaderContentType, fiber.MIMEOctetStream)
+		c.Set(fiber.HeaderContentDisposition, "attachment")
+	}
+	return c.SendStream(reader, int(info.Size))
+}
+
+func (l *Local) servePut(c *fiber.Ctx) error {
+	bucket, key, err := objectParams(c)
+	if err != nil {
+		return c.SendStatus(fiber.StatusBadRequest)
+	}
+	expires := c.Query("expires")
+	expiresAt, err := strconv.ParseInt(expires, 10, 64)
+	if err != nil || time.Now().Unix() > expiresAt {
+		return c.Status(fiber.StatusForbidden).SendString("request has expired")
+	}
+
+	body := c.Body()
+	options := PresignOptions{ContentType: c.Get(fiber.HeaderContentType), ContentLength: int64(len(body))}
+	expected := l.signature(http.MethodPut, bucket, key, expires, options)
+	// Requests presigned without size constraint accept any size
+	unsized := l.signature(http.MethodPut, bucket, key, expires, PresignOptions{ContentType: options.ContentType})
+	signature := c.Query("signature")
+	if !hmac.Equal([]byte(signature), []byte(expected)) && !hmac.Equal([]byte(signature), []byte(unsized)) {
+		return c.Status(fiber.StatusForbidden).SendString("signature does not match")
+	}
+
+	if err = l.Put(c.UserContext(), bucket, key, bytes.NewReader(body), int64(len(body)), PutOptions{}); err != nil {
+		return c.Status(fiber.StatusInternalServerError).SendString(err.Error())
+	}
+	return c.SendStatus(fiber.StatusOK)
+}
+
+func objectParams(c *fiber.Ctx) (string, string, error) {
+	bucket, err := url.PathUnescape(c.Params("bucket"))
+	if err != nil {
+		return "", "", err
+	}
+	key, err := url.PathUnescape(c.Params("*"))
+	return bucket, key, err
+}
+
+func objectInfo(file *os.File) (ObjectInfo, error) {
+	stat, err := file.Stat()
+	if err != nil {
+		return ObjectInfo{}, err
+	}
+	head := make([]byte, 512)
+	n, err := file.ReadAt(head, 0)
+	if err != nil && !errors.Is(err, io.EOF) {
+		return ObjectInfo{}, err
+	}
+	return ObjectInfo{Size: stat.Size(), ContentType: http.DetectContentType(head[:n])}, nil
+}
+
+func escapeKey(key string) string {
+	parts := strings.Split(key, "/")
+	for i, part := range parts {
+		parts[i] = url.PathEscape(part)
+	}
+	return strings.Join(parts, "/")
+}
@@ -0,0 +1,102 @@
+package storage
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/G0tem/go-service-auth/internal/config"
+	"github.com/minio/minio-go/v7"
+	"github.com/minio/minio-go/v7/pkg/credentials"
+)
+
+// S3 stores objects in S3 compatible storage (MinIO, AWS S3)
+type S3 struct {
+	client *minio.Client
+}
+
+// NewS3 creates client of S3 storage (no requests are made until used)
+func NewS3(cfg *config.Config) (*S3, error) {
+	client, err := minio.New(cfg.S3Endpoint, &minio.Options{
+		Creds:        credentials.NewStaticV4(cfg.S3AccessKey, cfg.S3SecretAccessKey, ""),
+		BucketLookup: minio.BucketLookupPath,
+		Region:       cfg.S3Region,
+		Secure:       cfg.S3Secure,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &S3{client: client}, nil
+}
+
+func (s *S3) Put(ctx context.Context, bucket, key string, reader io.Reader, size int64, options PutOptions) error {
+	_, err := s.client.PutObject(ctx, bucket, key, reader, size, minio.PutObjectOptions{
+		ContentType:  options.ContentType,
+		CacheControl: options.CacheControl,
+	})
+	return err
+}
+
+func (s *S3) Get(ctx context.Context, bucket, key string) (io.ReadCloser, ObjectInfo, error) {
+	object, err := s.client.GetObject(ctx, bucket, key, minio.GetObjectOptions{})
+	if err != nil {
+		return nil, ObjectInfo{}, s3Error(err)
+	}
+	info, err := object.Stat()
+	if err != nil {
+		_ = object.Close()
+		return nil, ObjectInfo{}, s3Error(err)
+	}
+	return object, ObjectInfo{Size: info.Size, ContentType: info.ContentType}, nil
+}
+
+func (s *S3) Stat(ctx context.Context, bucket, key string) (ObjectInfo, error) {
+	info, err := s.client.StatObject(ctx, bucket, key, minio.StatObjectOptions{})
+	if err != nil {
+		return ObjectInfo{}, s3Error(err)
+	}
+	return ObjectInfo{Size: info.Size, ContentType: info.ContentType}, nil
+}
+
+func (s *S3) Copy(ctx context.Context, bucket, srcKey, dstKey string) error {
+	_, err := s.client.CopyObject(ctx,
+		minio.CopyDestOptions{Bucket: bucket, Object: dstKey},
+		minio.CopySrcOptions{Bucket: bucket, Object: srcKey},
+	)
+	return s3Error(err)
+}
+
+func (s *S3) Delete(ctx context.Context, bucket, key string) error {
+	return s.client.RemoveObject(ctx, bucket, key, minio.RemoveObjectOptions{})
+}
+
+func (s *S3) PresignPut(
+	ctx context.Context, bucket, key string, expires time.Duration, options PresignOptions,
+) (*PresignedRequest, error) {
+	headers := presignHeaders(options)
+	signed := http.Header{}
+	for name, value := range headers {
+		signed.Set(name, value)
+	}
+	link, err := s.client.PresignHeader(ctx, http.MethodPut, bucket, key, expires, nil, signed)
+	if err != nil {
+		return nil, err
+	}
+	return &PresignedRequest{Method: http.MethodPut, URL: link.String(), Headers: headers}, nil
+}
+
+func (s *S3) PresignGet(ctx context.Context, bucket, key string, expires time.Duration) (string, error) {
+	link, err := s.client.PresignedGetObject(ctx, bucket, key, expires, nil)
+	if err != nil {
+		return "", err
+	}
+	return link.String(), nil
+}
+
+func s3Error(err error) error {
+	if response := minio.ToErrorResponse(err); response.Code == "NoSuchKey" || response.StatusCode == http.StatusNotFound {
+		return ErrNotFound
+	}
+	return err
+}
@@ -0,0 +1,83 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/G0tem/go-service-auth/internal/config"
+)
+
+// Drivers of object storage (STORAGE_DRIVER)
+const (
+	DriverS3    = "s3"
+	DriverLocal = "local"
+)
+
+// ErrNotFound means the object does not exist
+var ErrNotFound = errors.New("object not found")
+
+// Storage keeps objects (avatars, covers) in buckets
+type Storage interface {
+	// Put stores the object of the given size
+	Put(ctx context.Context, bucket, key string, reader io.Reader, size int64, options PutOptions) error
+	// Get opens the object, the caller must close it
+	Get(ctx context.Context, bucket, key string) (io.ReadCloser, ObjectInfo, error)
+	// Stat returns size and content type of the object
+	Stat(ctx context.Context, bucket, key string) (ObjectInfo, error)
+	// Copy copies the object to dstKey of the same bucket (missing source is ErrNotFound)
+	Copy(ctx context.Context, bucket, srcKey, dstKey string) error
+	// Delete removes the object (missing object is not an error)
+	Delete(ctx context.Context, bucket, key string) error
+	// PresignPut returns request uploading the object directly to the storage without credentials
+	PresignPut(ctx context.Context, bucket, key string, expires time.Duration, options PresignOptions) (*PresignedRequest, error)
+	// PresignGet returns link downloading the object without credentials
+	PresignGet(ctx context.Context, bucket, key string, expires time.Duration) (string, error)
+}
+
+type PutOptions struct {
+	ContentType  string
+	CacheControl string
+}
+
+// PresignOptions are constraints of presigned upload (the request must have exactly these headers)
+type PresignOptions struct {
+	ContentType   string
+	ContentLength int64
+}
+
+// PresignedRequest is the upload request the client must send (Headers are signed)
+type PresignedRequest struct {
+	Method  string            `json:"method"`
+	URL     string            `json:"url"`
+	Headers map[string]string `json:"headers"`
+}
+
+type ObjectInfo struct {
+	Size        int64
+	ContentType string
+}
+
+// New creates storage selected by STORAGE_DRIVER (once at startup, clients are safe for concurrent use)
+func New(cfg *config.Config) (Storage, error) {
+	switch cfg.StorageDriver {
+	case DriverS3, "":
+		return NewS3(cfg)
+	case DriverLocal:
+		return NewLocal(cfg.StorageLocalRoot, cfg.StorageLocalPublicUrl, cfg.SecretKey)
+	}
+	return nil, fmt.Errorf("unknown storage driver %q", cfg.StorageDriver)
+}
+
+func presignHeaders(options PresignOptions) map[string]string {
+	headers := map[string]string{}
+	if options.ContentType != "" {
+		headers["Content-Type"] = options.ContentType
+	}
+	if options.ContentLength > 0 {
+		headers["Content-Length"] = fmt.Sprint(options.ContentLength)
+	}
+	return headers
+}
@@ -0,0 +1,18 @@
+package types
+
+// EmailChangeRequest requests change of the current user email (confirmation link is sent to NewEmail)
+type EmailChangeRequest struct {
+	NewEmail string `json:"new_email"`
+	// Password is the current password of the user
+	Password string `json:"password"`
+}
+
+// EmailChangeConfirmRequest confirms email change with the token from the link
+type EmailChangeConfirmRequest struct {
+	Token string `json:"token"`
+}
+
+// UsernameChangeRequest changes username of the current user
+type UsernameChangeRequest struct {
+	Username string `json:"username"`
+}
@@ -1,5 +1,59 @@
 package types
 
+import "time"
+
 type FileUploadResponse struct {
 	Url string `json:"url"`
 }
+
+// AvatarResponse represents avatar of the current user
+type AvatarResponse struct {
+	Status string     `json:"status"`
+	Data   AvatarData `json:"data"`
+}
+
+type AvatarData struct {
+	// AvatarURL is CDN link of the default variant (empty if the user has no avatar)
+	AvatarURL string `json:"avatar_url"`
+	// Variants are CDN links of all variants by their names (large, medium, small)
+	Variants map[string]string `json:"variants"`
+}
+
+// CoverUploadRequest requests presigned upload of the cover
+type CoverUploadRequest struct {
+	// ContentType is image/jpeg, image/png or image/webp
+	ContentType string `json:"content_type"`
+	// Size of the file in bytes
+	Size int64 `json:"size"`
+}
+
+type CoverUploadResponse struct {
+	Status string          `json:"status"`
+	Data   CoverUploadData `json:"data"`
+}
+
+// CoverUploadData is the request uploading the cover directly to the storage
+type CoverUploadData struct {
+	// Key identifies the upload in completion request
+	Key    string `json:"key"`
+	Method string `json:"method"`
+	URL    string `json:"url"`
+	// Headers must be sent with exactly these values
+	Headers   map[string]string `json:"headers"`
+	ExpiresAt time.Time         `json:"expires_at"`
+}
+
+// CoverCompleteRequest attaches uploaded cover to the profile
+type CoverCompleteRequest struct {
+	Key string `json:"key"`
+}
+
+type CoverResponse struct {
+	Status string    `json:"status"`
+	Data   CoverData `json:"data"`
+}
+
+type CoverData struct {
+	// CoverURL is public link of the cover (empty if the user has no cover)
+	CoverURL string `json:"cover_url"`
+}
@@ -1,12 +1,24 @@
 package types
 
+// Auth modes of clients
+const (
+	// AuthModeToken - token is returned in response body and sent in Authorization header (default)
+	AuthModeToken = "token"
+	// AuthModeCookie - token is set in HttpOnly cookie, state-changing requests require CSRF token
+	AuthModeCookie = "cookie"
+)
+
 type LoginSuccessData struct {
-	Token string `json:"token"`
+	Token string `json:"token,omitempty"`
+	// CsrfToken must be sent in X-CSRF-Token header of state-changing requests in cookie mode
+	CsrfToken string `json:"csrf_token,omitempty"`
 }
 
 type LoginRequest struct {
 	Identity string `json:"identity"`
 	Password string `json:"password"`
+	// Mode is "token" (by default) or "cookie"
+	Mode string `json:"mode"`
 }
 type LoginSuccessResponse struct {
 	Status string           `json:"status"`
@@ -0,0 +1,8 @@
+package types
+
+// MailMessage is the message published to the mail exchange, the mail service renders Template with Data
+type MailMessage struct {
+	To       string            `json:"to"`
+	Template string            `json:"template"`
+	Data     map[string]string `json:"data"`
+}
@@ -1,7 +1,42 @@
 package types
 
+import "time"
+
 // GetMeResponse represents response for get-me endpoint
 type GetMeResponse struct {
-	ID    string `json:"id"`
-	Email string `json:"email"`
-} 
\ No newline at end of file
+	ID             string         `json:"id"`
+	Email          string         `json:"email"`
+	Username       string         `json:"username"`
+	EmailConfirmed bool           `json:"email_confirmed"`
+	IsActive       bool           `json:"is_active"`
+	AvatarURL      string         `json:"avatar_url"`
+	CoverURL       string         `json:"cover_url"`
+	DisplayName    string         `json:"display_name"`
+	Locale         string         `json:"locale"`
+	Timezone       string         `json:"timezone"`
+	Attributes     map[string]any `json:"attributes"`
+	// Role is the primary role, Roles are all assigned roles
+	Role  string   `json:"role"`
+	Roles []string `json:"roles"`
+	// Permissions are effective "model:action" permissions, denied ones are prefixed with "!"
+	Permissions []string  `json:"permissions"`
+	Mfa         MfaStatus `json:"mfa"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+// MfaStatus represents multi-factor authentication state of the user
+type MfaStatus struct {
+	Enabled bool `json:"enabled"`
+}
+
+// UpdateMeRequest changes only profile fields which are set (empty string clears the field)
+type UpdateMeRequest struct {
+	DisplayName *string `json:"display_name"`
+	// Locale is BCP 47 language tag (e.g. "en-US")
+	Locale *string `json:"locale"`
+	// Timezone is IANA time zone name (e.g. "Europe/Moscow")
+	Timezone *string `json:"timezone"`
+	// Attributes replace custom attributes of the user (empty object clears them)
+	Attributes map[string]any `json:"attributes"`
+}
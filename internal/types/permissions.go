@@ -1,19 +1,14 @@
 package types
 
-import "gorm.io/datatypes"
-
-type UserPermit struct {
-	UserID  string         `json:"user_id" gorm:"column:user_id"`
-	Permits datatypes.JSON `json:"permits" gorm:"column:permits"`
-}
-
+// Permits represents roles and permissions of a user
 type Permits struct {
-	UserID     string         `json:"user_id" gorm:"column:user_id"`
-	Name       string         `json:"name" gorm:"column:name"`
-	Model      string         `json:"model" gorm:"column:model"`
-	Action     string         `json:"action" gorm:"column:action"`
-	Permits    datatypes.JSON `json:"permits" gorm:"column:permits"`
-	PermitList []string       `json:"permit_list" gorm:"column:permit_list"`
+	UserID string `json:"user_id"`
+	// Roles assigned to the user
+	Roles []string `json:"roles"`
+	// Permissions granted to the user directly (model:action, denied ones are prefixed with "!")
+	Permissions []string `json:"permissions"`
+	// PermitList is the effective list of permissions (union of role and direct permissions, denied ones are prefixed with "!")
+	PermitList []string `json:"permit_list"`
 }
 
 // AddRolePermission represents a model used to store RolePermission
@@ -21,6 +16,7 @@ type AddRolePermission struct {
 	Role             string
 	PermissionModel  string
 	PermissionAction string
+	Deny             bool
 }
 
 type AcceptRoleResponse struct {
@@ -0,0 +1,159 @@
+package types
+
+import "time"
+
+const (
+	PermissionSourceRole   = "role"
+	PermissionSourceDirect = "direct"
+)
+
+// RoleResponse represents role with its parent
+type RoleResponse struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	Parent      string `json:"parent"`
+}
+
+type RolesResponse struct {
+	Status string         `json:"status"`
+	Data   []RoleResponse `json:"data"`
+}
+
+type SetRoleParentRequest struct {
+	// Parent role name, empty string removes parent
+	Parent string `json:"parent"`
+}
+
+// PermissionSource describes how a permission was obtained
+type PermissionSource struct {
+	// Kind is "role" or "direct"
+	Kind string `json:"kind" enums:"role,direct"`
+	// Role which has the permission linked
+	Role string `json:"role,omitempty"`
+	// Via is the inheritance path from assigned role to Role
+	Via []string `json:"via,omitempty"`
+	// Deny is true for explicitly denied permission
+	Deny bool `json:"deny"`
+}
+
+// PermissionExplanation describes effective permission and all its sources
+type PermissionExplanation struct {
+	Permission string             `json:"permission"`
+	Sources    []PermissionSource `json:"sources"`
+}
+
+type ExplainPermissionsResponse struct {
+	Status string                  `json:"status"`
+	Data   []PermissionExplanation `json:"data"`
+}
+
+type CheckPermissionResponse struct {
+	Status string `json:"status"`
+	// Allowed is the check result
+	Allowed bool `json:"allowed"`
+	// MatchedBy is the user permission pattern decided the result (empty if nothing matched)
+	MatchedBy string `json:"matched_by"`
+}
+
+// Resource identifies an object permissions can be scoped to
+type Resource struct {
+	Type string `json:"type" query:"resource_type"`
+	// ID is exact id, "*" (any resource of the type) or prefix pattern like "team-a/*" (patterns are for grants only)
+	ID string `json:"id" query:"resource_id"`
+}
+
+// ScopedGrant represents role or permission granted to user for resources
+type ScopedGrant struct {
+	Role       string   `json:"role,omitempty"`
+	Permission string   `json:"permission,omitempty"`
+	Resource   Resource `json:"resource"`
+	Deny       bool     `json:"deny"`
+}
+
+type ScopedGrantsResponse struct {
+	Status string        `json:"status"`
+	Data   []ScopedGrant `json:"data"`
+}
+
+// UserGrantRequest grants (revokes) either role or permission (model:action) to user,
+// globally or for resources when resource is set
+type UserGrantRequest struct {
+	Role       string    `json:"role"`
+	Permission string    `json:"permission"`
+	Resource   *Resource `json:"resource"`
+	Deny       bool      `json:"deny"`
+}
+
+type CanResponse struct {
+	Status  string `json:"status"`
+	Allowed bool   `json:"allowed"`
+}
+
+// RbacPolicy is a declarative description of roles, their permissions and inheritance
+type RbacPolicy struct {
+	// Version of the policy, should be increased on every change
+	Version     int              `json:"version" yaml:"version"`
+	Description string           `json:"description,omitempty" yaml:"description,omitempty"`
+	Roles       []RbacPolicyRole `json:"roles" yaml:"roles"`
+}
+
+type RbacPolicyRole struct {
+	Name        string `json:"name" yaml:"name"`
+	Description string `json:"description,omitempty" yaml:"description,omitempty"`
+	// Parent role which permissions are inherited
+	Parent string `json:"parent,omitempty" yaml:"parent,omitempty"`
+	// Permissions in model:action format, "*" matches any model or action, denied ones are prefixed with "!"
+	Permissions []string `json:"permissions" yaml:"permissions"`
+}
+
+// RbacPolicyInfo describes policy applied to the database
+type RbacPolicyInfo struct {
+	// Source is the policy file path or "default" for built-in policy
+	Source string `json:"source"`
+	// Hash is sha256 of the normalized policy
+	Hash      string    `json:"hash"`
+	SyncMode  string    `json:"sync_mode" enums:"add_missed_only,delete_links,delete_outdated"`
+	AppliedAt time.Time `json:"applied_at"`
+	// Skipped is true if the policy had been already applied and nothing was changed
+	Skipped bool       `json:"skipped"`
+	Policy  RbacPolicy `json:"policy"`
+}
+
+type RbacPolicyResponse struct {
+	Status string         `json:"status"`
+	Data   RbacPolicyInfo `json:"data"`
+}
+
+// RbacPlanLink is a link between role and permission in the sync plan
+type RbacPlanLink struct {
+	Role string `json:"role"`
+	// Permission in model:action format
+	Permission string `json:"permission"`
+	Deny       bool   `json:"deny"`
+}
+
+// RbacPlanRoleChange is a change of role attribute in the sync plan
+type RbacPlanRoleChange struct {
+	Role  string `json:"role"`
+	Field string `json:"field" enums:"description,parent"`
+	From  string `json:"from"`
+	To    string `json:"to"`
+}
+
+// RbacSyncPlan is a full set of changes synchronization of roles and permissions is going to make
+type RbacSyncPlan struct {
+	SyncMode          string               `json:"sync_mode" enums:"add_missed_only,delete_links,delete_outdated"`
+	AddRoles          []string             `json:"add_roles"`
+	AddPermissions    []string             `json:"add_permissions"`
+	AddLinks          []RbacPlanLink       `json:"add_links"`
+	UpdateLinks       []RbacPlanLink       `json:"update_links"`
+	UpdateRoles       []RbacPlanRoleChange `json:"update_roles"`
+	DeleteLinks       []RbacPlanLink       `json:"delete_links"`
+	DeletePermissions []string             `json:"delete_permissions"`
+	DeleteRoles       []string             `json:"delete_roles"`
+}
+
+type RbacSyncPlanResponse struct {
+	Status string       `json:"status"`
+	Data   RbacSyncPlan `json:"data"`
+}
@@ -5,4 +5,6 @@ type RegisterRequest struct {
 	Username        string `json:"username"`
 	Password        string `json:"password"`
 	ConfirmPassword string `json:"confirmPassword"`
+	// Mode is "token" (by default) or "cookie"
+	Mode string `json:"mode"`
 }
@@ -0,0 +1,22 @@
+package types
+
+import "time"
+
+// SessionResponse represents login session of the user
+type SessionResponse struct {
+	ID         string    `json:"id"`
+	UserAgent  string    `json:"user_agent"`
+	IP         string    `json:"ip"`
+	MfaLevel   string    `json:"mfa_level"`
+	CreatedAt  time.Time `json:"created_at"`
+	LastSeenAt time.Time `json:"last_seen_at"`
+	ExpiresAt  time.Time `json:"expires_at"`
+	// Current is set for the session of the token the request is made with
+	Current bool `json:"current"`
+}
+
+// SessionListResponse represents response with active sessions of the user
+type SessionListResponse struct {
+	Status string            `json:"status"`
+	Data   []SessionResponse `json:"data"`
+}
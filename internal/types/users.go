@@ -1,5 +1,7 @@
 package types
 
+import "time"
+
 type PasswordChangeRequest struct {
 	OldPassword        string `json:"old_password"`
 	NewPassword        string `json:"new_password"`
@@ -10,3 +12,96 @@ type UpdateUserRolesRequest struct {
 	UserId string   `json:"user_id"`
 	Roles  []string `json:"roles"`
 }
+
+// CreateUserRequest creates user on behalf of admin
+type CreateUserRequest struct {
+	Username string `json:"username"`
+	Email    string `json:"email"`
+	Password string `json:"password"`
+	// Roles assigned to the user (default role if empty), the first one is primary
+	Roles          []string `json:"roles"`
+	EmailConfirmed bool     `json:"email_confirmed"`
+}
+
+// UpdateUserRequest changes only fields which are set
+type UpdateUserRequest struct {
+	Username       *string `json:"username"`
+	Email          *string `json:"email"`
+	Password       *string `json:"password"`
+	IsActive       *bool   `json:"is_active"`
+	EmailConfirmed *bool   `json:"email_confirmed"`
+}
+
+// UserListRequest selects page of users
+type UserListRequest struct {
+	PaginationRequest
+	// Search matches part of username or email (case insensitive)
+	Search string `query:"search" json:"search"`
+	// Sort fields "+field,-field" (username, email, created_at, updated_at, is_active, email_confirmed)
+	Sort string `query:"sort" json:"sort"`
+	// Role assigned to users
+	Role           string `query:"role" json:"role"`
+	IsActive       *bool  `query:"is_active" json:"is_active"`
+	EmailConfirmed *bool  `query:"email_confirmed" json:"email_confirmed"`
+	// CreatedFrom and CreatedTo bound creation time (RFC3339, inclusive)
+	CreatedFrom string `query:"created_from" json:"created_from"`
+	CreatedTo   string `query:"created_to" json:"created_to"`
+}
+
+// UserFilter selects users, empty fields match any user
+type UserFilter struct {
+	Search         string
+	Role           string
+	IsActive       *bool
+	EmailConfirmed *bool
+	CreatedFrom    *time.Time
+	CreatedTo      *time.Time
+}
+
+// SortField orders list by the field
+type SortField struct {
+	Field string
+	Desc  bool
+}
+
+type UserListResponse struct {
+	Status     string             `json:"status"`
+	Data       []UserResponse     `json:"data"`
+	Pagination PaginationResponse `json:"pagination"`
+}
+
+type UserDetailResponse struct {
+	Status string       `json:"status"`
+	Data   UserResponse `json:"data"`
+}
+
+// BulkUserActionRequest applies action to every user
+type BulkUserActionRequest struct {
+	IDs    []string `json:"ids"`
+	Action string   `json:"action" enums:"activate,deactivate,confirm_email,revoke_sessions,delete"`
+}
+
+// BulkUserActionResult is the result of the action for one user
+type BulkUserActionResult struct {
+	ID     string `json:"id"`
+	Status string `json:"status" enums:"ok,error"`
+	Error  string `json:"error,omitempty"`
+}
+
+type BulkUserActionResponse struct {
+	Status string                 `json:"status"`
+	Data   []BulkUserActionResult `json:"data"`
+}
+
+// UserResponse represents user for admins
+type UserResponse struct {
+	ID             string    `json:"id"`
+	Username       string    `json:"username"`
+	Email          string    `json:"email"`
+	EmailConfirmed bool      `json:"email_confirmed"`
+	IsActive       bool      `json:"is_active"`
+	Role           string    `json:"role"`
+	AvatarURL      string    `json:"avatar_url"`
+	CreatedAt      time.Time `json:"created_at"`
+	UpdatedAt      time.Time `json:"updated_at"`
+}
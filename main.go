@@ -2,21 +2,31 @@ package main
 
 import (
 	"context"
+	"encoding/json"
+	"flag"
 	"fmt"
 	"os"
+	"os/signal"
+	"syscall"
 
 	_ "github.com/G0tem/go-service-auth/docs" // swagger docs
+	"github.com/G0tem/go-service-auth/internal/abac"
 	"github.com/G0tem/go-service-auth/internal/config"
+	"github.com/G0tem/go-service-auth/internal/cors"
 	"github.com/G0tem/go-service-auth/internal/database"
+	"github.com/G0tem/go-service-auth/internal/events"
+	"github.com/G0tem/go-service-auth/internal/gateway"
 	grpcServer "github.com/G0tem/go-service-auth/internal/grpc"
 	"github.com/G0tem/go-service-auth/internal/handler"
-	"github.com/G0tem/go-service-auth/internal/handler/rbac"
-	"github.com/G0tem/go-service-auth/internal/model"
+	rbacLayer "github.com/G0tem/go-service-auth/internal/handler/rbac"
+	"github.com/G0tem/go-service-auth/internal/queue"
 	"github.com/G0tem/go-service-auth/internal/router"
+	"github.com/G0tem/go-service-auth/internal/service"
+	"github.com/G0tem/go-service-auth/internal/storage"
+	"github.com/go-redis/redis/v8"
 	"github.com/gofiber/contrib/fiberzerolog"
 	"github.com/gofiber/contrib/swagger"
 	"github.com/gofiber/fiber/v2"
-	"github.com/gofiber/fiber/v2/middleware/cors"
 	_ "github.com/lib/pq"
 	"github.com/rs/zerolog"
 	"github.com/rs/zerolog/log"
@@ -34,6 +44,10 @@ import (
 
 // @BasePath /api/v1
 func main() {
+	rbacPlan := flag.Bool("rbac-plan", false, "print changes RBAC policy synchronization is going to make and exit")
+	rbacPlanFormat := flag.String("rbac-plan-format", rbacLayer.PlanFormatText, "format of -rbac-plan output: text or json")
+	flag.Parse()
+
 	// Initialize Zerolog logger with output to stdout
 	zerolog.TimeFieldFormat = zerolog.TimeFormatUnix
 	log.Logger = log.Output(zerolog.ConsoleWriter{Out: os.Stderr})
@@ -43,7 +57,7 @@ func main() {
 
 	db, err := database.Connect(cfg)
 	if err != nil {
-		return
+		os.Exit(1)
 	}
 
 	app := fiber.New(fiber.Config{
@@ -53,10 +67,15 @@ func main() {
 
 	swaggerCfg := swagger.Config{
 		BasePath: "/api/v1",
-		FilePath: "./docs/swagger.yaml",
+		FilePath: "./docs/swagger.json",
 		Path:     "docs",
 		CacheAge: 1,
 	}
+	// REST gateway endpoints are documented by the document generated from protos
+	swaggerCfg.FileContent, err = gateway.MergeOpenAPIFiles(swaggerCfg.FilePath, "./docs/gateway.swagger.json")
+	if err != nil {
+		log.Warn().Msgf("Gateway OpenAPI document is not merged: %v", err)
+	}
 
 	logger := zerolog.New(os.Stderr).With().Timestamp().Logger()
 
@@ -64,61 +83,174 @@ func main() {
 	app.Use(fiberzerolog.New(fiberzerolog.Config{
 		Logger: &logger,
 	}))
-	app.Use(func(c *fiber.Ctx) error {
-		c.Set("Access-Control-Allow-Origin", "*")
-		c.Set("Access-Control-Allow-Methods", "GET,POST,HEAD,PUT,DELETE,PATCH")
-		c.Set("Access-Control-Allow-Headers", "*")
-		c.Set("Access-Control-Expose-Headers", "*")
-
-		// Handle preflight requests
-		if c.Method() == "OPTIONS" {
-			return c.SendStatus(fiber.StatusNoContent)
-		}
+	corsPolicy, corsOverrides := cors.FromConfig(&cfg)
+	app.Use(cors.New(corsPolicy, corsOverrides...))
 
-		return c.Next()
+	redisClient := redis.NewClient(&redis.Options{
+		Addr: cfg.RedisAddr, // Адрес Redis (например, "localhost:6379")
+		DB:   cfg.RedisDB,   // Номер базы данных Redis
 	})
 
-	app.Use(cors.New(cors.Config{
-		AllowOrigins:     "*",
-		AllowHeaders:     "*",
-		AllowMethods:     "GET,POST,HEAD,PUT,DELETE,PATCH",
-		AllowCredentials: false,
-		ExposeHeaders:    "*",
-		MaxAge:           86400, // 24 часов в секундах
-	}))
+	syncAction, err := rbacLayer.ParseSyncAction(cfg.RbacSyncAction)
+	if err != nil {
+		log.Error().Msgf("Setup roles error: %v", err)
+		os.Exit(1)
+	}
+
+	// Background jobs (cache invalidations, health checks) stop on shutdown
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
 
-	rbac := &rbac.RBACLayer{
-		DB:  db,
-		Ctx: context.Background(),
+	rbac := &rbacLayer.RBACLayer{
+		DB:          db,
+		Ctx:         ctx,
+		PolicyFile:  cfg.RbacPolicyFile,
+		SyncAction:  syncAction,
+		LockTimeout: cfg.DbLockTimeout,
+		Events:      events.NewStream(redisClient, cfg.UserEventsMaxLen),
 	}
-	err = rbac.InitSafety(map[string]string{
-		model.AdminRole:       "admin:all",
-		model.DefaultUserRole: "user:read",
-	})
+	if cfg.RbacCacheTTL > 0 {
+		rbac.Cache = rbacLayer.NewPermissionCache(rbac.Ctx, redisClient, cfg.RbacCacheTTL)
+		// Invalidations made by other replicas
+		go rbac.Cache.Listen(rbac.Ctx)
+	}
+
+	// Preview changes of RBAC policy synchronization and exit
+	if *rbacPlan {
+		plan, err := rbac.PlanLoadPolicy(syncAction)
+		if err != nil {
+			log.Error().Msgf("Plan roles error: %v", err)
+			os.Exit(1)
+		}
+		if *rbacPlanFormat == rbacLayer.PlanFormatJSON {
+			err = json.NewEncoder(os.Stdout).Encode(plan)
+		} else {
+			_, err = fmt.Fprint(os.Stdout, rbacLayer.FormatPlan(plan))
+		}
+		if err != nil {
+			log.Error().Msgf("Plan roles error: %v", err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	policy, err := rbac.LoadPolicy(false)
 	if err != nil {
 		log.Error().Msgf("Setup roles error: %v", err)
-		return
+		os.Exit(1)
+	}
+	log.Info().Msgf("RBAC policy %v (version %v) is up to date", policy.Source, policy.Policy.Version)
+
+	// Reload RBAC policy on SIGHUP
+	go func() {
+		sighup := make(chan os.Signal, 1)
+		signal.Notify(sighup, syscall.SIGHUP)
+		for range sighup {
+			policy, err := rbac.LoadPolicy(false)
+			if err != nil {
+				log.Error().Msgf("Reload RBAC policy error: %v", err)
+				continue
+			}
+			log.Info().Msgf("RBAC policy %v (version %v) reloaded", policy.Source, policy.Policy.Version)
+		}
+	}()
+
+	abacEngine, err := abac.NewEngine(abac.Policy{})
+	if cfg.AbacPolicyFile != "" {
+		abacEngine, err = abac.LoadPolicyFile(cfg.AbacPolicyFile)
 	}
+	if err != nil {
+		log.Error().Msgf("Setup abac policy error: %v", err)
+		os.Exit(1)
+	}
+	abacEngine.LogDecisions = cfg.AbacLogDecisions
 
-	handlers := handler.NewHandler(db, rbac, &cfg)
+	mailer := queue.NewMailPublisher(cfg.RMQConnUrl, cfg.RMQMailExchange, cfg.RMQMailExchangeAutocreate)
+	store, err := storage.New(&cfg)
+	if err != nil {
+		log.Error().Msgf("Storage error: %v", err)
+		os.Exit(1)
+	}
+	if local, ok := store.(*storage.Local); ok {
+		local.Mount(app)
+	}
+	svc := service.New(db, rbac, redisClient, mailer, store, &cfg)
+	handlers := handler.NewHandler(db, rbac, abacEngine, svc, redisClient, &cfg)
+	// Staging objects of cover uploads which were not completed
+	go svc.RunCoverUploadCleanup(ctx, cfg.CoverUploadTTL)
 
 	router.SetupRoutes(app)
 	handlers.SetupRoutes(app)
 
+	grpc, err := grpcServer.NewServer(db, redisClient, &cfg, rbac, abacEngine, svc)
+	if err != nil {
+		log.Error().Msgf("gRPC server error: %v", err)
+		os.Exit(1)
+	}
+	restGateway, err := gateway.New(ctx, &cfg)
+	if err != nil {
+		log.Error().Msgf("REST gateway error: %v", err)
+		os.Exit(1)
+	}
+	restGateway.Mount(app, handlers.GatewayGuards()...)
+
 	app.Use(func(c *fiber.Ctx) error {
 		return c.SendStatus(404) // => 404 "Not Found"
 	})
+	go grpc.Health.Run(ctx)
 
-	// Запускаем gRPC сервер в отдельной горутине
+	// HTTP and gRPC servers run until SIGINT/SIGTERM or until one of them fails
+	serverErrors := make(chan error, 2)
 	go func() {
-		if err := grpcServer.StartGrpcServer(&cfg); err != nil {
-			log.Error().Msgf("gRPC server error: %v", err)
+		if err := app.Listen(fmt.Sprintf(":%v", cfg.HttpPort)); err != nil {
+			serverErrors <- fmt.Errorf("http server: %w", err)
+		}
+	}()
+	go func() {
+		if err := grpc.Serve(); err != nil {
+			serverErrors <- fmt.Errorf("gRPC server: %w", err)
 		}
 	}()
 
-	err = app.Listen(fmt.Sprintf(":%v", cfg.HttpPort))
-	if err != nil {
+	shutdown := make(chan os.Signal, 1)
+	signal.Notify(shutdown, syscall.SIGINT, syscall.SIGTERM)
+
+	exitCode := 0
+	select {
+	case sig := <-shutdown:
+		log.Info().Msgf("Received %v, shutting down", sig)
+	case err := <-serverErrors:
 		log.Error().Msgf("Unexpected error: %v", err)
-		return
+		exitCode = 1
+	}
+
+	shutdownCtx, shutdownCancel := context.WithTimeout(context.Background(), cfg.ShutdownTimeout)
+	defer shutdownCancel()
+
+	grpc.Shutdown(shutdownCtx)
+	if err := app.ShutdownWithContext(shutdownCtx); err != nil {
+		log.Error().Msgf("HTTP server shutdown error: %v", err)
+		exitCode = 1
+	}
+	if err := restGateway.Close(); err != nil {
+		log.Error().Msgf("REST gateway close error: %v", err)
+	}
+	cancel()
+
+	if err := mailer.Close(); err != nil {
+		log.Error().Msgf("Mail queue close error: %v", err)
+	}
+	if err := redisClient.Close(); err != nil {
+		log.Error().Msgf("Redis close error: %v", err)
+	}
+	if sqlDB, err := db.DB(); err == nil {
+		if err := sqlDB.Close(); err != nil {
+			log.Error().Msgf("Database close error: %v", err)
+		}
+	}
+
+	log.Info().Msg("Stopped")
+	if exitCode != 0 {
+		os.Exit(exitCode)
 	}
 }
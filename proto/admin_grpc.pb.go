@@ -0,0 +1,771 @@
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.6.0
+// - protoc             v3.21.12
+// source: proto/admin.proto
+
+package proto
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.64.0 or later.
+const _ = grpc.SupportPackageIsVersion9
+
+const (
+	AuthAdminService_ListUsers_FullMethodName            = "/auth.AuthAdminService/ListUsers"
+	AuthAdminService_GetUser_FullMethodName              = "/auth.AuthAdminService/GetUser"
+	AuthAdminService_CreateUser_FullMethodName           = "/auth.AuthAdminService/CreateUser"
+	AuthAdminService_UpdateUser_FullMethodName           = "/auth.AuthAdminService/UpdateUser"
+	AuthAdminService_DeleteUser_FullMethodName           = "/auth.AuthAdminService/DeleteUser"
+	AuthAdminService_RevokeUserSessions_FullMethodName   = "/auth.AuthAdminService/RevokeUserSessions"
+	AuthAdminService_ListRoles_FullMethodName            = "/auth.AuthAdminService/ListRoles"
+	AuthAdminService_CreateRole_FullMethodName           = "/auth.AuthAdminService/CreateRole"
+	AuthAdminService_DeleteRole_FullMethodName           = "/auth.AuthAdminService/DeleteRole"
+	AuthAdminService_SetRoleParent_FullMethodName        = "/auth.AuthAdminService/SetRoleParent"
+	AuthAdminService_ListPermissions_FullMethodName      = "/auth.AuthAdminService/ListPermissions"
+	AuthAdminService_CreatePermission_FullMethodName     = "/auth.AuthAdminService/CreatePermission"
+	AuthAdminService_DeletePermission_FullMethodName     = "/auth.AuthAdminService/DeletePermission"
+	AuthAdminService_AddRolePermission_FullMethodName    = "/auth.AuthAdminService/AddRolePermission"
+	AuthAdminService_RemoveRolePermission_FullMethodName = "/auth.AuthAdminService/RemoveRolePermission"
+	AuthAdminService_GrantUser_FullMethodName            = "/auth.AuthAdminService/GrantUser"
+	AuthAdminService_RevokeUser_FullMethodName           = "/auth.AuthAdminService/RevokeUser"
+)
+
+// AuthAdminServiceClient is the client API for AuthAdminService service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+//
+// AuthAdminService предоставляет методы администрирования пользователей, ролей и прав
+// (использует тот же сервисный слой, что и HTTP API)
+type AuthAdminServiceClient interface {
+	// ListUsers возвращает страницу пользователей (поиск по имени пользователя или email)
+	ListUsers(ctx context.Context, in *ListUsersRequest, opts ...grpc.CallOption) (*ListUsersResponse, error)
+	// GetUser возвращает пользователя по ID
+	GetUser(ctx context.Context, in *GetUserRequest, opts ...grpc.CallOption) (*AdminUser, error)
+	// CreateUser создает пользователя с указанными ролями (роль по умолчанию, если роли не указаны)
+	CreateUser(ctx context.Context, in *CreateUserRequest, opts ...grpc.CallOption) (*AdminUser, error)
+	// UpdateUser изменяет указанные поля пользователя
+	// (деактивация и смена пароля отзывают сессии пользователя)
+	UpdateUser(ctx context.Context, in *UpdateUserRequest, opts ...grpc.CallOption) (*AdminUser, error)
+	// DeleteUser удаляет пользователя
+	DeleteUser(ctx context.Context, in *GetUserRequest, opts ...grpc.CallOption) (*AdminEmpty, error)
+	// RevokeUserSessions отзывает все выданные пользователю токены
+	RevokeUserSessions(ctx context.Context, in *GetUserRequest, opts ...grpc.CallOption) (*AdminEmpty, error)
+	// ListRoles возвращает все роли
+	ListRoles(ctx context.Context, in *AdminEmpty, opts ...grpc.CallOption) (*ListRolesResponse, error)
+	// CreateRole создает роль (существующая роль возвращается без изменений)
+	CreateRole(ctx context.Context, in *CreateRoleRequest, opts ...grpc.CallOption) (*Role, error)
+	// DeleteRole удаляет роль
+	DeleteRole(ctx context.Context, in *RoleRequest, opts ...grpc.CallOption) (*AdminEmpty, error)
+	// SetRoleParent задает родительскую роль (пустой parent отвязывает роль от родителя)
+	SetRoleParent(ctx context.Context, in *SetRoleParentRequest, opts ...grpc.CallOption) (*Role, error)
+	// ListPermissions возвращает все права
+	ListPermissions(ctx context.Context, in *AdminEmpty, opts ...grpc.CallOption) (*ListPermissionsResponse, error)
+	// CreatePermission создает право (model:action)
+	CreatePermission(ctx context.Context, in *PermissionRequest, opts ...grpc.CallOption) (*Permission, error)
+	// DeletePermission удаляет право
+	DeletePermission(ctx context.Context, in *PermissionRequest, opts ...grpc.CallOption) (*AdminEmpty, error)
+	// AddRolePermission разрешает (или запрещает) право роли
+	AddRolePermission(ctx context.Context, in *RolePermissionRequest, opts ...grpc.CallOption) (*AdminEmpty, error)
+	// RemoveRolePermission убирает право из роли
+	RemoveRolePermission(ctx context.Context, in *RolePermissionRequest, opts ...grpc.CallOption) (*AdminEmpty, error)
+	// GrantUser выдает пользователю роль или право глобально или для ресурса
+	GrantUser(ctx context.Context, in *UserGrantRequest, opts ...grpc.CallOption) (*AdminEmpty, error)
+	// RevokeUser отзывает у пользователя роль или право глобально или для ресурса
+	RevokeUser(ctx context.Context, in *UserGrantRequest, opts ...grpc.CallOption) (*AdminEmpty, error)
+}
+
+type authAdminServiceClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewAuthAdminServiceClient(cc grpc.ClientConnInterface) AuthAdminServiceClient {
+	return &authAdminServiceClient{cc}
+}
+
+func (c *authAdminServiceClient) ListUsers(ctx context.Context, in *ListUsersRequest, opts ...grpc.CallOption) (*ListUsersResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ListUsersResponse)
+	err := c.cc.Invoke(ctx, AuthAdminService_ListUsers_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *authAdminServiceClient) GetUser(ctx context.Context, in *GetUserRequest, opts ...grpc.CallOption) (*AdminUser, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(AdminUser)
+	err := c.cc.Invoke(ctx, AuthAdminService_GetUser_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *authAdminServiceClient) CreateUser(ctx context.Context, in *CreateUserRequest, opts ...grpc.CallOption) (*AdminUser, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(AdminUser)
+	err := c.cc.Invoke(ctx, AuthAdminService_CreateUser_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *authAdminServiceClient) UpdateUser(ctx context.Context, in *UpdateUserRequest, opts ...grpc.CallOption) (*AdminUser, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(AdminUser)
+	err := c.cc.Invoke(ctx, AuthAdminService_UpdateUser_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *authAdminServiceClient) DeleteUser(ctx context.Context, in *GetUserRequest, opts ...grpc.CallOption) (*AdminEmpty, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(AdminEmpty)
+	err := c.cc.Invoke(ctx, AuthAdminService_DeleteUser_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *authAdminServiceClient) RevokeUserSessions(ctx context.Context, in *GetUserRequest, opts ...grpc.CallOption) (*AdminEmpty, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(AdminEmpty)
+	err := c.cc.Invoke(ctx, AuthAdminService_RevokeUserSessions_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *authAdminServiceClient) ListRoles(ctx context.Context, in *AdminEmpty, opts ...grpc.CallOption) (*ListRolesResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ListRolesResponse)
+	err := c.cc.Invoke(ctx, AuthAdminService_ListRoles_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *authAdminServiceClient) CreateRole(ctx context.Context, in *CreateRoleRequest, opts ...grpc.CallOption) (*Role, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Role)
+	err := c.cc.Invoke(ctx, AuthAdminService_CreateRole_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *authAdminServiceClient) DeleteRole(ctx context.Context, in *RoleRequest, opts ...grpc.CallOption) (*AdminEmpty, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(AdminEmpty)
+	err := c.cc.Invoke(ctx, AuthAdminService_DeleteRole_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *authAdminServiceClient) SetRoleParent(ctx context.Context, in *SetRoleParentRequest, opts ...grpc.CallOption) (*Role, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Role)
+	err := c.cc.Invoke(ctx, AuthAdminService_SetRoleParent_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *authAdminServiceClient) ListPermissions(ctx context.Context, in *AdminEmpty, opts ...grpc.CallOption) (*ListPermissionsResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ListPermissionsResponse)
+	err := c.cc.Invoke(ctx, AuthAdminService_ListPermissions_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *authAdminServiceClient) CreatePermission(ctx context.Context, in *PermissionRequest, opts ...grpc.CallOption) (*Permission, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Permission)
+	err := c.cc.Invoke(ctx, AuthAdminService_CreatePermission_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *authAdminServiceClient) DeletePermission(ctx context.Context, in *PermissionRequest, opts ...grpc.CallOption) (*AdminEmpty, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(AdminEmpty)
+	err := c.cc.Invoke(ctx, AuthAdminService_DeletePermission_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *authAdminServiceClient) AddRolePermission(ctx context.Context, in *RolePermissionRequest, opts ...grpc.CallOption) (*AdminEmpty, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(AdminEmpty)
+	err := c.cc.Invoke(ctx, AuthAdminService_AddRolePermission_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *authAdminServiceClient) RemoveRolePermission(ctx context.Context, in *RolePermissionRequest, opts ...grpc.CallOption) (*AdminEmpty, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(AdminEmpty)
+	err := c.cc.Invoke(ctx, AuthAdminService_RemoveRolePermission_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *authAdminServiceClient) GrantUser(ctx context.Context, in *UserGrantRequest, opts ...grpc.CallOption) (*AdminEmpty, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(AdminEmpty)
+	err := c.cc.Invoke(ctx, AuthAdminService_GrantUser_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *authAdminServiceClient) RevokeUser(ctx context.Context, in *UserGrantRequest, opts ...grpc.CallOption) (*AdminEmpty, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(AdminEmpty)
+	err := c.cc.Invoke(ctx, AuthAdminService_RevokeUser_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// AuthAdminServiceServer is the server API for AuthAdminService service.
+// All implementations must embed UnimplementedAuthAdminServiceServer
+// for forward compatibility.
+//
+// AuthAdminService предоставляет методы администрирования пользователей, ролей и прав
+// (использует тот же сервисный слой, что и HTTP API)
+type AuthAdminServiceServer interface {
+	// ListUsers возвращает страницу пользователей (поиск по имени пользователя или email)
+	ListUsers(context.Context, *ListUsersRequest) (*ListUsersResponse, error)
+	// GetUser возвращает пользователя по ID
+	GetUser(context.Context, *GetUserRequest) (*AdminUser, error)
+	// CreateUser создает пользователя с указанными ролями (роль по умолчанию, если роли не указаны)
+	CreateUser(context.Context, *CreateUserRequest) (*AdminUser, error)
+	// UpdateUser изменяет указанные поля пользователя
+	// (деактивация и смена пароля отзывают сессии пользователя)
+	UpdateUser(context.Context, *UpdateUserRequest) (*AdminUser, error)
+	// DeleteUser удаляет пользователя
+	DeleteUser(context.Context, *GetUserRequest) (*AdminEmpty, error)
+	// RevokeUserSessions отзывает все выданные пользователю токены
+	RevokeUserSessions(context.Context, *GetUserRequest) (*AdminEmpty, error)
+	// ListRoles возвращает все роли
+	ListRoles(context.Context, *AdminEmpty) (*ListRolesResponse, error)
+	// CreateRole создает роль (существующая роль возвращается без изменений)
+	CreateRole(context.Context, *CreateRoleRequest) (*Role, error)
+	// DeleteRole удаляет роль
+	DeleteRole(context.Context, *RoleRequest) (*AdminEmpty, error)
+	// SetRoleParent задает родительскую роль (пустой parent отвязывает роль от родителя)
+	SetRoleParent(context.Context, *SetRoleParentRequest) (*Role, error)
+	// ListPermissions возвращает все права
+	ListPermissions(context.Context, *AdminEmpty) (*ListPermissionsResponse, error)
+	// CreatePermission создает право (model:action)
+	CreatePermission(context.Context, *PermissionRequest) (*Permission, error)
+	// DeletePermission удаляет право
+	DeletePermission(context.Context, *PermissionRequest) (*AdminEmpty, error)
+	// AddRolePermission разрешает (или запрещает) право роли
+	AddRolePermission(context.Context, *RolePermissionRequest) (*AdminEmpty, error)
+	// RemoveRolePermission убирает право из роли
+	RemoveRolePermission(context.Context, *RolePermissionRequest) (*AdminEmpty, error)
+	// GrantUser выдает пользователю роль или право глобально или для ресурса
+	GrantUser(context.Context, *UserGrantRequest) (*AdminEmpty, error)
+	// RevokeUser отзывает у пользователя роль или право глобально или для ресурса
+	RevokeUser(context.Context, *UserGrantRequest) (*AdminEmpty, error)
+	mustEmbedUnimplementedAuthAdminServiceServer()
+}
+
+// UnimplementedAuthAdminServiceServer must be embedded to have
+// forward compatible implementations.
+//
+// NOTE: this should be embedded by value instead of pointer to avoid a nil
+// pointer dereference when methods are called.
+type UnimplementedAuthAdminServiceServer struct{}
+
+func (UnimplementedAuthAdminServiceServer) ListUsers(context.Context, *ListUsersRequest) (*ListUsersResponse, error) {
+	return nil, status.Error(codes.Unimplemented, "method ListUsers not implemented")
+}
+func (UnimplementedAuthAdminServiceServer) GetUser(context.Context, *GetUserRequest) (*AdminUser, error) {
+	return nil, status.Error(codes.Unimplemented, "method GetUser not implemented")
+}
+func (UnimplementedAuthAdminServiceServer) CreateUser(context.Context, *CreateUserRequest) (*AdminUser, error) {
+	return nil, status.Error(codes.Unimplemented, "method CreateUser not implemented")
+}
+func (UnimplementedAuthAdminServiceServer) UpdateUser(context.Context, *UpdateUserRequest) (*AdminUser, error) {
+	return nil, status.Error(codes.Unimplemented, "method UpdateUser not implemented")
+}
+func (UnimplementedAuthAdminServiceServer) DeleteUser(context.Context, *GetUserRequest) (*AdminEmpty, error) {
+	return nil, status.Error(codes.Unimplemented, "method DeleteUser not implemented")
+}
+func (UnimplementedAuthAdminServiceServer) RevokeUserSessions(context.Context, *GetUserRequest) (*AdminEmpty, error) {
+	return nil, status.Error(codes.Unimplemented, "method RevokeUserSessions not implemented")
+}
+func (UnimplementedAuthAdminServiceServer) ListRoles(context.Context, *AdminEmpty) (*ListRolesResponse, error) {
+	return nil, status.Error(codes.Unimplemented, "method ListRoles not implemented")
+}
+func (UnimplementedAuthAdminServiceServer) CreateRole(context.Context, *CreateRoleRequest) (*Role, error) {
+	return nil, status.Error(codes.Unimplemented, "method CreateRole not implemented")
+}
+func (UnimplementedAuthAdminServiceServer) DeleteRole(context.Context, *RoleRequest) (*AdminEmpty, error) {
+	return nil, status.Error(codes.Unimplemented, "method DeleteRole not implemented")
+}
+func (UnimplementedAuthAdminServiceServer) SetRoleParent(context.Context, *SetRoleParentRequest) (*Role, error) {
+	return nil, status.Error(codes.Unimplemented, "method SetRoleParent not implemented")
+}
+func (UnimplementedAuthAdminServiceServer) ListPermissions(context.Context, *AdminEmpty) (*ListPermissionsResponse, error) {
+	return nil, status.Error(codes.Unimplemented, "method ListPermissions not implemented")
+}
+func (UnimplementedAuthAdminServiceServer) CreatePermission(context.Context, *PermissionRequest) (*Permission, error) {
+	return nil, status.Error(codes.Unimplemented, "method CreatePermission not implemented")
+}
+func (UnimplementedAuthAdminServiceServer) DeletePermission(context.Context, *PermissionRequest) (*AdminEmpty, error) {
+	return nil, status.Error(codes.Unimplemented, "method DeletePermission not implemented")
+}
+func (UnimplementedAuthAdminServiceServer) AddRolePermission(context.Context, *RolePermissionRequest) (*AdminEmpty, error) {
+	return nil, status.Error(codes.Unimplemented, "method AddRolePermission not implemented")
+}
+func (UnimplementedAuthAdminServiceServer) RemoveRolePermission(context.Context, *RolePermissionRequest) (*AdminEmpty, error) {
+	return nil, status.Error(codes.Unimplemented, "method RemoveRolePermission not implemented")
+}
+func (UnimplementedAuthAdminServiceServer) GrantUser(context.Context, *UserGrantRequest) (*AdminEmpty, error) {
+	return nil, status.Error(codes.Unimplemented, "method GrantUser not implemented")
+}
+func (UnimplementedAuthAdminServiceServer) RevokeUser(context.Context, *UserGrantRequest) (*AdminEmpty, error) {
+	return nil, status.Error(codes.Unimplemented, "method RevokeUser not implemented")
+}
+func (UnimplementedAuthAdminServiceServer) mustEmbedUnimplementedAuthAdminServiceServer() {}
+func (UnimplementedAuthAdminServiceServer) testEmbeddedByValue()                          {}
+
+// UnsafeAuthAdminServiceServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to AuthAdminServiceServer will
+// result in compilation errors.
+type UnsafeAuthAdminServiceServer interface {
+	mustEmbedUnimplementedAuthAdminServiceServer()
+}
+
+func RegisterAuthAdminServiceServer(s grpc.ServiceRegistrar, srv AuthAdminServiceServer) {
+	// If the following call panics, it indicates UnimplementedAuthAdminServiceServer was
+	// embedded by pointer and is nil.  This will cause panics if an
+	// unimplemented method is ever invoked, so we test this at initialization
+	// time to prevent it from happening at runtime later due to I/O.
+	if t, ok := srv.(interface{ testEmbeddedByValue() }); ok {
+		t.testEmbeddedByValue()
+	}
+	s.RegisterService(&AuthAdminService_ServiceDesc, srv)
+}
+
+func _AuthAdminService_ListUsers_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListUsersRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AuthAdminServiceServer).ListUsers(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: AuthAdminService_ListUsers_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AuthAdminServiceServer).ListUsers(ctx, req.(*ListUsersRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _AuthAdminService_GetUser_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetUserRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AuthAdminServiceServer).GetUser(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: AuthAdminService_GetUser_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AuthAdminServiceServer).GetUser(ctx, req.(*GetUserRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _AuthAdminService_CreateUser_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CreateUserRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AuthAdminServiceServer).CreateUser(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: AuthAdminService_CreateUser_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AuthAdminServiceServer).CreateUser(ctx, req.(*CreateUserRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _AuthAdminService_UpdateUser_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(UpdateUserRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AuthAdminServiceServer).UpdateUser(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: AuthAdminService_UpdateUser_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AuthAdminServiceServer).UpdateUser(ctx, req.(*UpdateUserRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _AuthAdminService_DeleteUser_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetUserRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AuthAdminServiceServer).DeleteUser(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: AuthAdminService_DeleteUser_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AuthAdminServiceServer).DeleteUser(ctx, req.(*GetUserRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _AuthAdminService_RevokeUserSessions_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetUserRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AuthAdminServiceServer).RevokeUserSessions(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: AuthAdminService_RevokeUserSessions_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AuthAdminServiceServer).RevokeUserSessions(ctx, req.(*GetUserRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _AuthAdminService_ListRoles_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(AdminEmpty)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AuthAdminServiceServer).ListRoles(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: AuthAdminService_ListRoles_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AuthAdminServiceServer).ListRoles(ctx, req.(*AdminEmpty))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _AuthAdminService_CreateRole_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CreateRoleRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AuthAdminServiceServer).CreateRole(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: AuthAdminService_CreateRole_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AuthAdminServiceServer).CreateRole(ctx, req.(*CreateRoleRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _AuthAdminService_DeleteRole_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(RoleRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AuthAdminServiceServer).DeleteRole(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: AuthAdminService_DeleteRole_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AuthAdminServiceServer).DeleteRole(ctx, req.(*RoleRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _AuthAdminService_SetRoleParent_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SetRoleParentRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AuthAdminServiceServer).SetRoleParent(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: AuthAdminService_SetRoleParent_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AuthAdminServiceServer).SetRoleParent(ctx, req.(*SetRoleParentRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _AuthAdminService_ListPermissions_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(AdminEmpty)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AuthAdminServiceServer).ListPermissions(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: AuthAdminService_ListPermissions_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AuthAdminServiceServer).ListPermissions(ctx, req.(*AdminEmpty))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _AuthAdminService_CreatePermission_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(PermissionRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AuthAdminServiceServer).CreatePermission(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: AuthAdminService_CreatePermission_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AuthAdminServiceServer).CreatePermission(ctx, req.(*PermissionRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _AuthAdminService_DeletePermission_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(PermissionRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AuthAdminServiceServer).DeletePermission(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: AuthAdminService_DeletePermission_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AuthAdminServiceServer).DeletePermission(ctx, req.(*PermissionRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _AuthAdminService_AddRolePermission_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(RolePermissionRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AuthAdminServiceServer).AddRolePermission(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: AuthAdminService_AddRolePermission_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AuthAdminServiceServer).AddRolePermission(ctx, req.(*RolePermissionRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _AuthAdminService_RemoveRolePermission_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(RolePermissionRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AuthAdminServiceServer).RemoveRolePermission(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: AuthAdminService_RemoveRolePermission_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AuthAdminServiceServer).RemoveRolePermission(ctx, req.(*RolePermissionRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _AuthAdminService_GrantUser_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(UserGrantRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AuthAdminServiceServer).GrantUser(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: AuthAdminService_GrantUser_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AuthAdminServiceServer).GrantUser(ctx, req.(*UserGrantRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _AuthAdminService_RevokeUser_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(UserGrantRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AuthAdminServiceServer).RevokeUser(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: AuthAdminService_RevokeUser_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AuthAdminServiceServer).RevokeUser(ctx, req.(*UserGrantRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+// AuthAdminService_ServiceDesc is the grpc.ServiceDesc for AuthAdminService service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var AuthAdminService_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "auth.AuthAdminService",
+	HandlerType: (*AuthAdminServiceServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "ListUsers",
+			Handler:    _AuthAdminService_ListUsers_Handler,
+		},
+		{
+			MethodName: "GetUser",
+			Handler:    _AuthAdminService_GetUser_Handler,
+		},
+		{
+			MethodName: "CreateUser",
+			Handler:    _AuthAdminService_CreateUser_Handler,
+		},
+		{
+			MethodName: "UpdateUser",
+			Handler:    _AuthAdminService_UpdateUser_Handler,
+		},
+		{
+			MethodName: "DeleteUser",
+			Handler:    _AuthAdminService_DeleteUser_Handler,
+		},
+		{
+			MethodName: "RevokeUserSessions",
+			Handler:    _AuthAdminService_RevokeUserSessions_Handler,
+		},
+		{
+			MethodName: "ListRoles",
+			Handler:    _AuthAdminService_ListRoles_Handler,
+		},
+		{
+			MethodName: "CreateRole",
+			Handler:    _AuthAdminService_CreateRole_Handler,
+		},
+		{
+			MethodName: "DeleteRole",
+			Handler:    _AuthAdminService_DeleteRole_Handler,
+		},
+		{
+			MethodName: "SetRoleParent",
+			Handler:    _AuthAdminService_SetRoleParent_Handler,
+		},
+		{
+			MethodName: "ListPermissions",
+			Handler:    _AuthAdminService_ListPermissions_Handler,
+		},
+		{
+			MethodName: "CreatePermission",
+			Handler:    _AuthAdminService_CreatePermission_Handler,
+		},
+		{
+			MethodName: "DeletePermission",
+			Handler:    _AuthAdminService_DeletePermission_Handler,
+		},
+		{
+			MethodName: "AddRolePermission",
+			Handler:    _AuthAdminService_AddRolePermission_Handler,
+		},
+		{
+			MethodName: "RemoveRolePermission",
+			Handler:    _AuthAdminService_RemoveRolePermission_Handler,
+		},
+		{
+			MethodName: "GrantUser",
+			Handler:    _AuthAdminService_GrantUser_Handler,
+		},
+		{
+			MethodName: "RevokeUser",
+			Handler:    _AuthAdminService_RevokeUser_Handler,
+		},
+	},
+	Streams:  []grpc.StreamDesc{},
+	Metadata: "proto/admin.proto",
+}
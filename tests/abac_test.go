@@ -0,0 +1,186 @@
+package tests
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/G0tem/go-service-auth/internal/abac"
+	"github.com/G0tem/go-service-auth/internal/config"
+	authGrpc "github.com/G0tem/go-service-auth/internal/grpc"
+	"github.com/G0tem/go-service-auth/internal/handler"
+	"github.com/G0tem/go-service-auth/internal/model"
+	"github.com/G0tem/go-service-auth/proto"
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestAbacExpression(t *testing.T) {
+	attributes := abac.Attributes{
+		"subject":  abac.Attributes{"user_id": "42", "roles": []string{"user", "support"}, "age": 30},
+		"resource": abac.Attributes{"owner_id": "42", "region": "eu"},
+		"env":      abac.Attributes{"hour": 10.0},
+	}
+
+	cases := map[string]bool{
+		``:                                          true,
+		`subject.user_id == resource.owner_id`:      true,
+		`subject.user_id != resource.owner_id`:      false,
+		`"support" in subject.roles`:                true,
+		`resource.region in ["us", "asia"]`:         false,
+		`env.hour >= 9 && env.hour < 18`:            true,
+		`subject.age > 18 || missing.value`:         true,
+		`!(subject.age <= "29") && true`:            true,
+		`resource.missing == null`:                  true,
+		`"eu" in 'europe, eu' && subject.age == 30`: true,
+	}
+	for source, expected := range cases {
+		expression, err := abac.Compile(source)
+		if err != nil {
+			t.Errorf("Compile %q: unexpected error %v", source, err)
+			continue
+		}
+		result, err := expression.Evaluate(attributes)
+		if err != nil {
+			t.Errorf("Evaluate %q: unexpected error %v", source, err)
+		} else if result != expected {
+			t.Errorf("Evaluate %q: expected %v, got %v", source, expected, result)
+		}
+	}
+
+	for _, source := range []string{`subject.user_id ==`, `(true`, `"unterminated`, `a # b`, `true false`} {
+		if _, err := abac.Compile(source); err == nil {
+			t.Errorf("Compile %q: expected error", source)
+		}
+	}
+
+	expression, _ := abac.Compile(`subject.user_id`)
+	if _, err := expression.Evaluate(attributes); err == nil {
+		t.Errorf("Expected error for non-boolean result")
+	}
+}
+
+func TestAbacEngine(t *testing.T) {
+	rules := []abac.Rule{
+		{ID: "own", Effect: abac.EffectAllow, Actions: []string{"user:read"}, Condition: `subject.user_id == resource.id`},
+		{ID: "night", Effect: abac.EffectDeny, Actions: []string{"user:*"}, Condition: `env.hour < 6`},
+		{ID: "broken", Effect: abac.EffectDeny, Actions: []string{"user:delete"}, Condition: `subject.user_id`},
+	}
+	request := func(action, userId string, hour float64) abac.Request {
+		return abac.Request{
+			Action:      action,
+			Subject:     abac.Attributes{"user_id": userId},
+			Resource:    abac.Attributes{"id": "1"},
+			Environment: abac.Attributes{"hour": hour},
+		}
+	}
+
+	cases := []struct {
+		algorithm string
+		request   abac.Request
+		allowed   bool
+		rule      string
+	}{
+		{abac.DenyOverrides, request("user:read", "1", 12), true, "own"},
+		{abac.DenyOverrides, request("user:read", "1", 3), false, "night"},
+		{abac.DenyOverrides, request("user:read", "2", 12), false, ""},
+		{abac.DenyOverrides, request("orders:read", "1", 12), false, ""},
+		{abac.PermitOverrides, request("user:read", "1", 3), true, "own"},
+		{abac.PermitOverrides, request("user:read", "2", 3), false, "night"},
+		{abac.FirstApplicable, request("user:read", "1", 3), true, "own"},
+		// Rule failed to evaluate is applicable deny rule
+		{abac.PermitOverrides, request("user:delete", "1", 12), false, "broken"},
+	}
+	for i, c := range cases {
+		engine, err := abac.NewEngine(abac.Policy{Algorithm: c.algorithm, Rules: rules})
+		if err != nil {
+			t.Fatalf("Unexpected error %v", err)
+		}
+		decision := engine.Evaluate(c.request)
+		if decision.Allowed != c.allowed || decision.RuleID != c.rule {
+			t.Errorf("Case %d (%v): unexpected decision %+v", i, c.algorithm, decision)
+		}
+	}
+}
+
+func TestAbacInvalidPolicy(t *testing.T) {
+	policies := []abac.Policy{
+		{Algorithm: "unknown"},
+		{Rules: []abac.Rule{{Effect: "maybe"}}},
+		{Rules: []abac.Rule{{ID: "a", Effect: abac.EffectAllow}, {ID: "a", Effect: abac.EffectDeny}}},
+		{Rules: []abac.Rule{{Effect: abac.EffectAllow, Condition: "a =="}}},
+		{Rules: []abac.Rule{{Effect: abac.EffectAllow, Actions: []string{"read"}}}},
+	}
+	for i, policy := range policies {
+		if _, err := abac.NewEngine(policy); err == nil {
+			t.Errorf("Policy %d: expected error", i)
+		}
+	}
+
+	if _, err := abac.LoadPolicyFile("../abac_policy.yaml"); err != nil {
+		t.Errorf("Example policy: unexpected error %v", err)
+	}
+}
+
+func TestGrpcAuthorizeRejectsReservedEnvironment(t *testing.T) {
+	engine, err := abac.NewEngine(abac.Policy{Rules: []abac.Rule{
+		{ID: "night", Effect: abac.EffectDeny, Actions: []string{"user:*"}, Condition: `env.hour < 6`},
+	}})
+	failOnError(t, err, "Failed to create engine")
+	// The request is rejected before the user is loaded, so the server needs no database
+	server := authGrpc.NewAuthServer(nil, &config.Config{}, nil, engine)
+
+	for _, key := range []string{"time", "hour", "minute", "weekday"} {
+		_, err := server.Authorize(context.Background(), &proto.AuthorizeRequest{
+			UserId:      "7c9e6679-7425-40de-944b-e07fc1f90ae7",
+			Action:      "user:read",
+			Environment: map[string]string{key: "12"},
+		})
+		if code := status.Code(err); code != codes.InvalidArgument {
+			t.Errorf("Environment %v: expected %v, got %v", key, codes.InvalidArgument, code)
+		}
+	}
+}
+
+func TestAuthorizeMiddlewareUsesStoredUser(t *testing.T) {
+	const secret = "secret"
+	const userId = "7c9e6679-7425-40de-944b-e07fc1f90ae7"
+	engine, err := abac.LoadPolicyFile("../abac_policy.yaml")
+	failOnError(t, err, "Failed to load policy")
+	token := signTestToken(t, secret, time.Now().Add(time.Hour))
+
+	// Claims of the token don't change when the user is deactivated, the stored user does
+	active := true
+	loader := func(ctx context.Context, id uuid.UUID) (abac.Attributes, error) {
+		return abac.SubjectFromUser(model.User{ID: id, IsActive: active}, []string{model.DefaultUserRole}, nil), nil
+	}
+	app := fiber.New()
+	app.Use(handler.JWTMiddleware(secret))
+	app.Get("/users/:id", handler.AuthorizeWith(engine, loader, "user:read", handler.ResourceFromParams("id")),
+		func(c *fiber.Ctx) error { return c.SendStatus(fiber.StatusOK) })
+
+	cases := []struct {
+		name   string
+		active bool
+		id     string
+		status int
+	}{
+		{"own profile", true, userId, fiber.StatusOK},
+		{"profile of other user", true, "0b8f3c1e-52a4-4d8e-9d4a-6f1f0c2a7e11", fiber.StatusForbidden},
+		{"own profile of inactive user", false, userId, fiber.StatusForbidden},
+	}
+	for _, tc := range cases {
+		active = tc.active
+		req := httptest.NewRequest(http.MethodGet, "/users/"+tc.id, nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		resp, err := app.Test(req)
+		failOnError(t, err, "Request failed")
+		if resp.StatusCode != tc.status {
+			t.Errorf("%v: expected status %d, got %d", tc.name, tc.status, resp.StatusCode)
+		}
+	}
+}
@@ -0,0 +1,67 @@
+package tests
+
+import (
+	"bytes"
+	"errors"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"strings"
+	"testing"
+
+	"github.com/G0tem/go-service-auth/internal/avatar"
+)
+
+func encodePNG(t *testing.T, width, height int) []byte {
+	img := image.NewNRGBA(image.Rect(0, 0, width, height))
+	for x := 0; x < width; x++ {
+		img.Set(x, 0, color.NRGBA{R: 255, A: 128})
+	}
+	var buf bytes.Buffer
+	failOnError(t, png.Encode(&buf, img), "Failed to encode png")
+	return buf.Bytes()
+}
+
+func TestAvatarProcess(t *testing.T) {
+	data := encodePNG(t, 300, 100)
+	images, err := avatar.Process("user", data)
+	failOnError(t, err, "Failed to process avatar")
+
+	if len(images) != len(avatar.Variants) {
+		t.Fatalf("Expected %d variants, got %d", len(avatar.Variants), len(images))
+	}
+	keys := avatar.VariantKeys(images[0].Key)
+	for i, img := range images {
+		variant := avatar.Variants[i]
+		decoded, err := jpeg.Decode(bytes.NewReader(img.Data))
+		failOnError(t, err, "Variant is not jpeg")
+		if bounds := decoded.Bounds(); bounds.Dx() != variant.Size || bounds.Dy() != variant.Size {
+			t.Errorf("Variant %v has size %v", variant.Name, bounds)
+		}
+		if !strings.HasPrefix(img.Key, "user/") || keys[variant.Name] != img.Key {
+			t.Errorf("Unexpected key %v of variant %v (keys %v)", img.Key, variant.Name, keys)
+		}
+	}
+
+	again, err := avatar.Process("user", data)
+	failOnError(t, err, "Failed to process avatar")
+	if again[0].Key != images[0].Key {
+		t.Errorf("Keys must be deterministic: %v != %v", again[0].Key, images[0].Key)
+	}
+}
+
+func TestAvatarValidation(t *testing.T) {
+	cases := map[string][]byte{
+		"text":  []byte("<svg xmlns=\"http://www.w3.org/2000/svg\"></svg>"),
+		"small": encodePNG(t, avatar.MinDimension-1, 100),
+		"large": encodePNG(t, avatar.MaxDimension+1, 100),
+	}
+	for name, data := range cases {
+		_, err := avatar.Process("user", data)
+		var validationErr *avatar.ValidationError
+		if !errors.As(err, &validationErr) {
+			t.Errorf("Expected validation error for %v image, got %v", name, err)
+		}
+	}
+}
@@ -0,0 +1,60 @@
+package tests
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/G0tem/go-service-auth/internal/handler"
+	"github.com/gofiber/fiber/v2"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+func TestCookieAuthCsrf(t *testing.T) {
+	const secret = "secret"
+	const sessionId = "0b8f3c1e-52a4-4d8e-9d4a-6f1f0c2a7e11"
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+		"user_id": "7c9e6679-7425-40de-944b-e07fc1f90ae7",
+		"sid":     sessionId,
+		"exp":     time.Now().Add(time.Hour).Unix(),
+	}).SignedString([]byte(secret))
+	failOnError(t, err, "Failed to sign token")
+
+	app := fiber.New()
+	app.Use(handler.JWTMiddleware(secret))
+	app.All("/", func(c *fiber.Ctx) error { return c.SendStatus(fiber.StatusOK) })
+
+	cases := []struct {
+		name   string
+		method string
+		csrf   string
+		status int
+	}{
+		{"safe method without CSRF token", http.MethodGet, "", fiber.StatusOK},
+		{"state-changing method without CSRF token", http.MethodPost, "", fiber.StatusForbidden},
+		{"CSRF token of other session", http.MethodPost, handler.CsrfToken(secret, "other"), fiber.StatusForbidden},
+		{"CSRF token of the session", http.MethodDelete, handler.CsrfToken(secret, sessionId), fiber.StatusOK},
+	}
+	for _, tc := range cases {
+		req := httptest.NewRequest(tc.method, "/", nil)
+		req.AddCookie(&http.Cookie{Name: handler.TokenCookie, Value: token})
+		if tc.csrf != "" {
+			req.Header.Set(handler.CsrfHeader, tc.csrf)
+		}
+		resp, err := app.Test(req)
+		failOnError(t, err, "Request failed")
+		if resp.StatusCode != tc.status {
+			t.Errorf("%v: expected status %d, got %d", tc.name, tc.status, resp.StatusCode)
+		}
+	}
+
+	// Bearer tokens are not subject to CSRF
+	req := httptest.NewRequest(http.MethodPost, "/", nil)
+	req.Header.Set("Authorization", "Bearer "+token)
+	resp, err := app.Test(req)
+	failOnError(t, err, "Request failed")
+	if resp.StatusCode != fiber.StatusOK {
+		t.Errorf("Expected status 200 for bearer token, got %d", resp.StatusCode)
+	}
+}
@@ -0,0 +1,117 @@
+package tests
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/G0tem/go-service-auth/internal/cors"
+	"github.com/gofiber/fiber/v2"
+)
+
+func TestCorsAllowsOrigin(t *testing.T) {
+	policy := cors.Policy{Origins: []string{"https://app.example.com", "https://*.example.org"}}
+	cases := map[string]bool{
+		"https://app.example.com":       true,
+		"HTTPS://APP.EXAMPLE.COM":       true,
+		"http://app.example.com":        false,
+		"https://app.example.com:8443":  false,
+		"https://a.example.org":         true,
+		"https://a.b.example.org":       true,
+		"https://example.org":           false,
+		"https://evil.com/.example.org": false,
+		"https://a.example.org:8443":    false,
+		"https://example.org.evil.com":  false,
+	}
+	for origin, expected := range cases {
+		if policy.AllowsOrigin(origin) != expected {
+			t.Errorf("AllowsOrigin(%q) must be %v", origin, expected)
+		}
+	}
+}
+
+func corsTestApp(policy cors.Policy, overrides ...cors.Override) *fiber.App {
+	app := fiber.New()
+	app.Use(cors.New(policy, overrides...))
+	app.All("/*", func(c *fiber.Ctx) error { return c.SendStatus(fiber.StatusOK) })
+	return app
+}
+
+func corsPreflight(t *testing.T, app *fiber.App, path, origin string) *http.Response {
+	req := httptest.NewRequest(http.MethodOptions, path, nil)
+	req.Header.Set("Origin", origin)
+	req.Header.Set("Access-Control-Request-Method", http.MethodPost)
+	req.Header.Set("Access-Control-Request-Headers", "Content-Type, X-CSRF-Token")
+	resp, err := app.Test(req)
+	failOnError(t, err, "Preflight request failed")
+	return resp
+}
+
+func TestCorsPreflight(t *testing.T) {
+	app := corsTestApp(cors.Policy{
+		Origins:     []string{"https://app.example.com"},
+		Methods:     cors.Methods,
+		Credentials: true,
+		MaxAge:      time.Hour,
+	}, cors.Override{Prefix: "/api/v1/storage", Policy: cors.Policy{Origins: []string{"*"}, Methods: cors.Methods, Credentials: true}})
+
+	resp := corsPreflight(t, app, "/api/v1/auth/login", "https://app.example.com")
+	if resp.StatusCode != fiber.StatusNoContent {
+		t.Errorf("Expected status 204, got %d", resp.StatusCode)
+	}
+	expected := map[string]string{
+		"Access-Control-Allow-Origin":      "https://app.example.com",
+		"Access-Control-Allow-Credentials": "true",
+		"Access-Control-Allow-Headers":     "Content-Type, X-CSRF-Token",
+		"Access-Control-Max-Age":           "3600",
+		"Vary":                             "Origin, Access-Control-Request-Method, Access-Control-Request-Headers",
+	}
+	for header, value := range expected {
+		if resp.Header.Get(header) != value {
+			t.Errorf("Expected %v: %q, got %q", header, value, resp.Header.Get(header))
+		}
+	}
+	if resp.Header.Get("Access-Control-Allow-Methods") == "" {
+		t.Errorf("Expected allowed methods in preflight response")
+	}
+
+	resp = corsPreflight(t, app, "/api/v1/auth/login", "https://evil.com")
+	if resp.Header.Get("Access-Control-Allow-Origin") != "" || resp.Header.Get("Access-Control-Allow-Methods") != "" {
+		t.Errorf("Preflight of not allowed origin must have no CORS headers")
+	}
+	if resp.Header.Get("Vary") == "" {
+		t.Errorf("Expected Vary header for not allowed origin")
+	}
+
+	// Any origin is allowed by the override, but without credentials
+	resp = corsPreflight(t, app, "/api/v1/storage/covers/key.jpg", "https://evil.com")
+	if resp.Header.Get("Access-Control-Allow-Origin") != "*" || resp.Header.Get("Access-Control-Allow-Credentials") != "" {
+		t.Errorf("Expected any origin without credentials, got %v", resp.Header)
+	}
+	// Override covers only paths under its prefix
+	resp = corsPreflight(t, app, "/api/v1/storage-admin/keys", "https://evil.com")
+	if resp.Header.Get("Access-Control-Allow-Origin") != "" {
+		t.Errorf("Override must not apply to paths sharing its prefix, got %v", resp.Header)
+	}
+}
+
+func TestCorsActualRequest(t *testing.T) {
+	app := corsTestApp(cors.Policy{Origins: []string{"https://*.example.com"}, Methods: cors.Methods, Credentials: true})
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/auth/get-me", nil)
+	req.Header.Set("Origin", "https://app.example.com")
+	resp, err := app.Test(req)
+	failOnError(t, err, "Request failed")
+	if resp.StatusCode != fiber.StatusOK || resp.Header.Get("Access-Control-Allow-Origin") != "https://app.example.com" ||
+		resp.Header.Get("Access-Control-Allow-Credentials") != "true" || resp.Header.Get("Vary") != "Origin" {
+		t.Errorf("Unexpected response of allowed origin: %d %v", resp.StatusCode, resp.Header)
+	}
+
+	// Requests without Origin are not cross-origin
+	resp, err = app.Test(httptest.NewRequest(http.MethodGet, "/api/v1/auth/get-me", nil))
+	failOnError(t, err, "Request failed")
+	if resp.StatusCode != fiber.StatusOK || resp.Header.Get("Access-Control-Allow-Origin") != "" {
+		t.Errorf("Unexpected response without origin: %d %v", resp.StatusCode, resp.Header)
+	}
+}
@@ -0,0 +1,52 @@
+package tests
+
+import (
+	"testing"
+
+	"github.com/G0tem/go-service-auth/internal/events"
+)
+
+func TestEventsCompareIDs(t *testing.T) {
+	cases := []struct {
+		a, b     string
+		expected int
+	}{
+		{"1700000000000-0", "1700000000000-1", -1},
+		{"1700000000000-5", "1700000000000-5", 0},
+		{"1700000000001-0", "1700000000000-9", 1},
+		{"1700000000000", "1700000000000-0", 0},
+		{"0", "1700000000000-0", -1},
+	}
+	for _, c := range cases {
+		result, err := events.CompareIDs(c.a, c.b)
+		if err != nil || result != c.expected {
+			t.Errorf("CompareIDs(%v, %v): expected %v, got %v (%v)", c.a, c.b, c.expected, result, err)
+		}
+	}
+	if _, err := events.CompareIDs("abc", "0"); err == nil {
+		t.Errorf("Expected error for invalid id")
+	}
+}
+
+func TestEventsFilter(t *testing.T) {
+	granted := events.Event{Type: events.UserRoleGranted, UserID: "u1"}
+	changed := events.Event{Type: events.PermissionsChanged}
+
+	cases := []struct {
+		filter   events.Filter
+		event    events.Event
+		expected bool
+	}{
+		{events.Filter{}, granted, true},
+		{events.Filter{UserID: "u1"}, granted, true},
+		{events.Filter{UserID: "u2"}, granted, false},
+		{events.Filter{UserID: "u2"}, changed, true},
+		{events.Filter{Types: []string{events.UserDeactivated}}, granted, false},
+		{events.Filter{Types: []string{events.UserRoleGranted}, UserID: "u1"}, granted, true},
+	}
+	for i, c := range cases {
+		if c.filter.Match(c.event) != c.expected {
+			t.Errorf("Case %d: expected %v", i, c.expected)
+		}
+	}
+}
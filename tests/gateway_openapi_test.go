@@ -0,0 +1,61 @@
+package tests
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/G0tem/go-service-auth/internal/gateway"
+)
+
+func TestMergeOpenAPI(t *testing.T) {
+	swagDoc := []byte(`{
+		"swagger": "2.0",
+		"basePath": "/api/v1",
+		"paths": {"/auth/login": {"post": {"summary": "Login"}}},
+		"definitions": {"types.LoginRequest": {"type": "object"}},
+		"securityDefinitions": {"ApiKeyAuth": {"type": "apiKey", "name": "Authorization", "in": "header"}}
+	}`)
+	gatewayDoc := []byte(`{
+		"swagger": "2.0",
+		"tags": [{"name": "AuthService"}, {"name": "AuthAdminService"}],
+		"paths": {
+			"/users/{user_id}": {"get": {"operationId": "AuthService_GetUserInfo", "tags": ["AuthService"]}},
+			"/auth.AuthAdminService/ListUsers": {"post": {"operationId": "AuthAdminService_ListUsers", "tags": ["AuthAdminService"]}}
+		},
+		"definitions": {"authGetUserInfoResponse": {"type": "object"}}
+	}`)
+
+	merged, err := gateway.MergeOpenAPI(swagDoc, gatewayDoc)
+	failOnError(t, err, "Failed to merge documents")
+
+	var doc struct {
+		Paths       map[string]map[string]map[string]any `json:"paths"`
+		Definitions map[string]any                       `json:"definitions"`
+		Tags        []map[string]string                  `json:"tags"`
+	}
+	failOnError(t, json.Unmarshal(merged, &doc), "Failed to parse merged document")
+
+	if _, ok := doc.Paths["/auth/login"]; !ok {
+		t.Errorf("Swag path is lost")
+	}
+	operation, ok := doc.Paths["/gateway/users/{user_id}"]["get"]
+	if !ok {
+		t.Fatalf("Gateway path is not prefixed with gateway path relative to base path: %v", doc.Paths)
+	}
+	if operation["security"] == nil {
+		t.Errorf("Gateway operation must require %v", gateway.SecurityDefinition)
+	}
+	if _, ok := doc.Paths["/gateway/auth.AuthAdminService/ListUsers"]; ok {
+		t.Errorf("AuthAdminService is not exposed by the gateway, its operations must be skipped")
+	}
+	if doc.Definitions["types.LoginRequest"] == nil || doc.Definitions["authGetUserInfoResponse"] == nil {
+		t.Errorf("Definitions are not merged: %v", doc.Definitions)
+	}
+	if len(doc.Tags) != 1 || doc.Tags[0]["name"] != "AuthService" {
+		t.Errorf("Tags are not merged: %v", doc.Tags)
+	}
+
+	if _, err = gateway.MergeOpenAPI([]byte(`{"basePath": "/other"}`), gatewayDoc); err == nil {
+		t.Errorf("Expected error for gateway outside of base path")
+	}
+}
@@ -0,0 +1,162 @@
+package tests
+
+import (
+	"context"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/G0tem/go-service-auth/internal/config"
+	authGrpc "github.com/G0tem/go-service-auth/internal/grpc"
+	"github.com/G0tem/go-service-auth/internal/handler"
+	"github.com/G0tem/go-service-auth/proto"
+	"github.com/golang-jwt/jwt/v5"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// testTokens accepts tokens signed with the secret except revoked ones, permissions are taken from the claims
+type testTokens struct {
+	secret  string
+	revoked string
+}
+
+func (tokens testTokens) CallerFromToken(ctx context.Context, token string) (*authGrpc.Caller, error) {
+	claims, err := handler.ParseJWT(token, tokens.secret)
+	if err != nil || token == tokens.revoked {
+		return nil, status.Error(codes.Unauthenticated, "invalid token")
+	}
+	return &authGrpc.Caller{
+		Name:        claims.Username,
+		Kind:        authGrpc.CallerKindJWT,
+		Permissions: claims.Permissions,
+		Service:     slices.Contains(claims.Audience, handler.ServiceAudience),
+	}, nil
+}
+
+func TestGrpcInterceptorsAuth(t *testing.T) {
+	cfg := &config.Config{SecretKey: "secret", GrpcAuthEnabled: true, GrpcRequestTimeout: time.Second}
+	token := signTestToken(t, "secret", time.Now().Add(time.Hour))
+	revoked := signTestToken(t, "secret", time.Now().Add(2*time.Hour))
+	unary := authGrpc.NewInterceptors(cfg, testTokens{secret: "secret", revoked: revoked}).Unary()
+
+	call := func(ctx context.Context, method string, handler grpc.UnaryHandler) codes.Code {
+		if handler == nil {
+			handler = func(ctx context.Context, req any) (any, error) {
+				if caller, ok := authGrpc.CallerFromContext(ctx); !ok || caller.Name != "tester" {
+					t.Errorf("Unexpected caller %+v", caller)
+				}
+				if _, ok := ctx.Deadline(); !ok {
+					t.Errorf("Deadline is not set")
+				}
+				return nil, nil
+			}
+		}
+		_, err := unary(ctx, nil, &grpc.UnaryServerInfo{FullMethod: method}, handler)
+		return status.Code(err)
+	}
+	withToken := metadata.NewIncomingContext(context.Background(), metadata.Pairs("authorization", "Bearer "+token))
+
+	cases := []struct {
+		ctx    context.Context
+		method string
+		code   codes.Code
+	}{
+		{context.Background(), proto.AuthService_GetTestData_FullMethodName, codes.Unauthenticated},
+		{metadata.NewIncomingContext(context.Background(), metadata.Pairs("authorization", "Bearer bad")), proto.AuthService_GetTestData_FullMethodName, codes.Unauthenticated},
+		{withToken, proto.AuthService_GetTestData_FullMethodName, codes.OK},
+		{metadata.NewIncomingContext(context.Background(), metadata.Pairs("authorization", "Bearer "+revoked)), proto.AuthService_GetTestData_FullMethodName, codes.Unauthenticated},
+		// Information about other users requires user:list, the default role has only user:read
+		{withToken, proto.AuthService_GetUserInfo_FullMethodName, codes.PermissionDenied},
+		{withToken, proto.AuthService_CheckPermission_FullMethodName, codes.PermissionDenied},
+		{withToken, "/auth.AuthService/Unknown", codes.PermissionDenied},
+	}
+	if code := call(context.Background(), "/grpc.health.v1.Health/Check", func(ctx context.Context, req any) (any, error) {
+		return nil, nil
+	}); code != codes.OK {
+		t.Errorf("Health check must be public, got %v", code)
+	}
+	for i, c := range cases {
+		if code := call(c.ctx, c.method, nil); code != c.code {
+			t.Errorf("Case %d (%v): expected %v, got %v", i, c.method, c.code, code)
+		}
+	}
+
+	code := call(withToken, proto.AuthService_GetTestData_FullMethodName, func(ctx context.Context, req any) (any, error) {
+		panic("boom")
+	})
+	if code != codes.Internal {
+		t.Errorf("Panic: expected %v, got %v", codes.Internal, code)
+	}
+}
+
+func TestGrpcAdminServiceRequiresService(t *testing.T) {
+	cfg := &config.Config{SecretKey: "secret", GrpcAuthEnabled: true}
+	unary := authGrpc.NewInterceptors(cfg, testTokens{secret: "secret"}).Unary()
+	sign := func(claims jwt.MapClaims) context.Context {
+		claims["exp"] = time.Now().Add(time.Hour).Unix()
+		token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString([]byte("secret"))
+		failOnError(t, err, "Failed to sign token")
+		return metadata.NewIncomingContext(context.Background(), metadata.Pairs("authorization", "Bearer "+token))
+	}
+
+	cases := []struct {
+		name string
+		ctx  context.Context
+		code codes.Code
+	}{
+		// Permissions of the default "user" role
+		{"user token", sign(jwt.MapClaims{"username": "user", "permissions": []string{"user:read"}}), codes.PermissionDenied},
+		{"admin user token", sign(jwt.MapClaims{"username": "admin", "permissions": []string{"*:*"}}), codes.PermissionDenied},
+		{"service token without permission", sign(jwt.MapClaims{
+			"username": "billing", "aud": handler.ServiceAudience, "permissions": []string{"user:read"},
+		}), codes.PermissionDenied},
+		{"service token", sign(jwt.MapClaims{
+			"username": "billing", "aud": handler.ServiceAudience, "permissions": []string{"user:list"},
+		}), codes.OK},
+	}
+	for _, c := range cases {
+		_, err := unary(c.ctx, nil, &grpc.UnaryServerInfo{FullMethod: proto.AuthAdminService_ListUsers_FullMethodName},
+			func(ctx context.Context, req any) (any, error) { return nil, nil })
+		if code := status.Code(err); code != c.code {
+			t.Errorf("%v: expected %v, got %v", c.name, c.code, code)
+		}
+	}
+}
+
+func TestGrpcCreateUserRolesRequireRbacManage(t *testing.T) {
+	cfg := &config.Config{SecretKey: "secret", GrpcAuthEnabled: true}
+	unary := authGrpc.NewInterceptors(cfg, testTokens{secret: "secret"}).Unary()
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+		"username":    "billing",
+		"aud":         handler.ServiceAudience,
+		"permissions": []string{"user:manage"},
+		"exp":         time.Now().Add(time.Hour).Unix(),
+	}).SignedString([]byte("secret"))
+	failOnError(t, err, "Failed to sign token")
+	ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs("authorization", "Bearer "+token))
+
+	// The request is rejected before the user is created, so the server needs no service
+	admin := authGrpc.NewAdminServer(nil)
+	req := &proto.CreateUserRequest{Username: "root", Email: "root@example.com", Password: "password", Roles: []string{"admin"}}
+	_, err = unary(ctx, req, &grpc.UnaryServerInfo{FullMethod: proto.AuthAdminService_CreateUser_FullMethodName},
+		func(ctx context.Context, req any) (any, error) {
+			return admin.CreateUser(ctx, req.(*proto.CreateUserRequest))
+		})
+	if code := status.Code(err); code != codes.PermissionDenied {
+		t.Errorf("Expected %v, got %v", codes.PermissionDenied, code)
+	}
+}
+
+func TestGrpcClientPermissionsConfig(t *testing.T) {
+	t.Setenv("GRPC_CLIENT_PERMISSIONS", "billing=user:read rbac:read; gateway = *:* ;")
+	cfg := config.LoadConfig()
+	if len(cfg.GrpcClientPermissions["billing"]) != 2 || cfg.GrpcClientPermissions["gateway"][0] != "*:*" {
+		t.Errorf("Incorrect client permissions %v", cfg.GrpcClientPermissions)
+	}
+	if !cfg.GrpcAuthEnabled {
+		t.Errorf("gRPC auth must be enabled by default")
+	}
+}
@@ -0,0 +1,56 @@
+package tests
+
+import (
+	"testing"
+	"time"
+
+	"github.com/G0tem/go-service-auth/internal/handler"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+func signTestToken(t *testing.T, secret string, exp time.Time) string {
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+		"user_id":     "7c9e6679-7425-40de-944b-e07fc1f90ae7",
+		"username":    "tester",
+		"email":       "tester@example.com",
+		"role":        "user",
+		"roles":       []string{"user"},
+		"permissions": []string{"user:read"},
+		"exp":         exp.Unix(),
+	}).SignedString([]byte(secret))
+	failOnError(t, err, "Failed to sign token")
+	return token
+}
+
+func TestParseJWT(t *testing.T) {
+	exp := time.Now().Add(time.Hour).Truncate(time.Second)
+	claims, err := handler.ParseJWT(signTestToken(t, "secret", exp), "secret")
+	failOnError(t, err, "Failed to parse token")
+	if claims.Username != "tester" || claims.Role != "user" || len(claims.Permissions) != 1 || !claims.Exp.Equal(exp) {
+		t.Errorf("Incorrect claims %+v", claims)
+	}
+
+	// iat keeps millisecond precision of the time the session token was issued at
+	for _, issuedAt := range []time.Time{time.UnixMilli(1700000000123), time.UnixMilli(1700000000999), time.Now().Truncate(time.Millisecond)} {
+		token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+			"iat": float64(issuedAt.UnixMilli()) / 1000,
+			"exp": exp.Unix(),
+		}).SignedString([]byte("secret"))
+		failOnError(t, err, "Failed to sign token")
+		claims, err := handler.ParseJWT(token, "secret")
+		failOnError(t, err, "Failed to parse token")
+		if !claims.IssuedAt.Equal(issuedAt) {
+			t.Errorf("Expected iat %v, got %v", issuedAt, claims.IssuedAt)
+		}
+	}
+
+	if _, err = handler.ParseJWT(signTestToken(t, "other", exp), "secret"); err == nil {
+		t.Errorf("Expected error for wrong signature")
+	}
+	if _, err = handler.ParseJWT(signTestToken(t, "secret", time.Now().Add(-time.Hour)), "secret"); err == nil {
+		t.Errorf("Expected error for expired token")
+	}
+	if _, err = handler.ParseJWT("not a token", "secret"); err == nil {
+		t.Errorf("Expected error for malformed token")
+	}
+}
@@ -0,0 +1,56 @@
+package tests
+
+import (
+	"testing"
+
+	"github.com/G0tem/go-service-auth/internal/handler/rbac"
+	"github.com/G0tem/go-service-auth/internal/model"
+	"github.com/google/uuid"
+)
+
+func testRoles() []model.UserRole {
+	viewer := model.UserRole{ID: uuid.New(), Name: "viewer"}
+	editor := model.UserRole{ID: uuid.New(), Name: "editor", ParentID: &viewer.ID}
+	admin := model.UserRole{ID: uuid.New(), Name: "admin", ParentID: &editor.ID}
+	return []model.UserRole{viewer, editor, admin}
+}
+
+func TestRoleHierarchyLineage(t *testing.T) {
+	hierarchy := rbac.NewRoleHierarchy(testRoles())
+
+	lineage, err := hierarchy.Lineage("admin")
+	if err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+	if len(lineage) != 3 || lineage[0].Name != "admin" || lineage[1].Name != "editor" || lineage[2].Name != "viewer" {
+		t.Errorf("Incorrect lineage %v", lineage)
+	}
+
+	expanded, err := hierarchy.Expand("editor", "viewer", "unknown")
+	if err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+	if len(expanded) != 2 {
+		t.Errorf("Incorrect expanded roles %v", expanded)
+	}
+}
+
+func TestRoleHierarchyCycle(t *testing.T) {
+	hierarchy := rbac.NewRoleHierarchy(testRoles())
+
+	if err := hierarchy.CheckParent("viewer", "admin"); err == nil {
+		t.Errorf("Cycle viewer -> admin not detected")
+	}
+	if err := hierarchy.CheckParent("viewer", "viewer"); err == nil {
+		t.Errorf("Cycle viewer -> viewer not detected")
+	}
+	if err := hierarchy.CheckParent("editor", ""); err != nil {
+		t.Errorf("Unexpected error %v", err)
+	}
+
+	roles := testRoles()
+	roles[0].ParentID = &roles[2].ID
+	if _, err := rbac.NewRoleHierarchy(roles).Lineage("editor"); err == nil {
+		t.Errorf("Stored cycle not detected")
+	}
+}
@@ -0,0 +1,136 @@
+package tests
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/G0tem/go-service-auth/internal/handler/rbac"
+	"github.com/G0tem/go-service-auth/internal/types"
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+)
+
+func TestPermissionSetWildcards(t *testing.T) {
+	set := rbac.NewPermissionSet("orders:*", "*:read")
+
+	for _, permission := range []string{"orders:read", "orders:delete", "users:read", "orders:*"} {
+		if !set.Allows(permission) {
+			t.Errorf("Permission %v must be allowed", permission)
+		}
+	}
+	for _, permission := range []string{"users:delete", "users:*", "*:*"} {
+		if set.Allows(permission) {
+			t.Errorf("Permission %v must not be allowed", permission)
+		}
+	}
+}
+
+func TestPermissionSetSuperuser(t *testing.T) {
+	if !rbac.NewPermissionSet("*:*").Allows("users:delete", "*:*") {
+		t.Errorf("Superuser must be allowed everything")
+	}
+	if !rbac.NewPermissionSet("admin:all").Allows("users:delete") {
+		t.Errorf("Legacy superuser must be allowed everything")
+	}
+	if rbac.NewPermissionSet().Allows() {
+		t.Errorf("Empty requirement must not be allowed")
+	}
+}
+
+func TestPermissionSetDenyWins(t *testing.T) {
+	set := rbac.NewPermissionSet("*:*", "!orders:delete", "orders:delete")
+	if set.Allows("orders:delete") {
+		t.Errorf("Denied permission must not be allowed")
+	}
+	if !set.Allows("orders:read") {
+		t.Errorf("Not denied permission must be allowed")
+	}
+
+	set = rbac.NewPermissionSet("orders:*", "!*:delete")
+	if set.Allows("orders:delete") {
+		t.Errorf("Permission denied by wildcard must not be allowed")
+	}
+	matchedBy, allowed := set.Match("orders:delete")
+	if allowed || matchedBy.String() != "!*:delete" {
+		t.Errorf("Incorrect match %v %v", matchedBy, allowed)
+	}
+	if !set.AllowsAny("orders:delete", "orders:update") {
+		t.Errorf("One of permissions must be allowed")
+	}
+
+	// Wildcard requirement covers every action, so a deny of one of them blocks it
+	set = rbac.NewPermissionSet("orders:*", "!orders:delete")
+	for _, permission := range []string{"orders:*", "*:delete", "*:*"} {
+		if set.Allows(permission) {
+			t.Errorf("Permission %v overlapping denied orders:delete must not be allowed", permission)
+		}
+	}
+	if !set.Allows("orders:read") {
+		t.Errorf("Not denied permission must be allowed")
+	}
+}
+
+func TestMatchResource(t *testing.T) {
+	cases := []struct {
+		pattern    string
+		resourceId string
+		expected   bool
+	}{
+		{"42", "42", true},
+		{"42", "421", false},
+		{"*", "42", true},
+		{"team-a/*", "team-a/42", true},
+		{"team-a/*", "team-b/42", false},
+		{"*", "", false},
+		{"team-a/*", "", false},
+	}
+	for _, c := range cases {
+		if rbac.MatchResource(c.pattern, c.resourceId) != c.expected {
+			t.Errorf("Incorrect match of %v with %v", c.pattern, c.resourceId)
+		}
+	}
+}
+
+func TestCheckResourceAccess(t *testing.T) {
+	userId := uuid.New()
+	// Global permission to read projects and scoped grant to update projects of team a
+	can := func(id uuid.UUID, permission string, resource types.Resource) (bool, error) {
+		permissions := []string{"projects:read"}
+		if id == userId && resource.Type == "project" && rbac.MatchResource("team-a-*", resource.ID) {
+			permissions = append(permissions, "projects:update")
+		}
+		return rbac.NewPermissionSet(permissions...).Allows(permission), nil
+	}
+
+	app := fiber.New()
+	app.Use(func(c *fiber.Ctx) error {
+		c.Locals("user_id", c.Get("X-User-Id"))
+		return c.Next()
+	})
+	ok := func(c *fiber.Ctx) error { return c.SendStatus(fiber.StatusOK) }
+	app.Get("/projects/:id", rbac.CheckResourceAccessWith(can, "projects:read", "project", "id"), ok)
+	app.Patch("/projects/:id", rbac.CheckResourceAccessWith(can, "projects:update", "project", "id"), ok)
+
+	cases := []struct {
+		method string
+		id     string
+		user   string
+		status int
+	}{
+		{http.MethodGet, "team-b-1", userId.String(), fiber.StatusOK},
+		{http.MethodPatch, "team-a-1", userId.String(), fiber.StatusOK},
+		{http.MethodPatch, "team-b-1", userId.String(), fiber.StatusForbidden},
+		{http.MethodPatch, "team-a-1", uuid.NewString(), fiber.StatusForbidden},
+		{http.MethodGet, "team-a-1", "not a user id", fiber.StatusForbidden},
+	}
+	for _, c := range cases {
+		req := httptest.NewRequest(c.method, "/projects/"+c.id, nil)
+		req.Header.Set("X-User-Id", c.user)
+		resp, err := app.Test(req)
+		failOnError(t, err, "Request failed")
+		if resp.StatusCode != c.status {
+			t.Errorf("%v %v: expected status %d, got %d", c.method, c.id, c.status, resp.StatusCode)
+		}
+	}
+}
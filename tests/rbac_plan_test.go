@@ -0,0 +1,68 @@
+package tests
+
+import (
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/G0tem/go-service-auth/internal/handler/rbac"
+	"github.com/G0tem/go-service-auth/internal/model"
+	"github.com/G0tem/go-service-auth/internal/types"
+	"github.com/google/uuid"
+)
+
+func testSyncState() rbac.SyncState {
+	user := model.UserRole{ID: uuid.New(), Name: "user"}
+	legacy := model.UserRole{ID: uuid.New(), Name: "legacy"}
+	read := model.UserPermission{ID: uuid.New(), Model: "user", Action: "read"}
+	write := model.UserPermission{ID: uuid.New(), Model: "user", Action: "write"}
+	return rbac.SyncState{
+		Roles:       []model.UserRole{user, legacy},
+		Permissions: []model.UserPermission{read, write},
+		RolePermissions: []model.UserRolePermission{
+			{RoleID: user.ID, PermissionID: read.ID},
+			{RoleID: user.ID, PermissionID: write.ID},
+			{RoleID: legacy.ID, PermissionID: write.ID},
+		},
+	}
+}
+
+func TestSyncPlan(t *testing.T) {
+	matrix := map[string]string{
+		"user":  "user:read, !user:write",
+		"admin": "*:*",
+	}
+
+	plan, err := testSyncState().Plan(matrix, rbac.AddMissedOnly)
+	if err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+	if !slices.Equal(plan.AddRoles, []string{"admin"}) || !slices.Equal(plan.AddPermissions, []string{"*:*"}) {
+		t.Errorf("Incorrect additions %+v", plan)
+	}
+	if !slices.Equal(plan.UpdateLinks, []types.RbacPlanLink{{Role: "user", Permission: "user:write", Deny: true}}) {
+		t.Errorf("Incorrect updated links %+v", plan.UpdateLinks)
+	}
+	if len(plan.DeleteLinks) != 0 || len(plan.DeletePermissions) != 0 || len(plan.DeleteRoles) != 0 {
+		t.Errorf("Nothing must be deleted in add_missed_only mode %+v", plan)
+	}
+
+	plan, err = testSyncState().Plan(map[string]string{"user": "user:read"}, rbac.DeleteOutdatedRolesAndPermissions)
+	if err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+	if !slices.Equal(plan.DeleteLinks, []types.RbacPlanLink{{Role: "user", Permission: "user:write"}}) ||
+		!slices.Equal(plan.DeletePermissions, []string{"user:write"}) ||
+		!slices.Equal(plan.DeleteRoles, []string{"legacy"}) {
+		t.Errorf("Incorrect deletions %+v", plan)
+	}
+
+	text := rbac.FormatPlan(plan)
+	if !strings.Contains(text, "- role legacy") || !strings.Contains(text, "3 change(s)") {
+		t.Errorf("Incorrect text plan %v", text)
+	}
+
+	if _, err = testSyncState().Plan(map[string]string{"user": "user"}, rbac.AddMissedOnly); err == nil {
+		t.Errorf("Permission without action must be invalid")
+	}
+}
@@ -0,0 +1,55 @@
+package tests
+
+import (
+	"testing"
+
+	"github.com/G0tem/go-service-auth/internal/handler/rbac"
+)
+
+func TestParsePolicy(t *testing.T) {
+	policy, err := rbac.ParsePolicy([]byte(`
+version: 2
+roles:
+  - name: viewer
+    permissions: ["orders:read"]
+  - name: editor
+    parent: viewer
+    permissions: ["orders:*", "!orders:delete"]
+`), rbac.PolicyFormatYAML)
+	if err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+	if policy.Version != 2 || len(policy.Roles) != 2 || policy.Roles[1].Parent != "viewer" {
+		t.Errorf("Incorrect policy %+v", policy)
+	}
+	if matrix := rbac.PolicyMatrix(policy); matrix["editor"] != "orders:*,!orders:delete" {
+		t.Errorf("Incorrect matrix %v", matrix)
+	}
+
+	reordered, err := rbac.ParsePolicy([]byte(`{"version": 2, "roles": [
+		{"name": "editor", "parent": "viewer", "permissions": ["!orders:delete", "orders:*"]},
+		{"name": "viewer", "permissions": ["orders:read"]}
+	]}`), rbac.PolicyFormatJSON)
+	if err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+	if rbac.PolicyHash(policy) != rbac.PolicyHash(reordered) {
+		t.Errorf("Hash depends on order of roles and permissions")
+	}
+}
+
+func TestParseInvalidPolicy(t *testing.T) {
+	invalid := map[string]string{
+		"no version":         `roles: [{name: user}]`,
+		"unknown field":      `{version: 1, roles: [{name: user, permits: []}]}`,
+		"invalid permission": `{version: 1, roles: [{name: user, permissions: ["user"]}]}`,
+		"duplicate role":     `{version: 1, roles: [{name: user}, {name: user}]}`,
+		"unknown parent":     `{version: 1, roles: [{name: user, parent: admin}]}`,
+		"cycle":              `{version: 1, roles: [{name: a, parent: b}, {name: b, parent: a}]}`,
+	}
+	for name, data := range invalid {
+		if _, err := rbac.ParsePolicy([]byte(data), rbac.PolicyFormatYAML); err == nil {
+			t.Errorf("Policy with %v must be invalid", name)
+		}
+	}
+}
@@ -0,0 +1,150 @@
+package tests
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/G0tem/go-service-auth/internal/model"
+	"github.com/G0tem/go-service-auth/internal/service"
+	"github.com/google/uuid"
+)
+
+func TestServiceValidation(t *testing.T) {
+	if err := service.ValidateUsername("tester"); err != nil {
+		t.Errorf("Unexpected error for valid username: %v", err)
+	}
+	for _, username := range []string{"ab", "with space", string(make([]byte, 51))} {
+		if err := service.ValidateUsername(username); service.Kind(err) != service.ErrInvalidArgument {
+			t.Errorf("Expected invalid argument for username %q, got %v", username, err)
+		}
+	}
+	if err := service.ValidateEmail("not an email"); service.Kind(err) != service.ErrInvalidArgument {
+		t.Errorf("Expected invalid argument for email, got %v", err)
+	}
+	if err := service.ValidatePassword("12345"); service.Kind(err) != service.ErrInvalidArgument {
+		t.Errorf("Expected invalid argument for short password, got %v", err)
+	}
+
+	wrapped := fmt.Errorf("create user: %w", service.ErrAlreadyExists)
+	if service.Kind(wrapped) != service.ErrAlreadyExists {
+		t.Errorf("Expected already exists kind for wrapped error")
+	}
+	if service.Kind(errors.New("connection refused")) != nil {
+		t.Errorf("Expected no kind for unknown error")
+	}
+}
+
+func TestUserTokenRevoked(t *testing.T) {
+	user := model.User{}
+	if user.TokenRevoked(time.Now()) {
+		t.Errorf("Token must be valid without revocation")
+	}
+
+	revokedAt := time.Now()
+	user.TokensValidAfter = &revokedAt
+	if !user.TokenRevoked(revokedAt.Add(-time.Second)) {
+		t.Errorf("Token issued before revocation must be revoked")
+	}
+	if user.TokenRevoked(revokedAt.Add(time.Second)) {
+		t.Errorf("Token issued after revocation must be valid")
+	}
+	if !user.TokenRevoked(time.Time{}) {
+		t.Errorf("Token without iat must be revoked after revocation")
+	}
+}
+
+func TestUserSessionActive(t *testing.T) {
+	now := time.Now()
+	session := model.UserSession{ExpiresAt: now.Add(time.Hour)}
+	if !session.Active(now) {
+		t.Errorf("Session must be active before expiration")
+	}
+	if session.Active(now.Add(2 * time.Hour)) {
+		t.Errorf("Expired session must not be active")
+	}
+	session.RevokedAt = &now
+	if session.Active(now) {
+		t.Errorf("Revoked session must not be active")
+	}
+}
+
+func TestUserSessionCurrent(t *testing.T) {
+	issuedAt := time.Now().Truncate(time.Millisecond)
+	session := model.UserSession{TokenIssuedAt: issuedAt}
+	if !session.Current(issuedAt) {
+		t.Errorf("The last token of the session must be current")
+	}
+	// Refresh of the session replaces its token
+	session.TokenIssuedAt = issuedAt.Add(time.Minute)
+	if session.Current(issuedAt) {
+		t.Errorf("Token issued before refresh of the session must not be current")
+	}
+}
+
+func TestProfileValidation(t *testing.T) {
+	user := model.User{DisplayName: "Tester", Locale: "en-US", Timezone: "Europe/Moscow", PasswordHash: string(make([]byte, 60))}
+	if err := service.ValidateFields(&user, "DisplayName", "Locale", "Timezone"); err != nil {
+		t.Errorf("Unexpected error for valid profile: %v", err)
+	}
+
+	user.Locale = "not a locale"
+	user.Timezone = "Mars/Olympus"
+	err := service.ValidateFields(&user, "Locale", "Timezone")
+	if service.Kind(err) != service.ErrInvalidArgument {
+		t.Fatalf("Expected invalid argument for invalid profile, got %v", err)
+	}
+	if !strings.Contains(err.Error(), "locale") || !strings.Contains(err.Error(), "timezone") {
+		t.Errorf("Expected error to name json fields, got %v", err)
+	}
+
+	if err = service.ValidateAttributes(map[string]any{"team": "core"}); err != nil {
+		t.Errorf("Unexpected error for valid attributes: %v", err)
+	}
+	large := map[string]any{"note": strings.Repeat("x", service.MaxAttributesSize)}
+	if err = service.ValidateAttributes(large); service.Kind(err) != service.ErrInvalidArgument {
+		t.Errorf("Expected invalid argument for large attributes, got %v", err)
+	}
+}
+
+func TestEmailChangeLink(t *testing.T) {
+	link, err := service.EmailChangeLink("http://localhost:8002/front?page=email", "abc")
+	failOnError(t, err, "Failed to build link")
+	if link != "http://localhost:8002/front?page=email&token=abc" {
+		t.Errorf("Unexpected link %v", link)
+	}
+}
+
+func TestNextUsernameChange(t *testing.T) {
+	user := model.User{}
+	if next := service.NextUsernameChange(&user, time.Hour); !next.IsZero() {
+		t.Errorf("User never changed username must be allowed to change it, got %v", next)
+	}
+
+	changedAt := time.Now()
+	user.UsernameChangedAt = &changedAt
+	if next := service.NextUsernameChange(&user, 0); !next.IsZero() {
+		t.Errorf("Zero cooldown must not limit changes, got %v", next)
+	}
+	if next := service.NextUsernameChange(&user, time.Hour); !next.Equal(changedAt.Add(time.Hour)) {
+		t.Errorf("Expected next change after cooldown, got %v", next)
+	}
+}
+
+func TestCoverKey(t *testing.T) {
+	userId := uuid.New()
+	key := service.CoverKey(userId, "image/webp")
+	if !strings.HasPrefix(key, userId.String()+"/") || !strings.HasSuffix(key, ".webp") {
+		t.Errorf("Unexpected cover key %v", key)
+	}
+	if key == service.CoverKey(userId, "image/webp") {
+		t.Errorf("Each upload must get new key")
+	}
+	// Uploads are sent to staging keys, covers are never presigned
+	staging := service.CoverStagingKey(userId, "image/webp")
+	if !strings.HasPrefix(staging, service.CoverStagingPrefix+userId.String()+"/") || strings.HasPrefix(staging, userId.String()) {
+		t.Errorf("Unexpected staging key %v", staging)
+	}
+}
@@ -0,0 +1,134 @@
+package tests
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/G0tem/go-service-auth/internal/storage"
+	"github.com/gofiber/fiber/v2"
+)
+
+func TestLocalStorage(t *testing.T) {
+	ctx := context.Background()
+	store, err := storage.NewLocal(t.TempDir(), "http://localhost/api/v1/storage", "secret")
+	failOnError(t, err, "Failed to create storage")
+
+	data := []byte("<html><body>cover</body></html>")
+	err = store.Put(ctx, "covers", "user/cover.html", bytes.NewReader(data), int64(len(data)), storage.PutOptions{})
+	failOnError(t, err, "Failed to put object")
+
+	reader, info, err := store.Get(ctx, "covers", "user/cover.html")
+	failOnError(t, err, "Failed to get object")
+	content, err := io.ReadAll(reader)
+	_ = reader.Close()
+	failOnError(t, err, "Failed to read object")
+	if !bytes.Equal(content, data) || info.Size != int64(len(data)) || info.ContentType != "text/html; charset=utf-8" {
+		t.Errorf("Unexpected object %q %+v", content, info)
+	}
+
+	err = store.Put(ctx, "covers", "../../outside", bytes.NewReader(data), int64(len(data)), storage.PutOptions{})
+	failOnError(t, err, "Failed to put object with relative key")
+	if _, err = store.Stat(ctx, "covers", "outside"); err != nil {
+		t.Errorf("Relative key must stay in the bucket: %v", err)
+	}
+	if err = store.Put(ctx, "..", "key", bytes.NewReader(data), int64(len(data)), storage.PutOptions{}); err == nil {
+		t.Errorf("Expected error for invalid bucket")
+	}
+
+	failOnError(t, store.Copy(ctx, "covers", "user/cover.html", "user/copy.html"), "Failed to copy object")
+	if info, err = store.Stat(ctx, "covers", "user/copy.html"); err != nil || info.Size != int64(len(data)) {
+		t.Errorf("Unexpected copy %+v: %v", info, err)
+	}
+	if err = store.Copy(ctx, "covers", "user/missing", "user/copy.html"); !errors.Is(err, storage.ErrNotFound) {
+		t.Errorf("Expected not found error for missing source, got %v", err)
+	}
+
+	failOnError(t, store.Delete(ctx, "covers", "user/cover.html"), "Failed to delete object")
+	failOnError(t, store.Delete(ctx, "covers", "user/cover.html"), "Missing object must be deleted without error")
+	if _, err = store.Stat(ctx, "covers", "user/cover.html"); !errors.Is(err, storage.ErrNotFound) {
+		t.Errorf("Expected not found error, got %v", err)
+	}
+}
+
+func TestLocalStoragePresignedPut(t *testing.T) {
+	ctx := context.Background()
+	store, err := storage.NewLocal(t.TempDir(), "http://localhost/api/v1/storage", "secret")
+	failOnError(t, err, "Failed to create storage")
+	app := fiber.New()
+	store.Mount(app)
+
+	data := []byte("0123456789")
+	request, err := store.PresignPut(ctx, "covers", "user/cover.png", time.Minute, storage.PresignOptions{
+		ContentType:   "image/png",
+		ContentLength: int64(len(data)),
+	})
+	failOnError(t, err, "Failed to presign upload")
+	link, err := url.Parse(request.URL)
+	failOnError(t, err, "Invalid presigned url")
+
+	upload := func(contentType string, body []byte) int {
+		req := httptest.NewRequest(request.Method, link.RequestURI(), bytes.NewReader(body))
+		req.Header.Set("Content-Type", contentType)
+		resp, err := app.Test(req)
+		failOnError(t, err, "Failed to upload")
+		return resp.StatusCode
+	}
+	if status := upload("image/jpeg", data); status != fiber.StatusForbidden {
+		t.Errorf("Expected upload with other content type to be rejected, got %d", status)
+	}
+	if status := upload("image/png", append(data, '!')); status != fiber.StatusForbidden {
+		t.Errorf("Expected upload of other size to be rejected, got %d", status)
+	}
+	if status := upload("image/png", data); status != fiber.StatusOK {
+		t.Fatalf("Expected presigned upload to succeed, got %d", status)
+	}
+
+	resp, err := app.Test(httptest.NewRequest("GET", "/api/v1/storage/covers/user/cover.png", nil))
+	failOnError(t, err, "Failed to download")
+	content, _ := io.ReadAll(resp.Body)
+	if resp.StatusCode != fiber.StatusOK || !bytes.Equal(content, data) {
+		t.Errorf("Unexpected download %d %q", resp.StatusCode, content)
+	}
+}
+
+func TestLocalStorageServesOnlyImages(t *testing.T) {
+	ctx := context.Background()
+	root := t.TempDir()
+	store, err := storage.NewLocal(root, "http://localhost/api/v1/storage", "secret")
+	failOnError(t, err, "Failed to create storage")
+	app := fiber.New()
+	store.Mount(app)
+
+	png := []byte("\x89PNG\r\n\x1a\n0000")
+	html := []byte("<html><script>alert(1)</script></html>")
+	for key, data := range map[string][]byte{"user/cover.png": png, "user/cover.html": html, "user/.upload-123": png} {
+		err = store.Put(ctx, "covers", key, bytes.NewReader(data), int64(len(data)), storage.PutOptions{})
+		failOnError(t, err, "Failed to put object")
+	}
+
+	get := func(key string) *http.Response {
+		resp, err := app.Test(httptest.NewRequest(http.MethodGet, "/api/v1/storage/covers/"+key, nil))
+		failOnError(t, err, "Failed to download")
+		return resp
+	}
+	resp := get("user/cover.png")
+	if resp.Header.Get("Content-Type") != "image/png" || resp.Header.Get("X-Content-Type-Options") != "nosniff" {
+		t.Errorf("Unexpected headers of image %v", resp.Header)
+	}
+	// Other content must not be rendered on the API origin
+	resp = get("user/cover.html")
+	if resp.Header.Get("Content-Type") != "application/octet-stream" || resp.Header.Get("Content-Disposition") != "attachment" ||
+		resp.Header.Get("X-Content-Type-Options") != "nosniff" {
+		t.Errorf("Unexpected headers of html %v", resp.Header)
+	}
+	if resp = get("user/.upload-123"); resp.StatusCode != fiber.StatusNotFound {
+		t.Errorf("Temporary files must not be served, got %d", resp.StatusCode)
+	}
+}
@@ -0,0 +1,105 @@
+package tests
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/G0tem/go-service-auth/internal/model"
+	"github.com/G0tem/go-service-auth/internal/service"
+	"github.com/G0tem/go-service-auth/internal/types"
+	"github.com/google/uuid"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+)
+
+func TestUserOrder(t *testing.T) {
+	cases := []struct {
+		sort     []types.SortField
+		expected string
+	}{
+		{nil, "created_at desc, id"},
+		{[]types.SortField{{Field: "username"}, {Field: "created_at", Desc: true}}, "username, created_at desc, id"},
+	}
+	for _, c := range cases {
+		order, err := service.UserOrder(c.sort)
+		failOnError(t, err, "Unexpected error")
+		if order != c.expected {
+			t.Errorf("Expected order %q, got %q", c.expected, order)
+		}
+	}
+
+	// Only whitelisted fields can be sorted by, the field is never passed to SQL as is
+	for _, field := range []string{"password_hash", "id; drop table users", ""} {
+		if _, err := service.UserOrder([]types.SortField{{Field: field}}); service.Kind(err) != service.ErrInvalidArgument {
+			t.Errorf("Expected invalid argument for sort field %q, got %v", field, err)
+		}
+	}
+}
+
+func TestNormalizePage(t *testing.T) {
+	cases := []struct {
+		page, pageSize         int
+		expectedPage, expected int
+	}{
+		{0, 0, 1, service.DefaultPageSize},
+		{-1, -10, 1, service.DefaultPageSize},
+		{3, 50, 3, 50},
+		{2, service.MaxPageSize + 1, 2, service.MaxPageSize},
+	}
+	for _, c := range cases {
+		page, pageSize := service.NormalizePage(c.page, c.pageSize)
+		if page != c.expectedPage || pageSize != c.expected {
+			t.Errorf("NormalizePage(%d, %d): expected (%d, %d), got (%d, %d)",
+				c.page, c.pageSize, c.expectedPage, c.expected, page, pageSize)
+		}
+	}
+}
+
+func TestUsersQueryFilters(t *testing.T) {
+	// Dry run builds SQL without connecting to the database
+	db, err := gorm.Open(postgres.New(postgres.Config{DSN: GetTestDSN()}), &gorm.Config{DryRun: true, DisableAutomaticPing: true})
+	failOnError(t, err, "Failed to open database")
+	svc := service.New(db, nil, nil, nil, nil, nil)
+
+	active := false
+	from := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	statement := svc.UsersQuery(context.Background(), types.UserFilter{
+		Search:      "50%_off",
+		Role:        "support",
+		IsActive:    &active,
+		CreatedFrom: &from,
+	}).Find(&[]model.User{}).Statement
+	sql := statement.SQL.String()
+
+	for _, part := range []string{"username ilike", "user_roles.name =", "is_active =", "created_at >="} {
+		if !strings.Contains(sql, part) {
+			t.Errorf("Expected %q in query %v", part, sql)
+		}
+	}
+	for _, part := range []string{"email_confirmed =", "created_at <="} {
+		if strings.Contains(sql, part) {
+			t.Errorf("Unexpected %q in query %v", part, sql)
+		}
+	}
+	// LIKE wildcards of the search are escaped
+	if statement.Vars[0] != `%50\%\_off%` {
+		t.Errorf("Search pattern is not escaped: %v", statement.Vars[0])
+	}
+}
+
+func TestCheckBulkTargets(t *testing.T) {
+	actor, other := uuid.New(), uuid.New()
+	for _, action := range []string{service.BulkDeactivate, service.BulkDelete} {
+		if err := service.CheckBulkTargets(actor, []uuid.UUID{other, actor}, action); service.Kind(err) != service.ErrInvalidArgument {
+			t.Errorf("%v: expected invalid argument for the actor, got %v", action, err)
+		}
+		if err := service.CheckBulkTargets(actor, []uuid.UUID{other}, action); err != nil {
+			t.Errorf("%v: unexpected error %v", action, err)
+		}
+	}
+	if err := service.CheckBulkTargets(actor, []uuid.UUID{actor}, service.BulkConfirmEmail); err != nil {
+		t.Errorf("Unexpected error for confirmation of own email: %v", err)
+	}
+}